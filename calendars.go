@@ -0,0 +1,27 @@
+package main
+
+import (
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// defaultCalendarsPath is used when CALENDARS_CONFIG is not set
+const defaultCalendarsPath = "calendars.json"
+
+// loadCalendars saves the blackout calendars of the file given by CALENDARS_CONFIG,
+// or calendars.json, to the job manager. Calendars already stored are kept.
+func loadCalendars(jobMgr *jobpro.DefaultJobManager) error {
+	var cals []jobpro.Calendar
+	if err := readConfigFile("CALENDARS_CONFIG", defaultCalendarsPath, &cals); err != nil {
+		return err
+	}
+	if len(cals) == 0 {
+		return nil
+	}
+
+	if err := jobMgr.SaveCalendars(cals...); err != nil {
+		return serr.Wrap(err, "error saving calendars")
+	}
+	return nil
+}
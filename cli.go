@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"job_processor/jobpro"
+	"job_processor/util"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/rohanthewiz/serr"
+)
+
+const defaultDBPath = "jobs.ddb"
+
+// runSubcommand runs a CLI subcommand and returns the process exit code
+// Note that DuckDB locks the database file, so the processor should not be running on the same file.
+func runSubcommand(args []string) int {
+	var err error
+
+	switch args[0] {
+	case "export":
+		err = exportCmd(args[1:])
+	case "import":
+		err = importCmd(args[1:])
+	case "worker":
+		err = workerCmd(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\nusage: %s [export|import|worker] [flags]\n", args[0], os.Args[0])
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// exportCmd writes job definitions and results to a file
+func exportCmd(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	dbPath := fs.String("db", defaultDBPath, "DuckDB file to export from")
+	format := fs.String("format", "json", "json, csv or parquet")
+	table := fs.String("table", "results", "table for csv/parquet: jobs or results")
+	from := fs.String("from", "", "include results starting at or after (date, timestamp or e.g. -24h)")
+	to := fs.String("to", "", "include results starting before (date, timestamp or e.g. -1h)")
+	out := fs.String("out", "", "output file (default jobs-export.<format>)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := jobpro.ExportOptions{
+		Format: jobpro.ExportFormat(*format),
+		Table:  jobpro.ExportTable(*table),
+	}
+	var err error
+	if opts.From, err = util.ParseDateOrTime(*from); err != nil {
+		return serr.Wrap(err, "invalid -from")
+	}
+	if opts.To, err = util.ParseDateOrTime(*to); err != nil {
+		return serr.Wrap(err, "invalid -to")
+	}
+
+	store, err := jobpro.NewDuckDBStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	data, err := store.Export(opts)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		*out = "jobs-export." + *format
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return serr.Wrap(err, "failed to write export file")
+	}
+	fmt.Printf("Exported to %s\n", *out)
+	return nil
+}
+
+// importCmd applies a JSON export bundle to a store and prints the report
+func importCmd(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	dbPath := fs.String("db", defaultDBPath, "DuckDB file to import into")
+	in := fs.String("in", "", "JSON export file (required)")
+	overwrite := fs.Bool("overwrite", false, "overwrite existing definitions that differ")
+	replay := fs.Bool("replay", false, "replay results from the export")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return serr.New("-in is required")
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		return serr.Wrap(err, "failed to open import file")
+	}
+	defer f.Close()
+
+	bundle, err := jobpro.ReadExportBundle(f)
+	if err != nil {
+		return err
+	}
+
+	store, err := jobpro.NewDuckDBStore(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := jobpro.ImportBundle(store, bundle, jobpro.ImportOptions{
+		Overwrite:     *overwrite,
+		ReplayResults: *replay,
+	})
+	if err != nil {
+		return err
+	}
+
+	byts, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(byts))
+	return nil
+}
+
+// workerCmd runs remote jobs claimed from a scheduler until interrupted.
+// The API token, if the scheduler requires one, is read from WORKER_TOKEN.
+func workerCmd(args []string) error {
+	host, _ := os.Hostname()
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	scheduler := fs.String("scheduler", "http://localhost:8000", "URL of the scheduler")
+	id := fs.String("id", fmt.Sprintf("%s-%d", host, os.Getpid()), "worker Id, unique among the scheduler's workers")
+	labels := fs.String("labels", "", "capabilities jobs can require, e.g. gpu,region=eu")
+	slots := fs.Int("slots", 1, "how many runs at once")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := jobpro.WorkerConfig{
+		SchedulerURL: *scheduler,
+		Token:        os.Getenv("WORKER_TOKEN"),
+		ID:           *id,
+		Labels:       map[string]string{},
+		Slots:        *slots,
+	}
+	for _, label := range strings.Split(*labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			k, v, _ := strings.Cut(label, "=")
+			cfg.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Worker %s claiming runs from %s\n", cfg.ID, cfg.SchedulerURL)
+	return jobpro.RunWorker(ctx, cfg)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// defaultHooksPath is used when HOOKS_CONFIG is not set
+const defaultHooksPath = "hooks.json"
+
+// loadHooks configures the inbound webhooks of the file given by HOOKS_CONFIG, or hooks.json.
+// Hooks are not stored, as the file holds their secrets.
+func loadHooks(jobMgr *jobpro.DefaultJobManager) error {
+	var hooks []jobpro.HookConfig
+	if err := readConfigFile("HOOKS_CONFIG", defaultHooksPath, &hooks); err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	if err := jobMgr.ConfigureHooks(hooks...); err != nil {
+		return serr.Wrap(err, "error configuring webhooks")
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package jobpro
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeBucket is the granularity used when grouping results into a time series
+type TimeBucket string
+
+const (
+	BucketHour TimeBucket = "hour"
+	BucketDay  TimeBucket = "day"
+)
+
+// Validate checks that the bucket is an hour or a day
+func (b TimeBucket) Validate() error {
+	if b != BucketHour && b != BucketDay {
+		return fmt.Errorf("unsupported time bucket: %q", b)
+	}
+	return nil
+}
+
+// DurationStats summarizes the run durations of a job over a window
+type DurationStats struct {
+	JobID string
+	Runs  int // the runs that completed, failed or timed out
+	AvgMs float64
+	P50Ms float64
+	P95Ms float64
+	P99Ms float64
+	MaxMs float64
+}
+
+// StatusCounts counts runs by terminal status
+type StatusCounts struct {
+	Successes       int
+	Failures        int
+	TimedOut        int
+	Cancelled       int // stopped by an operator
+	Interrupted     int // cancelled by the job manager shutting down
+	SkippedOverlap  int
+	SkippedCalendar int
+	SkippedPool     int
+}
+
+// statusCountsSQL selects the columns scanned by StatusCounts.dest
+const statusCountsSQL = `
+		       COUNT(*) FILTER (WHERE status = 'complete'),
+		       COUNT(*) FILTER (WHERE status = 'failed'),
+		       COUNT(*) FILTER (WHERE status = 'timed_out'),
+		       COUNT(*) FILTER (WHERE status = 'cancelled'),
+		       COUNT(*) FILTER (WHERE status = 'interrupted_by_shutdown'),
+		       COUNT(*) FILTER (WHERE status = 'skipped_overlap'),
+		       COUNT(*) FILTER (WHERE status = 'skipped_calendar'),
+		       COUNT(*) FILTER (WHERE status = 'skipped_pool')`
+
+// dest returns the scan destinations of the statusCountsSQL columns
+func (c *StatusCounts) dest() []any {
+	return []any{&c.Successes, &c.Failures, &c.TimedOut, &c.Cancelled, &c.Interrupted,
+		&c.SkippedOverlap, &c.SkippedCalendar, &c.SkippedPool}
+}
+
+// successRate is the percentage of the runs that ran to an outcome (complete, failed or timed out) which completed.
+// Runs that were cancelled, interrupted or skipped don't count against it.
+func (c StatusCounts) successRate() float64 {
+	return successRate(c.Successes, c.Successes+c.Failures+c.TimedOut)
+}
+
+// RatePoint is one bucket of a success rate / run count time series
+type RatePoint struct {
+	Bucket time.Time
+	Runs   int
+	StatusCounts
+	SuccessRate float64 // percent of the runs that ran to an outcome which completed successfully
+}
+
+// ErrorCluster groups error messages that are the same once variable parts
+// (numbers, ids, quoted values) are normalized away
+type ErrorCluster struct {
+	Pattern   string   // Normalized error text
+	Example   string   // One raw message from the cluster
+	Count     int      // How many runs produced this error
+	JobIDs    []string // Jobs that produced this error
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// JobSummary is the at-a-glance view of a job used by the jobs table
+type JobSummary struct {
+	JobID  string
+	Window string
+	Runs   int
+	StatusCounts
+	SuccessRate float64
+	Durations   DurationStats
+	RecentRuns  []JobResult // most recent first
+}
+
+// normalizedErrorSQL rewrites error_msg so that messages differing only in
+// numbers, uuids, hex values or quoted strings fall into the same cluster
+const normalizedErrorSQL = `
+	regexp_replace(regexp_replace(regexp_replace(regexp_replace(trim(error_msg),
+		'[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}', '<id>', 'g'),
+		'0x[0-9a-fA-F]+', '<hex>', 'g'),
+		'"[^"]*"|''[^'']*''', '<str>', 'g'),
+		'\b\d+(\.\d+)*\b', '<n>', 'g')`
+
+// windowFilter returns the where clause and args restricting results to the window,
+// the store's namespace and optionally a job
+func (s *DuckDBStore) windowFilter(jobID string, window time.Duration) (string, []any) {
+	where := []string{"start_time >= ?"}
+	args := []any{time.Now().UTC().Add(-window)}
+	if jobID != "" {
+		where = append(where, "job_id = ?")
+		args = append(args, jobID)
+	}
+	if cond, nsArgs := s.nsFilter("namespace"); cond != "" {
+		where = append(where, cond)
+		args = append(args, nsArgs...)
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// GetDurationStats returns p50/p95/p99 durations per job over the window
+// If jobID is empty, stats for all jobs are returned.
+// Only runs that went count: skipped and cancelled runs would skew the durations toward zero.
+func (s *DuckDBStore) GetDurationStats(jobID string, window time.Duration) ([]DurationStats, error) {
+	where, args := s.windowFilter(jobID, window)
+	where += " AND status IN ('complete', 'failed', 'timed_out')"
+
+	rows, err := s.db.Query(`
+		SELECT job_id, COUNT(*),
+		       AVG(duration_micro),
+		       quantile_cont(duration_micro, 0.5),
+		       quantile_cont(duration_micro, 0.95),
+		       quantile_cont(duration_micro, 0.99),
+		       MAX(duration_micro)
+		FROM job_results`+where+`
+		GROUP BY job_id
+		ORDER BY job_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duration stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []DurationStats{}
+	for rows.Next() {
+		var ds DurationStats
+		var avg, p50, p95, p99, maxDur float64
+		if err := rows.Scan(&ds.JobID, &ds.Runs, &avg, &p50, &p95, &p99, &maxDur); err != nil {
+			return nil, fmt.Errorf("failed to scan duration stats row: %w", err)
+		}
+		ds.AvgMs, ds.P50Ms, ds.P95Ms, ds.P99Ms, ds.MaxMs = avg/1000, p50/1000, p95/1000, p99/1000, maxDur/1000
+		stats = append(stats, ds)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetSuccessRateSeries returns run counts and success rate grouped by hour or day over the window
+// If jobID is empty, all jobs are included
+func (s *DuckDBStore) GetSuccessRateSeries(jobID string, bucket TimeBucket, window time.Duration) ([]RatePoint, error) {
+	if err := bucket.Validate(); err != nil {
+		return nil, err
+	}
+	where, args := s.windowFilter(jobID, window)
+
+	rows, err := s.db.Query(`
+		SELECT date_trunc('`+string(bucket)+`', start_time) AS bucket,
+		       COUNT(*),`+statusCountsSQL+`
+		FROM job_results`+where+`
+		GROUP BY bucket
+		ORDER BY bucket
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get success rate series: %w", err)
+	}
+	defer rows.Close()
+
+	points := []RatePoint{}
+	for rows.Next() {
+		var p RatePoint
+		if err := rows.Scan(append([]any{&p.Bucket, &p.Runs}, p.StatusCounts.dest()...)...); err != nil {
+			return nil, fmt.Errorf("failed to scan success rate row: %w", err)
+		}
+		p.SuccessRate = p.StatusCounts.successRate()
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// GetTopErrors returns the most frequent error messages over the window, grouped by normalized text
+// If jobID is empty, errors from all jobs are included
+func (s *DuckDBStore) GetTopErrors(jobID string, window time.Duration, limit int) ([]ErrorCluster, error) {
+	where, args := s.windowFilter(jobID, window)
+	args = append(args, limit)
+
+	rows, err := s.db.Query(`
+		WITH errs AS (
+			SELECT job_id, error_msg, start_time, `+normalizedErrorSQL+` AS pattern
+			FROM job_results`+where+` AND error_msg IS NOT NULL AND error_msg <> ''
+		)
+		SELECT pattern, any_value(error_msg), COUNT(*),
+		       string_agg(DISTINCT job_id, ','), MIN(start_time), MAX(start_time)
+		FROM errs
+		GROUP BY pattern
+		ORDER BY COUNT(*) DESC, MAX(start_time) DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top errors: %w", err)
+	}
+	defer rows.Close()
+
+	clusters := []ErrorCluster{}
+	for rows.Next() {
+		var ec ErrorCluster
+		var jobIDs string
+		if err := rows.Scan(&ec.Pattern, &ec.Example, &ec.Count, &jobIDs,
+			&ec.FirstSeen, &ec.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan error cluster row: %w", err)
+		}
+		ec.JobIDs = strings.Split(jobIDs, ",")
+		clusters = append(clusters, ec)
+	}
+
+	return clusters, rows.Err()
+}
+
+// GetJobSummary returns run counts, success rate, duration percentiles
+// and the most recent runs of a job over the window
+func (s *DuckDBStore) GetJobSummary(jobID string, window time.Duration, recent int) (JobSummary, error) {
+	summary := JobSummary{JobID: jobID, Window: window.String()}
+
+	where, args := s.windowFilter(jobID, window)
+	err := s.db.QueryRow(`
+		SELECT COUNT(*),`+statusCountsSQL+`
+		FROM job_results`+where, args...).Scan(append([]any{&summary.Runs}, summary.StatusCounts.dest()...)...)
+	if err != nil {
+		return summary, fmt.Errorf("failed to get job summary counts: %w", err)
+	}
+	summary.SuccessRate = summary.StatusCounts.successRate()
+
+	durations, err := s.GetDurationStats(jobID, window)
+	if err != nil {
+		return summary, err
+	}
+	if len(durations) > 0 {
+		summary.Durations = durations[0]
+	} else {
+		summary.Durations.JobID = jobID
+	}
+
+	summary.RecentRuns, err = s.GetJobResults(jobID, recent)
+	if err != nil {
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+// successRate returns the percentage of successes in runs, rounded to one decimal
+func successRate(successes, runs int) float64 {
+	if runs == 0 {
+		return 0
+	}
+	return float64(int(float64(successes)/float64(runs)*1000+0.5)) / 10
+}
+
+// OutputPoint is the value of one structured output key for a single run
+type OutputPoint struct {
+	StartTime time.Time
+	Status    JobStatus
+	Value     float64
+}
+
+// jsonKeyPath returns the JSON path of a top level key (output or tag), quoted so any key name is safe
+func jsonKeyPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+// GetOutputSeries returns the numeric value of an output key for each run of a job over the window,
+// oldest first. Runs without the key, or where it is not numeric, are left out.
+func (s *DuckDBStore) GetOutputSeries(jobID, key string, window time.Duration) ([]OutputPoint, error) {
+	where, args := s.windowFilter(jobID, window)
+	args = append([]any{jsonKeyPath(key)}, args...)
+
+	rows, err := s.db.Query(`
+		SELECT start_time, status, val
+		FROM (
+			SELECT start_time, status, TRY_CAST(json_extract_string(output, ?) AS DOUBLE) AS val
+			FROM job_results`+where+`
+		)
+		WHERE val IS NOT NULL
+		ORDER BY start_time
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output series: %w", err)
+	}
+	defer rows.Close()
+
+	points := []OutputPoint{}
+	for rows.Next() {
+		var p OutputPoint
+		var status string
+		if err := rows.Scan(&p.StartTime, &status, &p.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan output series row: %w", err)
+		}
+		p.Status = JobStatus(status)
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// GetOutputKeys returns the distinct top level output keys recorded for a job over the window
+func (s *DuckDBStore) GetOutputKeys(jobID string, window time.Duration) ([]string, error) {
+	where, args := s.windowFilter(jobID, window)
+
+	rows, err := s.db.Query(`
+		SELECT DISTINCT unnest(json_keys(output)) AS k
+		FROM job_results`+where+` AND output IS NOT NULL
+		ORDER BY k
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get output keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("failed to scan output key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
@@ -0,0 +1,158 @@
+package jobpro
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDuckDBStore_Analytics(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	job := JobDef{
+		JobID:       "analytics-job",
+		JobName:     "Analytics Job",
+		SchedType:   Periodic,
+		Status:      StatusCreated,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+		NextRunTime: time.Now().UTC(),
+	}
+	if err := store.SaveJob(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	// 100 runs with durations 1ms..100ms, every 10th run fails with a varying message
+	base := time.Now().UTC().Add(-2 * time.Hour)
+	for i := 1; i <= 100; i++ {
+		result := JobResult{
+			JobID:     job.JobID,
+			StartTime: base.Add(time.Duration(i) * time.Second),
+			EndTime:   base.Add(time.Duration(i)*time.Second + time.Duration(i)*time.Millisecond),
+			Duration:  time.Duration(i) * time.Millisecond,
+			Status:    StatusComplete,
+		}
+		if i%10 == 0 {
+			result.Status = StatusFailed
+			result.ErrorMsg = fmt.Sprintf("connection to 10.0.0.%d refused after %d retries", i, i/10)
+		}
+		if err := store.RecordJobResult(result); err != nil {
+			t.Fatalf("Failed to record job result: %v", err)
+		}
+	}
+
+	// A run outside of the window should be ignored
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	if err := store.RecordJobResult(JobResult{JobID: job.JobID, StartTime: old, EndTime: old,
+		Duration: time.Hour, Status: StatusFailed, ErrorMsg: "ancient"}); err != nil {
+		t.Fatalf("Failed to record old job result: %v", err)
+	}
+
+	stats, err := store.GetDurationStats(job.JobID, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get duration stats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Runs != 100 {
+		t.Fatalf("Expected stats for 100 runs, got %+v", stats)
+	}
+	if stats[0].P50Ms < 49 || stats[0].P50Ms > 52 {
+		t.Errorf("Expected p50 around 50ms, got %.2f", stats[0].P50Ms)
+	}
+	if stats[0].P99Ms < 98 || stats[0].P99Ms > 100 {
+		t.Errorf("Expected p99 around 99ms, got %.2f", stats[0].P99Ms)
+	}
+
+	points, err := store.GetSuccessRateSeries(job.JobID, BucketDay, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get success rate series: %v", err)
+	}
+	runs := 0
+	for _, p := range points {
+		runs += p.Runs
+	}
+	if runs != 100 {
+		t.Errorf("Expected 100 runs in daily series, got %d", runs)
+	}
+
+	if _, err := store.GetSuccessRateSeries(job.JobID, "week", time.Hour); err == nil {
+		t.Errorf("Expected an error for an unsupported bucket")
+	}
+	if TimeBucket("week").Validate() == nil || BucketDay.Validate() != nil {
+		t.Errorf("Expected only hour and day buckets to be valid")
+	}
+
+	clusters, err := store.GetTopErrors("", 24*time.Hour, 5)
+	if err != nil {
+		t.Fatalf("Failed to get top errors: %v", err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("Expected errors to collapse into 1 cluster, got %d: %+v", len(clusters), clusters)
+	}
+	if clusters[0].Count != 10 {
+		t.Errorf("Expected cluster count of 10, got %d", clusters[0].Count)
+	}
+	if want := "connection to <n> refused after <n> retries"; clusters[0].Pattern != want {
+		t.Errorf("Expected pattern %q, got %q", want, clusters[0].Pattern)
+	}
+
+	summary, err := store.GetJobSummary(job.JobID, 24*time.Hour, 5)
+	if err != nil {
+		t.Fatalf("Failed to get job summary: %v", err)
+	}
+	if summary.Runs != 100 || summary.SuccessRate != 90 {
+		t.Errorf("Expected 100 runs at 90%% success, got %d at %.1f%%", summary.Runs, summary.SuccessRate)
+	}
+	if len(summary.RecentRuns) != 5 {
+		t.Errorf("Expected 5 recent runs, got %d", len(summary.RecentRuns))
+	}
+}
+
+func TestDurationStatsIgnoreRunsThatDidNotGo(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.SaveJob(JobDef{JobID: "stats-job", JobName: "Stats Job", SchedType: Periodic, Status: StatusCreated,
+		CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	// 10 runs of 10ms..100ms, then as many runs skipped or cancelled before they did anything
+	base := time.Now().UTC().Add(-time.Hour)
+	record := func(i int, status JobStatus, duration time.Duration) {
+		t.Helper()
+		start := base.Add(time.Duration(i) * time.Second)
+		if err := store.RecordJobResult(JobResult{JobID: "stats-job", StartTime: start, EndTime: start.Add(duration),
+			Duration: duration, Status: status}); err != nil {
+			t.Fatalf("Failed to record job result: %v", err)
+		}
+	}
+	for i := 1; i <= 10; i++ {
+		record(i, StatusComplete, time.Duration(i)*10*time.Millisecond)
+	}
+	durations := func() DurationStats {
+		t.Helper()
+		stats, err := store.GetDurationStats("stats-job", 24*time.Hour)
+		if err != nil || len(stats) != 1 {
+			t.Fatalf("Failed to get duration stats: %v (%+v)", err, stats)
+		}
+		return stats[0]
+	}
+	before := durations()
+
+	statuses := []JobStatus{StatusSkippedOverlap, StatusSkippedPool, StatusSkippedCalendar, StatusCancelled}
+	for i := 11; i <= 20; i++ {
+		record(i, statuses[i%len(statuses)], 0)
+	}
+	after := durations()
+	if after.P50Ms != before.P50Ms || after.Runs != 10 {
+		t.Errorf("Expected skipped and cancelled runs not to count, p50 went from %.1fms to %.1fms over %d runs",
+			before.P50Ms, after.P50Ms, after.Runs)
+	}
+}
@@ -2,6 +2,7 @@ package jobpro
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -14,6 +15,13 @@ type BaseJob struct {
 	freqType    FreqType
 	workFunc    func(context.Context) (string, error)
 	maxWorkTime time.Duration
+	tags        map[string]string
+	namespace   string
+	timezone    string
+	calendars   []string
+	calPolicy   CalendarPolicy
+	jitter      time.Duration
+	spread      time.Duration
 }
 
 /*// NewBaseJob creates a new BaseJob with the given parameters
@@ -30,72 +38,85 @@ func NewBaseJob(id, name string, freqType FreqType, workTime time.Duration,
 */
 
 // Run executes the job's workFunc and returns stats
-func (j *BaseJob) Run(ctx context.Context) (Stats, error) {
-	stats := Stats{
+// The work context is cancelled when the run exceeds the job's max work time, failing it with ErrTimedOut,
+// or when the run is stopped. A worker not returning within a grace period of that is counted as leaked.
+func (j *BaseJob) Run(ctx context.Context) (stats Stats, err error) {
+	stats = Stats{
 		StartTimeUTC: time.Now().UTC(),
 	}
 
-	const maxTimeGracePeriod = 5 * time.Second
-
-	// Create a timer for the job duration
-	var timer *time.Timer
+	// Cancelled on timeout, as well as when the run is stopped
+	var workCtx context.Context
+	var cancel context.CancelFunc
 	if j.maxWorkTime > 0 {
-		timer = time.NewTimer(j.maxWorkTime)
-		defer timer.Stop()
+		workCtx, cancel = context.WithTimeoutCause(ctx, j.maxWorkTime, ErrTimedOut)
+	} else {
+		workCtx, cancel = context.WithCancel(ctx)
 	}
+	defer cancel()
 
 	type Result struct {
 		msg string
 		err error
 	}
 
-	// Create a channel for the job result
+	// Create a channel for the job result, and one closed when the worker returns
 	resultCh := make(chan Result, 1)
+	done := make(chan struct{})
+
+	// The error of a run that timed out, whatever the worker returned
+	timedOut := func(err error) error {
+		if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return fmt.Errorf("%w after %s", ErrTimedOut, j.maxWorkTime)
+		}
+		return fmt.Errorf("%w after %s: %w", ErrTimedOut, j.maxWorkTime, err)
+	}
+
+	// Give the worker somewhere to put structured output
+	workCtx, output := withRunOutput(workCtx)
+	defer func() { stats.Output = output.Values() }()
 
 	// Run the actual worker
 	go func() {
-		msg, err := j.workFunc(ctx) // Run it!
+		defer close(done)
+		msg, err := j.workFunc(workCtx) // Run it!
 		resultCh <- Result{msg, err}
 	}()
 
-	// Wait for either the job to complete, the timer to expire, or the context to be canceled
+	// Wait for either the job to complete, time out, or be canceled
 	select {
-	case <-ctx.Done():
-		// Job was canceled
+	case result := <-resultCh:
+		stats.Duration = time.Since(stats.StartTimeUTC)
+		stats.SuccessMsg = result.msg
+		if errors.Is(context.Cause(workCtx), ErrTimedOut) { // returned just as it timed out
+			return stats, timedOut(result.err)
+		}
+		return stats, result.err
+
+	case <-workCtx.Done():
+	}
+
+	if !errors.Is(context.Cause(workCtx), ErrTimedOut) {
+		// Job was canceled - don't hold up the stop, but keep count of a worker that doesn't return
+		go awaitCancelledWorker(j.id, done)
 		stats.Duration = time.Since(stats.StartTimeUTC)
 		stats.SuccessMsg = "Job was canceled"
 		return stats, ctx.Err()
+	}
 
-	case <-j.timerOrNil(timer):
-		// Job duration exceeded, but still wait for result
-		select {
-		case result := <-resultCh:
-			stats.Duration = time.Since(stats.StartTimeUTC)
-			stats.SuccessMsg = result.msg
-			return stats, result.err
-		case <-time.After(maxTimeGracePeriod): // Small grace period
-			stats.Duration = time.Since(stats.StartTimeUTC)
-			stats.SuccessMsg = "Job timed out but didn't respect cancellation"
-			return stats, fmt.Errorf("job execution exceeded maximum duration")
-		}
-
+	// Job duration exceeded - give the worker a grace period to return
+	select {
 	case result := <-resultCh:
-		// Job completed successfully
 		stats.Duration = time.Since(stats.StartTimeUTC)
 		stats.SuccessMsg = result.msg
-		return stats, result.err
-	}
-}
+		return stats, timedOut(result.err)
 
-// timerOrNil takes a timer and returns the timer's channel or nil
-// This is just a convenience function for use in a select statement to conditionally
-// timeout a process or not
-// Note that a nil channel will never be "selected"
-func (j *BaseJob) timerOrNil(timer *time.Timer) <-chan time.Time {
-	if timer == nil {
-		return nil
+	case <-time.After(workerGracePeriod):
+		go trackLeakedWorker(j.id, done)
+		stats.Duration = time.Since(stats.StartTimeUTC)
+		stats.SuccessMsg = "Job timed out and didn't stop when cancelled"
+		return stats, timedOut(nil)
 	}
-	return timer.C
 }
 
 // ID returns the job ID
@@ -112,3 +133,38 @@ func (j *BaseJob) Name() string {
 func (j *BaseJob) Type() FreqType {
 	return j.freqType
 }
+
+// Tags returns the job's tags (labels)
+func (j *BaseJob) Tags() map[string]string {
+	return j.tags
+}
+
+// Calendars returns the names of the job's blackout calendars
+func (j *BaseJob) Calendars() []string {
+	return j.calendars
+}
+
+// CalendarPolicy returns whether runs in a blackout window are skipped or deferred
+func (j *BaseJob) CalendarPolicy() CalendarPolicy {
+	return j.calPolicy
+}
+
+// Timezone returns the timezone of the job's schedule
+func (j *BaseJob) Timezone() string {
+	return j.timezone
+}
+
+// Namespace returns the namespace the job belongs to
+func (j *BaseJob) Namespace() string {
+	return j.namespace
+}
+
+// Jitter returns the maximum random delay of the job's scheduled runs
+func (j *BaseJob) Jitter() time.Duration {
+	return j.jitter
+}
+
+// Spread returns the window the job's scheduled runs are spread over
+func (j *BaseJob) Spread() time.Duration {
+	return j.spread
+}
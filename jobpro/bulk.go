@@ -0,0 +1,67 @@
+package jobpro
+
+import (
+	"fmt"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// BulkAction is an operation applied to every job matching a selector
+type BulkAction string
+
+const (
+	BulkPause  BulkAction = "pause"
+	BulkResume BulkAction = "resume"
+	BulkStop   BulkAction = "stop"
+	BulkRunNow BulkAction = "run-now"
+)
+
+// BulkResult is the outcome of a bulk action on one job
+type BulkResult struct {
+	JobID string
+	Error string `json:",omitempty"`
+}
+
+// SelectJobs returns the definitions of the jobs matching the selector
+func (m *DefaultJobManager) SelectJobs(sel Selector) ([]JobDef, error) {
+	return m.store.ListJobs("", "", sel)
+}
+
+// Bulk applies an action to every job matching the selector and reports the outcome per job.
+// An empty selector is refused so a missing filter can't act on every job.
+// A failure on one job does not stop the action being applied to the others.
+func (m *DefaultJobManager) Bulk(action BulkAction, sel Selector) ([]BulkResult, error) {
+	if sel.Empty() {
+		return nil, serr.New("bulk actions require a non-empty selector")
+	}
+
+	var apply func(id string) error
+	switch action {
+	case BulkPause:
+		apply = m.PauseJob
+	case BulkResume:
+		apply = m.ResumeJob
+	case BulkStop:
+		apply = m.StopJob
+	case BulkRunNow:
+		apply = m.TriggerJobNow
+	default:
+		return nil, fmt.Errorf("unsupported bulk action: %q", action)
+	}
+
+	jobs, err := m.SelectJobs(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BulkResult, 0, len(jobs))
+	for _, job := range jobs {
+		res := BulkResult{JobID: job.JobID}
+		if err := apply(job.JobID); err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
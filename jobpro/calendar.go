@@ -0,0 +1,458 @@
+package jobpro
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CalendarPolicy is what happens to a scheduled run falling in a blackout window
+type CalendarPolicy string
+
+const (
+	// CalendarSkip drops the run (the default)
+	CalendarSkip CalendarPolicy = "skip"
+	// CalendarDefer runs the job once when the blackout window ends
+	CalendarDefer CalendarPolicy = "defer"
+)
+
+// CalendarJob is implemented by jobs that must not run during the blackout windows of calendars
+type CalendarJob interface {
+	Calendars() []string
+	CalendarPolicy() CalendarPolicy
+}
+
+// Calendar is a named set of blackout windows, such as maintenance windows,
+// market holidays or a month-end freeze. Jobs reference calendars by name.
+type Calendar struct {
+	Name string
+	// Timezone the windows are in. Defaults to the timezone of the job being checked.
+	Timezone  string            `json:",omitempty"`
+	Windows   []CalendarWindow  `json:",omitempty"` // one-off date ranges
+	Holidays  []string          `json:",omitempty"` // whole days, e.g. "2026-12-25"
+	Recurring []RecurringWindow `json:",omitempty"` // e.g. weekends
+}
+
+// CalendarWindow is a one-off blackout from Start up to End.
+// Times are "2006-01-02 15:04" or a date, "2006-01-02". An End date includes that whole day.
+type CalendarWindow struct {
+	Start  string
+	End    string
+	Reason string `json:",omitempty"`
+}
+
+// RecurringWindow is a blackout recurring on matching days, e.g. weekends or the last
+// three days of each month. Empty Weekdays or MonthDays match any day.
+type RecurringWindow struct {
+	Weekdays  []string `json:",omitempty"` // "mon", "tue", ... "sun"
+	MonthDays []int    `json:",omitempty"` // 1 to 31, or -1 for the last day of the month, -2 the day before, ...
+	// From and To ("15:04") restrict the window to a time of day. Empty means the whole day.
+	From   string `json:",omitempty"`
+	To     string `json:",omitempty"`
+	Reason string `json:",omitempty"`
+}
+
+// Blackout describes the blackout window a time falls in
+type Blackout struct {
+	Calendar string
+	Reason   string
+	Until    time.Time // when the window (and any adjoining windows) end
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate checks that the calendar's windows can be parsed
+func (c Calendar) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("calendar has no name")
+	}
+	if _, err := loadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("calendar %s: %w", c.Name, err)
+	}
+
+	for _, w := range c.Windows {
+		start, end, err := w.bounds(time.UTC)
+		if err != nil {
+			return fmt.Errorf("calendar %s: %w", c.Name, err)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("calendar %s: window %s - %s ends before it starts", c.Name, w.Start, w.End)
+		}
+	}
+	for _, d := range c.Holidays {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			return fmt.Errorf("calendar %s: invalid holiday %q", c.Name, d)
+		}
+	}
+	for _, r := range c.Recurring {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("calendar %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// bounds returns the start and (exclusive) end of the window in loc
+func (w CalendarWindow) bounds(loc *time.Location) (start, end time.Time, err error) {
+	if start, err = time.ParseInLocation("2006-01-02 15:04", w.Start, loc); err != nil {
+		if start, err = time.ParseInLocation(time.DateOnly, w.Start, loc); err != nil {
+			return start, end, fmt.Errorf("invalid window start %q", w.Start)
+		}
+	}
+	if end, err = time.ParseInLocation("2006-01-02 15:04", w.End, loc); err != nil {
+		if end, err = time.ParseInLocation(time.DateOnly, w.End, loc); err != nil {
+			return start, end, fmt.Errorf("invalid window end %q", w.End)
+		}
+		end = end.AddDate(0, 0, 1) // the whole end day
+	}
+	return start, end, nil
+}
+
+func (r RecurringWindow) validate() error {
+	for _, d := range r.Weekdays {
+		if _, ok := weekdayNames[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("invalid weekday %q", d)
+		}
+	}
+	for _, d := range r.MonthDays {
+		if d == 0 || d < -31 || d > 31 {
+			return fmt.Errorf("invalid day of month %d", d)
+		}
+	}
+	from, to, err := r.timesOfDay()
+	if err != nil {
+		return err
+	}
+	if to <= from {
+		return fmt.Errorf("recurring window %s - %s ends before it starts", r.From, r.To)
+	}
+	return nil
+}
+
+// timesOfDay returns the window as offsets from midnight
+func (r RecurringWindow) timesOfDay() (from, to time.Duration, err error) {
+	parse := func(s string, def time.Duration) (time.Duration, error) {
+		if s == "" {
+			return def, nil
+		}
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time of day %q", s)
+		}
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+	if from, err = parse(r.From, 0); err != nil {
+		return
+	}
+	to, err = parse(r.To, 24*time.Hour)
+	return
+}
+
+// matchesDay reports whether the window recurs on the day of t
+func (r RecurringWindow) matchesDay(t time.Time) bool {
+	if len(r.Weekdays) > 0 && !slices.ContainsFunc(r.Weekdays, func(d string) bool {
+		return weekdayNames[strings.ToLower(d)] == t.Weekday()
+	}) {
+		return false
+	}
+	if len(r.MonthDays) > 0 {
+		lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if !slices.ContainsFunc(r.MonthDays, func(d int) bool {
+			return d == t.Day() || (d < 0 && lastDay+d+1 == t.Day())
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// window returns the end of the blackout window of the calendar that t falls in, if any.
+// Windows are evaluated in the calendar's timezone, else in loc.
+func (c Calendar) window(t time.Time, loc *time.Location) (end time.Time, reason string, ok bool) {
+	if c.Timezone != "" {
+		if l, err := time.LoadLocation(c.Timezone); err == nil {
+			loc = l
+		}
+	}
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+	for _, w := range c.Windows {
+		start, wEnd, err := w.bounds(loc)
+		if err == nil && !t.Before(start) && t.Before(wEnd) {
+			return wEnd, w.Reason, true
+		}
+	}
+
+	for _, d := range c.Holidays {
+		if d == t.Format(time.DateOnly) {
+			return midnight.AddDate(0, 0, 1), "holiday " + d, true
+		}
+	}
+
+	for _, r := range c.Recurring {
+		from, to, err := r.timesOfDay()
+		if err != nil || !r.matchesDay(t) {
+			continue
+		}
+		if start, rEnd := midnight.Add(from), midnight.Add(to); !t.Before(start) && t.Before(rEnd) {
+			if to == 24*time.Hour {
+				rEnd = midnight.AddDate(0, 0, 1) // the next midnight, even across DST changes
+			}
+			return rEnd, r.Reason, true
+		}
+	}
+
+	return time.Time{}, "", false
+}
+
+// blackout returns the blackout window of the calendars that t falls in, if any.
+// Adjoining windows, such as a Saturday and a Sunday, extend Until.
+func blackout(cals []Calendar, t time.Time, loc *time.Location) (Blackout, bool) {
+	var b Blackout
+	found := false
+
+	// Bound the search - adjoining windows can't extend a blackout forever
+	for i := 0; i < 1000; i++ {
+		extended := false
+		for _, c := range cals {
+			if end, reason, ok := c.window(t, loc); ok {
+				if !found {
+					b = Blackout{Calendar: c.Name, Reason: reason}
+					found = true
+				}
+				t, extended = end, true
+				break
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+
+	b.Until = t
+	return b, found
+}
+
+// SaveCalendar creates or replaces a calendar
+func (s *DuckDBStore) SaveCalendar(cal Calendar) error {
+	def, err := json.Marshal(cal)
+	if err != nil {
+		return fmt.Errorf("failed to encode calendar: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO calendars (name, definition, updated_at)
+		VALUES (?, CAST(?::VARCHAR AS JSON), ?)
+		ON CONFLICT (name) DO UPDATE SET
+			definition = excluded.definition,
+			updated_at = excluded.updated_at
+	`, cal.Name, string(def), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save calendar: %w", err)
+	}
+	return nil
+}
+
+// ListCalendars returns all calendars by name
+// Calendars are shared by all namespaces
+func (s *DuckDBStore) ListCalendars() ([]Calendar, error) {
+	rows, err := s.db.Query(`SELECT definition::VARCHAR FROM calendars ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendars: %w", err)
+	}
+	defer rows.Close()
+
+	cals := []Calendar{}
+	for rows.Next() {
+		var def sql.NullString
+		if err := rows.Scan(&def); err != nil {
+			return nil, fmt.Errorf("failed to scan calendar: %w", err)
+		}
+
+		var cal Calendar
+		if err := json.Unmarshal([]byte(def.String), &cal); err != nil {
+			return nil, fmt.Errorf("failed to decode calendar: %w", err)
+		}
+		cals = append(cals, cal)
+	}
+	return cals, rows.Err()
+}
+
+// DeleteCalendar removes a calendar
+func (s *DuckDBStore) DeleteCalendar(name string) error {
+	res, err := s.db.Exec(`DELETE FROM calendars WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("calendar %s not found", name)
+	}
+	return nil
+}
+
+// SaveCalendars validates, stores and applies calendars, replacing any of the same name
+func (m *DefaultJobManager) SaveCalendars(cals ...Calendar) error {
+	for _, cal := range cals {
+		if err := cal.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, cal := range cals {
+		if err := m.rootStore.SaveCalendar(cal); err != nil {
+			return err
+		}
+		m.mu.Lock()
+		m.calendars[cal.Name] = cal
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// ListCalendars returns the calendars
+func (m *DefaultJobManager) ListCalendars() ([]Calendar, error) {
+	return m.rootStore.ListCalendars()
+}
+
+// DeleteCalendar removes a calendar. Jobs referencing it are no longer blacked out by it.
+func (m *DefaultJobManager) DeleteCalendar(name string) error {
+	if err := m.rootStore.DeleteCalendar(name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.calendars, name)
+	m.mu.Unlock()
+	return nil
+}
+
+// loadCalendars reads the stored calendars into the manager
+func (m *DefaultJobManager) loadCalendars() error {
+	cals, err := m.rootStore.ListCalendars()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cal := range cals {
+		m.calendars[cal.Name] = cal
+	}
+	return nil
+}
+
+// checkCalendars returns an error if any of the calendar names is unknown
+// The caller must hold m.mu
+func (m *DefaultJobManager) checkCalendars(names []string) error {
+	for _, name := range names {
+		if _, ok := m.calendars[name]; !ok {
+			return fmt.Errorf("unknown calendar %q", name)
+		}
+	}
+	return nil
+}
+
+// runScheduled runs a job for a cron tick or one-time schedule, unless the time
+// falls in a blackout window of the job's calendars. The run is then skipped,
+// or deferred to the end of the window, and recorded with StatusSkippedCalendar.
+// Jobs run on demand (run now, manual start) ignore calendars.
+// scheduled is when the run was due.
+func (m *DefaultJobManager) runScheduled(id string, scheduled time.Time) {
+	// A timer may fire just as the manager steps down
+	if !m.IsLeader() {
+		return
+	}
+
+	jobDef, err := m.rootStore.GetJob(id)
+	if err != nil || len(jobDef.Calendars) == 0 {
+		m.executeJob(id, scheduledRun(scheduled))
+		return
+	}
+
+	m.mu.RLock()
+	cals := make([]Calendar, 0, len(jobDef.Calendars))
+	for _, name := range jobDef.Calendars {
+		if cal, ok := m.calendars[name]; ok {
+			cals = append(cals, cal)
+		}
+	}
+	namespace := m.jobNamespaces[id]
+	m.mu.RUnlock()
+
+	loc, err := loadLocation(jobDef.Timezone)
+	if err != nil || loc == nil {
+		loc = time.Local
+	}
+
+	now := time.Now()
+	b, blackedOut := blackout(cals, now, loc)
+	if !blackedOut {
+		m.executeJob(id, scheduledRun(scheduled))
+		return
+	}
+
+	msg := fmt.Sprintf("Skipped: in blackout window of calendar %s", b.Calendar)
+	if b.Reason != "" {
+		msg += " (" + b.Reason + ")"
+	}
+
+	if jobDef.CalendarPolicy == CalendarDefer {
+		msg += ", deferred to " + b.Until.UTC().Format("2006-01-02 15:04 MST")
+		m.deferRun(id, b.Until)
+	}
+	log.Printf("Job %s: %s", id, msg)
+
+	m.queueResult(JobResult{
+		JobID:         id,
+		StartTime:     now.UTC(),
+		EndTime:       now.UTC(),
+		Status:        StatusSkippedCalendar,
+		SuccessMsg:    msg,
+		Namespace:     namespace,
+		ScheduledTime: scheduled.UTC(),
+		Trigger:       TriggerCron,
+	})
+}
+
+// deferRun runs a job once at the given time. Runs deferred while one is already
+// pending are coalesced into it. Stopping or pausing the job cancels the deferred run.
+func (m *DefaultJobManager) deferRun(id string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, pending := m.scheduledJobs[id]; pending || m.shutdown {
+		return
+	}
+
+	m.scheduledJobs[id] = time.AfterFunc(time.Until(at), func() {
+		m.mu.Lock()
+		delete(m.scheduledJobs, id)
+		m.mu.Unlock()
+
+		// The window may have been extended in the meantime
+		m.runScheduled(id, at)
+	})
+}
+
+// queueResult queues the result of a run not executed by executeJob, such as one that did not start.
+// Once the manager is shutting down, the result is spooled, to be recorded on the next start.
+func (m *DefaultJobManager) queueResult(result JobResult) {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		log.Printf("Shutting down, spooling result for job %s", result.JobID)
+		m.spool.add([]JobResult{result})
+		return
+	}
+	m.wg.Add(1) // processResults marks each result done
+	m.mu.Unlock()
+
+	m.sendResult(result)
+}
@@ -0,0 +1,195 @@
+package jobpro
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCalendarBlackout(t *testing.T) {
+	cals := []Calendar{
+		{
+			Name:     "ops",
+			Windows:  []CalendarWindow{{Start: "2026-06-10 22:00", End: "2026-06-11 02:00", Reason: "db upgrade"}},
+			Holidays: []string{"2026-12-25"},
+			Recurring: []RecurringWindow{
+				{Weekdays: []string{"sat", "sun"}, Reason: "weekend"},
+				{Weekdays: []string{"wed"}, From: "03:00", To: "04:00", Reason: "backups"},
+			},
+		},
+		{
+			Name:      "month-end",
+			Recurring: []RecurringWindow{{MonthDays: []int{-2, -1}, Reason: "freeze"}},
+		},
+	}
+
+	at := func(s string) time.Time {
+		t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		name     string
+		at       string
+		want     bool
+		calendar string
+		until    string
+	}{
+		{"Inside one-off window", "2026-06-10 23:30", true, "ops", "2026-06-11 02:00"},
+		{"Window end is exclusive", "2026-06-11 02:00", false, "", ""},
+		{"Ordinary weekday", "2026-06-09 12:00", false, "", ""},
+		{"Holiday then weekend", "2026-12-25 08:00", true, "ops", "2026-12-28 00:00"},
+		{"Weekend extends to Monday", "2026-06-13 10:00", true, "ops", "2026-06-15 00:00"},
+		{"Time of day window", "2026-06-10 03:15", true, "ops", "2026-06-10 04:00"},
+		{"Outside time of day window", "2026-06-10 04:00", false, "", ""},
+		{"Month end freeze", "2026-06-29 09:00", true, "month-end", "2026-07-01 00:00"},
+		{"Day before month end freeze", "2026-06-28 09:00", true, "ops", "2026-07-01 00:00"}, // Sunday, then the freeze
+		{"February month end", "2026-02-27 09:00", true, "month-end", "2026-03-02 00:00"},    // then Sunday
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ok := blackout(cals, at(tt.at), time.UTC)
+			if ok != tt.want {
+				t.Fatalf("blackout(%s) = %v, want %v", tt.at, ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if b.Calendar != tt.calendar {
+				t.Errorf("Expected calendar %s, got %s", tt.calendar, b.Calendar)
+			}
+			if got := b.Until.UTC().Format("2006-01-02 15:04"); got != tt.until {
+				t.Errorf("Expected blackout until %s, got %s", tt.until, got)
+			}
+		})
+	}
+}
+
+func TestCalendarValidate(t *testing.T) {
+	bad := []Calendar{
+		{},
+		{Name: "a", Timezone: "Nowhere/Special"},
+		{Name: "a", Windows: []CalendarWindow{{Start: "2026-06-10", End: "tomorrow"}}},
+		{Name: "a", Windows: []CalendarWindow{{Start: "2026-06-10 10:00", End: "2026-06-10 09:00"}}},
+		{Name: "a", Holidays: []string{"12/25/2026"}},
+		{Name: "a", Recurring: []RecurringWindow{{Weekdays: []string{"someday"}}}},
+		{Name: "a", Recurring: []RecurringWindow{{MonthDays: []int{0}}}},
+		{Name: "a", Recurring: []RecurringWindow{{From: "10:00", To: "09:00"}}},
+	}
+	for i, cal := range bad {
+		if err := cal.Validate(); err == nil {
+			t.Errorf("Expected calendar %d (%+v) to be invalid", i, cal)
+		}
+	}
+
+	good := Calendar{Name: "a", Timezone: "Europe/London", Holidays: []string{"2026-12-25"},
+		Windows:   []CalendarWindow{{Start: "2026-06-10", End: "2026-06-10"}},
+		Recurring: []RecurringWindow{{Weekdays: []string{"Sat"}, From: "01:00", To: "02:30"}}}
+	if err := good.Validate(); err != nil {
+		t.Errorf("Expected calendar to be valid: %v", err)
+	}
+}
+
+func TestScheduledRunsInBlackout(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	// A window covering the next minute or so
+	now := time.Now().UTC()
+	err = mgr.SaveCalendars(Calendar{Name: "freeze", Timezone: "UTC", Windows: []CalendarWindow{{
+		Start:  now.Add(-time.Minute).Format("2006-01-02 15:04"),
+		End:    now.Add(2 * time.Minute).Format("2006-01-02 15:04"),
+		Reason: "release",
+	}}})
+	if err != nil {
+		t.Fatalf("Failed to save calendar: %v", err)
+	}
+
+	// Calendars are persisted
+	if cals, err := store.ListCalendars(); err != nil || len(cals) != 1 || cals[0].Name != "freeze" {
+		t.Fatalf("Expected the freeze calendar to be stored, got %+v (err %v)", cals, err)
+	}
+
+	var runs int32
+	work := func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}
+
+	if err := setupJob(mgr, JobConfig{Id: "bad-cal", Name: "Bad", Calendars: []string{"nope"},
+		RunFunction: work}); err == nil {
+		t.Error("Expected a job referencing an unknown calendar to be refused")
+	}
+
+	jc := JobConfig{Id: "frozen", Name: "Frozen", IsPeriodic: true, Schedule: "* * * * * *",
+		Calendars: []string{"freeze"}, AutoStart: true, RunFunction: work}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+
+	if n := atomic.LoadInt32(&runs); n != 0 {
+		t.Errorf("Expected no runs in the blackout window, got %d", n)
+	}
+	results, err := store.GetJobResults("frozen", 10)
+	if err != nil || len(results) == 0 {
+		t.Fatalf("Expected skipped results, got %d (err %v)", len(results), err)
+	}
+	if results[0].Status != StatusSkippedCalendar {
+		t.Errorf("Expected status %s, got %s", StatusSkippedCalendar, results[0].Status)
+	}
+
+	// Running on demand ignores calendars
+	if err := mgr.TriggerJobNow("frozen"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("Expected the triggered run to happen, got %d runs", n)
+	}
+}
+
+func TestDeferredRun(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	// Calendar windows are at minute precision - defer directly to test the coalescing
+	var runs int32
+	jc := JobConfig{Id: "deferred", Name: "Deferred", RunFunction: func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	until := time.Now().Add(200 * time.Millisecond)
+	mgr.deferRun("deferred", until)
+	mgr.deferRun("deferred", until.Add(time.Second)) // coalesced into the pending run
+
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 0 {
+		t.Errorf("Expected no run before the window ends, got %d", n)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("Expected one deferred run, got %d", n)
+	}
+}
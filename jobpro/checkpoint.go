@@ -0,0 +1,223 @@
+package jobpro
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Checkpoint saves the state of a run to the store, and gives it back to the next run of the job,
+// so a run interrupted by a shutdown or crash can carry on where it left off.
+// A work function gets it from its context with CheckpointFromContext.
+// The state is opaque to the manager, and is cleared once a run completes.
+type Checkpoint struct {
+	mu       sync.Mutex
+	store    JobStore // nil for detached checkpoints
+	jobID    string
+	resumed  bool // the run resumes an interrupted run
+	restored bool // the run got back the state saved by an earlier run
+	used     bool // state was loaded or saved during the run
+}
+
+type checkpointKey struct{}
+
+// withCheckpoint returns a context carrying the checkpoint of the job's run
+func withCheckpoint(ctx context.Context, store JobStore, jobID string, resumed bool) (context.Context, *Checkpoint) {
+	c := &Checkpoint{store: store, jobID: jobID, resumed: resumed}
+	return context.WithValue(ctx, checkpointKey{}, c), c
+}
+
+// CheckpointFromContext returns the Checkpoint of the run owning ctx.
+// Outside of a run, and on remote workers, a detached checkpoint is returned, which keeps no state.
+func CheckpointFromContext(ctx context.Context) *Checkpoint {
+	if c, ok := ctx.Value(checkpointKey{}).(*Checkpoint); ok {
+		return c
+	}
+	return &Checkpoint{}
+}
+
+// Load returns the last state saved for the job, or nil if there is none
+func (c *Checkpoint) Load() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return nil, nil
+	}
+
+	state, err := c.store.GetCheckpoint(c.jobID)
+	if err != nil {
+		return nil, err
+	}
+	c.used = true
+	if state != nil {
+		c.restored = true
+	}
+	return state, nil
+}
+
+// Save replaces the saved state of the job
+func (c *Checkpoint) Save(state []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		return nil
+	}
+
+	c.used = true
+	return c.store.SaveCheckpoint(c.jobID, state)
+}
+
+// Resumed reports whether the run resumes a run that was interrupted, or carries on from the state of an earlier run
+func (c *Checkpoint) Resumed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resumed || c.restored
+}
+
+// finish clears the state once the run completed, if the run used it
+func (c *Checkpoint) finish(status JobStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil || !c.used || status != StatusComplete {
+		return
+	}
+	if err := c.store.DeleteCheckpoint(c.jobID); err != nil {
+		log.Printf("Error clearing the checkpoint of job %s: %v", c.jobID, err)
+	}
+}
+
+// ActiveRun is a run in progress of a job that resumes interrupted runs. It stays in the store
+// while the run goes on, so a run still listed on startup was interrupted.
+type ActiveRun struct {
+	JobID         string
+	Namespace     string
+	StartTime     time.Time
+	ScheduledTime time.Time // zero for runs on demand
+}
+
+// resumesInterrupted reports whether the job resumes its runs interrupted by a shutdown or crash
+func resumesInterrupted(job Job) bool {
+	cj, ok := job.(ConfigurableJob)
+	return ok && cj.Config().ResumeInterrupted
+}
+
+// loadInterruptedRuns reads the runs left in progress by the last shutdown or crash.
+// They are resumed when their job is started, if it resumes interrupted runs.
+func (m *DefaultJobManager) loadInterruptedRuns() error {
+	runs, err := m.rootStore.ListActiveRuns()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, run := range runs {
+		m.interrupted[run.JobID] = run
+	}
+	if len(runs) > 0 {
+		log.Printf("Found %d runs interrupted by the last shutdown", len(runs))
+	}
+	return nil
+}
+
+// takeInterrupted reports whether the job has an interrupted run to resume, forgetting it.
+// The run of a job that doesn't resume interrupted runs is dropped from the store.
+// The caller must hold m.mu
+func (m *DefaultJobManager) takeInterrupted(id string, job Job) (ActiveRun, bool) {
+	run, ok := m.interrupted[id]
+	if !ok {
+		return ActiveRun{}, false
+	}
+	delete(m.interrupted, id)
+
+	if !resumesInterrupted(job) {
+		if err := m.rootStore.DeleteActiveRun(id); err != nil {
+			log.Printf("Error removing the interrupted run of job %s: %v", id, err)
+		}
+		return ActiveRun{}, false
+	}
+	return run, true
+}
+
+// SaveCheckpoint stores the checkpoint state of a job, replacing the previous one
+func (s *DuckDBStore) SaveCheckpoint(jobID string, state []byte) error {
+	_, err := s.db.Exec(`
+		INSERT INTO checkpoints (job_id, state, saved_at) VALUES (?, ?, ?)
+		ON CONFLICT (job_id) DO UPDATE SET state = excluded.state, saved_at = excluded.saved_at
+	`, jobID, state, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GetCheckpoint returns the checkpoint state of a job, or nil if it has none
+func (s *DuckDBStore) GetCheckpoint(jobID string) ([]byte, error) {
+	var state []byte
+	err := s.db.QueryRow(`SELECT state FROM checkpoints WHERE job_id = ?`, jobID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	if state == nil {
+		state = []byte{} // saved empty, which is not the same as none
+	}
+	return state, nil
+}
+
+// DeleteCheckpoint removes the checkpoint of a job
+func (s *DuckDBStore) DeleteCheckpoint(jobID string) error {
+	if _, err := s.db.Exec(`DELETE FROM checkpoints WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// SaveActiveRun records the run in progress of a job, replacing any earlier one
+func (s *DuckDBStore) SaveActiveRun(run ActiveRun) error {
+	_, err := s.db.Exec(`
+		INSERT INTO active_runs (job_id, namespace, start_time, scheduled_time) VALUES (?, ?, ?, ?)
+		ON CONFLICT (job_id) DO UPDATE SET namespace = excluded.namespace, start_time = excluded.start_time,
+			scheduled_time = excluded.scheduled_time
+	`, run.JobID, run.Namespace, run.StartTime.UTC(), nullTime(run.ScheduledTime))
+	if err != nil {
+		return fmt.Errorf("failed to save active run: %w", err)
+	}
+	return nil
+}
+
+// DeleteActiveRun removes the run in progress of a job
+func (s *DuckDBStore) DeleteActiveRun(jobID string) error {
+	if _, err := s.db.Exec(`DELETE FROM active_runs WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("failed to delete active run: %w", err)
+	}
+	return nil
+}
+
+// ListActiveRuns returns the runs recorded as in progress
+func (s *DuckDBStore) ListActiveRuns() ([]ActiveRun, error) {
+	rows, err := s.db.Query(`SELECT job_id, namespace, start_time, scheduled_time FROM active_runs ORDER BY start_time`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []ActiveRun{}
+	for rows.Next() {
+		var run ActiveRun
+		var namespace sql.NullString
+		var scheduled sql.NullTime
+		if err := rows.Scan(&run.JobID, &namespace, &run.StartTime, &scheduled); err != nil {
+			return nil, fmt.Errorf("failed to scan active run: %w", err)
+		}
+		run.Namespace, run.ScheduledTime = namespace.String, scheduled.Time
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
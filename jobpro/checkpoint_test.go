@@ -0,0 +1,135 @@
+package jobpro
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResumeAfterShutdown(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "jobs.db")
+
+	// A long job saving its state as it goes, interrupted by a shutdown
+	store1, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr1 := NewJobManager(store1)
+	saved := make(chan struct{})
+	jc := JobConfig{Id: "backfill", Name: "Backfill", IsPeriodic: true, Schedule: "0 0 0 1 1 *",
+		ResumeInterrupted: true, AutoStart: true}
+	jc.RunFunction = func(ctx context.Context) error {
+		cp := CheckpointFromContext(ctx)
+		if state, err := cp.Load(); err != nil || state != nil || cp.Resumed() {
+			t.Errorf("Expected the first run to start afresh, got %q (%v)", state, err)
+		}
+		if err := cp.Save([]byte("day=12")); err != nil {
+			t.Errorf("Failed to save checkpoint: %v", err)
+		}
+		close(saved)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := setupJob(mgr1, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr1.TriggerJobNow("backfill"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	<-saved
+	if err := mgr1.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+
+	// Started again, the job resumes at once from its checkpoint
+	store2, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	mgr2 := NewJobManager(store2)
+	defer mgr2.Shutdown(5 * time.Second)
+	jc.RunFunction = func(ctx context.Context) error {
+		cp := CheckpointFromContext(ctx)
+		if state, err := cp.Load(); err != nil || string(state) != "day=12" {
+			t.Errorf("Expected the interrupted run's checkpoint, got %q (%v)", state, err)
+		}
+		return nil
+	}
+	if err := setupJob(mgr2, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	results := waitForResults(t, store2, "backfill", 2)
+	if results[0].Status != StatusComplete || !results[0].Resumed {
+		t.Errorf("Expected a completed run marked as resumed, got %s (resumed: %v)", results[0].Status, results[0].Resumed)
+	}
+	if results[1].Status != StatusInterruptedByShutdown || results[1].Resumed {
+		t.Errorf("Expected the interrupted run before it, got %s (resumed: %v)", results[1].Status, results[1].Resumed)
+	}
+
+	// Completing clears the checkpoint and the run in progress
+	waitNotRunning(t, mgr2, 2*time.Second)
+	if state, err := store2.GetCheckpoint("backfill"); err != nil || state != nil {
+		t.Errorf("Expected the checkpoint to be cleared, got %q (%v)", state, err)
+	}
+	if runs, err := store2.ListActiveRuns(); err != nil || len(runs) != 0 {
+		t.Errorf("Expected no run in progress, got %+v (%v)", runs, err)
+	}
+}
+
+func TestResumeAfterCrash(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// A crash leaves the runs in progress recorded
+	started := time.Now().Add(-time.Minute)
+	for _, id := range []string{"resumes", "restarts"} {
+		if err := store.SaveActiveRun(ActiveRun{JobID: id, Namespace: DefaultNamespace, StartTime: started}); err != nil {
+			t.Fatalf("Failed to save active run: %v", err)
+		}
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	ran := make(chan bool, 2)
+	for _, jc := range []JobConfig{
+		{Id: "resumes", Name: "Resumes", IsPeriodic: true, Schedule: "0 0 0 1 1 *", ResumeInterrupted: true, AutoStart: true},
+		{Id: "restarts", Name: "Restarts", IsPeriodic: true, Schedule: "0 0 0 1 1 *", AutoStart: true},
+	} {
+		jc.RunFunction = func(ctx context.Context) error {
+			ran <- CheckpointFromContext(ctx).Resumed()
+			return nil
+		}
+		if err := setupJob(mgr, jc); err != nil {
+			t.Fatalf("Failed to setup job: %v", err)
+		}
+	}
+
+	// Only the job resuming interrupted runs runs at once
+	select {
+	case resumed := <-ran:
+		if !resumed {
+			t.Error("Expected the run to resume the interrupted one")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the interrupted run to be resumed")
+	}
+	if result := waitForResult(t, store, "resumes", 2*time.Second); !result.Resumed {
+		t.Errorf("Expected the result to be marked as resumed, got %+v", result)
+	}
+
+	// The other job's run is forgotten with its first run
+	if err := mgr.TriggerJobNow("restarts"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if resumed := <-ran; resumed {
+		t.Error("Expected the job that doesn't resume interrupted runs to start afresh")
+	}
+	waitForResult(t, store, "restarts", 2*time.Second)
+	if runs, err := store.ListActiveRuns(); err != nil || len(runs) != 0 {
+		t.Errorf("Expected no run in progress, got %+v (%v)", runs, err)
+	}
+}
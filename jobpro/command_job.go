@@ -0,0 +1,48 @@
+package jobpro
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// maxCommandOutput is how much of the end of a command's output is kept as the run's message
+const maxCommandOutput = 4 << 10
+
+// commandKillDelay is how long a cancelled command has to exit after SIGTERM before it is sent SIGKILL.
+// It is shorter than the worker grace period, so killed commands are not counted as leaked.
+const commandKillDelay = 3 * time.Second
+
+// runCommand runs a shell command, returning the end of its combined output
+// The parameters of the run, if any, are passed to the command as JSON in the JOB_PARAMS environment variable.
+// When the context is cancelled (the run timed out or was stopped) the command's process group
+// is sent SIGTERM, then SIGKILL if it hasn't exited after commandKillDelay.
+func runCommand(ctx context.Context, command string) (string, error) {
+	exited := make(chan struct{})
+	defer close(exited)
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if params := encodeParams(ctx); params != nil {
+		cmd.Env = append(os.Environ(), "JOB_PARAMS="+string(params))
+	}
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminate(cmd, exited, commandKillDelay)
+	}
+	// Don't wait on output pipes held open by stray children once the command is killed
+	cmd.WaitDelay = commandKillDelay + time.Second
+
+	out, err := cmd.CombinedOutput()
+	msg := strings.TrimSpace(string(out))
+	if len(msg) > maxCommandOutput {
+		msg = "..." + msg[len(msg)-maxCommandOutput:]
+	}
+	if err != nil {
+		return msg, serr.Wrap(err, "command failed", "output", msg)
+	}
+	return msg, nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package jobpro
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup is a no-op where process groups are not supported
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills the command, as there is no SIGTERM to send first
+func terminate(cmd *exec.Cmd, exited <-chan struct{}, killDelay time.Duration) error {
+	return cmd.Process.Kill()
+}
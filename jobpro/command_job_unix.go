@@ -0,0 +1,31 @@
+//go:build unix
+
+package jobpro
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts the command in its own process group, so signals reach the commands the shell starts
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminate sends the command's process group SIGTERM, and SIGKILL after killDelay unless the command has exited
+func terminate(cmd *exec.Cmd, exited <-chan struct{}, killDelay time.Duration) error {
+	pgid := -cmd.Process.Pid
+	if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-exited:
+		case <-time.After(killDelay):
+			_ = syscall.Kill(pgid, syscall.SIGKILL)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,86 @@
+package jobpro
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rohanthewiz/serr"
+)
+
+// ErrCommandJobsNotAllowed is returned for jobs running a shell command where the manager doesn't allow them
+var ErrCommandJobsNotAllowed = errors.New("command jobs are not allowed")
+
+// AllowCommandJobs sets whether jobs running a shell command can be created, imported, and loaded from the store.
+// Namespace views never allow them, only the manager itself.
+func (m *DefaultJobManager) AllowCommandJobs(allow bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.allowCommands = allow
+}
+
+// CommandJobsAllowed reports whether jobs running a shell command can be created through the manager
+func (m *DefaultJobManager) CommandJobsAllowed() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.allowCommands && m.namespace == ""
+}
+
+// CreateJob sets up a job from a config given at runtime (e.g. from the web UI) rather than registered at boot,
+// and starts it if AutoStart is set. The config is stored with the job, so LoadJobs can set it up again after a restart.
+func (m *DefaultJobManager) CreateJob(jc JobConfig) (string, error) {
+	if jc.Id == "" {
+		jc.Id = uuid.New().String()
+	}
+	if m.namespace != "" {
+		jc.Namespace = m.namespace
+	}
+
+	m.mu.RLock()
+	notLeader := m.checkLeader()
+	_, err := m.store.GetJob(jc.Id)
+	_, _, invalid := m.validateConfig(jc)
+	m.mu.RUnlock()
+	if notLeader != nil {
+		return "", notLeader
+	}
+	if jc.Command != "" && !m.CommandJobsAllowed() {
+		return "", ErrCommandJobsNotAllowed
+	}
+	if err == nil {
+		return "", fmt.Errorf("job with Id %s already exists", jc.Id)
+	}
+	if invalid != nil {
+		return "", serr.Wrap(invalid, "invalid job")
+	}
+
+	jobID, err := m.SetupJob(NewScheduledJob(jc), jc.Schedule)
+	if err != nil {
+		return "", serr.Wrap(err, "failed to set up job")
+	}
+
+	jobDef, err := m.store.GetJob(jobID)
+	if err != nil {
+		return jobID, serr.Wrap(err, "failed to get job details")
+	}
+	jobDef.Definition = &jc
+	if err := m.store.SaveJob(jobDef); err != nil {
+		return jobID, serr.Wrap(err, "failed to save job definition")
+	}
+
+	if jc.AutoStart {
+		if err := m.StartJob(jobID); err != nil {
+			return jobID, serr.Wrap(err, "failed to start job")
+		}
+	}
+
+	// Let the system know that jobs have been updated
+	select {
+	case m.jobsUpdated <- "updated":
+		fmt.Println("Job update (created) notification sent")
+	default: // Non-blocking send to avoid blocking if no one is listening
+		// If the channel is full, we don't want to block
+	}
+
+	return jobID, nil
+}
@@ -0,0 +1,172 @@
+package jobpro
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForResults polls the store until the job has n results
+func waitForResults(t *testing.T, store JobStore, jobID string, n int) []JobResult {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if results, err := store.GetJobResults(jobID, n); err == nil && len(results) >= n {
+			return results
+		}
+	}
+	t.Fatalf("Expected %d results of job %s", n, jobID)
+	return nil
+}
+
+func TestCreateCommandJob(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "created.db")
+
+	store1, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr1 := NewJobManager(store1)
+	mgr1.AllowCommandJobs(true)
+
+	jc := JobConfig{Id: "echo", Name: "Echo", Command: "echo hello from $0", AutoStart: true,
+		Tags: map[string]string{"team": "ops"}}
+	jobID, err := mgr1.CreateJob(jc)
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	result := waitForResults(t, store1, jobID, 1)[0]
+	if result.Status != StatusComplete || result.SuccessMsg != "hello from sh" {
+		t.Errorf("Expected the command's output, got %s %q (%s)", result.Status, result.SuccessMsg, result.ErrorMsg)
+	}
+
+	if _, err := mgr1.CreateJob(jc); err == nil {
+		t.Error("Expected a duplicate Id to be rejected")
+	}
+	for _, bad := range []JobConfig{
+		{Name: "Nothing to run"},
+		{Name: "Both", Command: "true", TriggerEndpoint: "/run"},
+		{Name: "No schedule", IsPeriodic: true, Command: "true"},
+		{Name: "Bad schedule", IsPeriodic: true, Schedule: "sometimes", Command: "true"},
+	} {
+		if _, err := mgr1.CreateJob(bad); err == nil {
+			t.Errorf("Expected job %q to be rejected", bad.Name)
+		}
+	}
+
+	if err := mgr1.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+
+	// After a restart the job is set up again from its stored definition
+	store2, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store after restart: %v", err)
+	}
+	mgr2 := NewJobManager(store2)
+	mgr2.AllowCommandJobs(true)
+	defer mgr2.Shutdown(5 * time.Second)
+
+	if err := mgr2.LoadJobs(); err != nil {
+		t.Fatalf("Failed to load jobs: %v", err)
+	}
+	settings, err := mgr2.GetJobSettings(jobID)
+	if err != nil {
+		t.Fatalf("Expected the created job to be loaded: %v", err)
+	}
+	if *settings.Name != "Echo" || settings.Tags["team"] != "ops" {
+		t.Errorf("Expected the stored config, got %+v", settings)
+	}
+	waitForResults(t, store2, jobID, 2) // AutoStart runs it again
+}
+
+func TestRetries(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	var attempts int32
+	jc := JobConfig{Id: "flaky", Name: "Flaky", RetryCount: 1, AutoStart: true,
+		RunFunction: func(ctx context.Context) error {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				return errors.New("first attempt fails")
+			}
+			return nil
+		}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	result := waitForResults(t, store, jc.Id, 1)[0]
+	if result.Status != StatusComplete || atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected success on the second attempt, got %s after %d attempts", result.Status, attempts)
+	}
+}
+
+func TestCommandJobsGate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "gated.db")
+	store, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+
+	jc := JobConfig{Id: "rm", Name: "Rm", Command: "echo pwned"}
+	if _, err := mgr.CreateJob(jc); !errors.Is(err, ErrCommandJobsNotAllowed) {
+		t.Errorf("Expected command jobs to be refused until allowed, got %v", err)
+	}
+
+	// Once allowed, only the manager itself takes them, not namespace views
+	mgr.AllowCommandJobs(true)
+	teamA := mgr.ForNamespace("team-a")
+	if _, err := teamA.CreateJob(jc); !errors.Is(err, ErrCommandJobsNotAllowed) {
+		t.Errorf("Expected a namespace view to refuse command jobs, got %v", err)
+	}
+
+	// Nor can a command job be imported through a namespace view
+	now := time.Now().UTC()
+	bundle := ExportBundle{Jobs: []JobDef{
+		{JobID: "http", JobName: "Http", SchedType: OneTime, Status: StatusCreated, CreatedAt: now, UpdatedAt: now,
+			Definition: &JobConfig{Id: "http", Name: "Http", TriggerEndpoint: "/run"}},
+		{JobID: jc.Id, JobName: jc.Name, SchedType: OneTime, Status: StatusCreated, CreatedAt: now, UpdatedAt: now,
+			Definition: &jc},
+	}}
+	if _, err := teamA.Import(bundle, ImportOptions{}); !errors.Is(err, ErrCommandJobsNotAllowed) {
+		t.Errorf("Expected a namespace view to refuse to import a command job, got %v", err)
+	}
+	for _, id := range []string{"http", jc.Id} {
+		if _, err := store.GetJob(id); err == nil {
+			t.Errorf("Expected job %s of the refused import not to be saved", id)
+		}
+	}
+
+	// A command job in the store is only loaded by a manager allowing them
+	if _, err := mgr.Import(bundle, ImportOptions{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if err := mgr.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+	store2, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store after restart: %v", err)
+	}
+	mgr2 := NewJobManager(store2)
+	defer mgr2.Shutdown(5 * time.Second)
+	if err := mgr2.LoadJobs(); err != nil {
+		t.Fatalf("Failed to load jobs: %v", err)
+	}
+	if _, err := mgr2.GetJobSettings("http"); err != nil {
+		t.Errorf("Expected the HTTP job to be loaded: %v", err)
+	}
+	if _, err := mgr2.GetJobSettings(jc.Id); err == nil {
+		t.Error("Expected the command job not to be loaded while command jobs are not allowed")
+	}
+}
@@ -1,18 +1,29 @@
 package jobpro
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"job_processor/util"
+	"strings"
 	"time"
 
-	_ "github.com/marcboeker/go-duckdb/v2"
+	"github.com/marcboeker/go-duckdb/v2"
 	"github.com/rohanthewiz/serr"
 )
 
+// ErrResultRejected is the error of results the store can never record, such as the result of a deleted job
+// violating a constraint, as opposed to a store that is unavailable
+var ErrResultRejected = errors.New("result rejected by the store")
+
 // DuckDBStore implements JobStore using DuckDB
 type DuckDBStore struct {
-	db *sql.DB
+	db        *sql.DB
+	namespace string    // if set, all queries are restricted to this namespace
+	cache     *jobCache // job definitions, shared with the namespace views
 }
 
 // NewDuckDBStore creates a new DuckDB-backed job store
@@ -25,7 +36,7 @@ func NewDuckDBStore(dbPath string) (*DuckDBStore, error) {
 		return nil, fmt.Errorf("failed to open DuckDB: %w", err)
 	}
 
-	store := &DuckDBStore{db: db}
+	store := &DuckDBStore{db: db, cache: newJobCache()}
 	if err := store.initialize(); err != nil {
 		_ = db.Close()
 		return nil, err
@@ -79,57 +90,292 @@ func (s *DuckDBStore) initialize() error {
 		return fmt.Errorf("failed to create job_results sequence: %w", err)
 	}
 
+	return s.migrate()
+}
+
+// migrations add columns introduced after the original schema, so existing databases are upgraded in place
+// DuckDB can't add columns with constraints, so new columns are nullable
+var migrations = []string{
+	`ALTER TABLE job_results ADD COLUMN IF NOT EXISTS output JSON`,
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS tags JSON`,
+	// Existing rows are backfilled with the default
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS namespace VARCHAR DEFAULT '` + DefaultNamespace + `'`,
+	`ALTER TABLE job_results ADD COLUMN IF NOT EXISTS namespace VARCHAR DEFAULT '` + DefaultNamespace + `'`,
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS timezone VARCHAR`,
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS calendars JSON`,
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS calendar_policy VARCHAR`,
+	`CREATE TABLE IF NOT EXISTS calendars (
+		name VARCHAR PRIMARY KEY,
+		definition JSON NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`ALTER TABLE job_results ADD COLUMN IF NOT EXISTS scheduled_time TIMESTAMP`,
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS edits JSON`,
+	`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS definition JSON`,
+	`CREATE TABLE IF NOT EXISTS run_queue (
+		run_id VARCHAR PRIMARY KEY,
+		job_id VARCHAR NOT NULL,
+		namespace VARCHAR NOT NULL,
+		config JSON NOT NULL,
+		requires VARCHAR,
+		scheduled_time TIMESTAMP,
+		enqueued_at TIMESTAMP NOT NULL,
+		attempts INTEGER NOT NULL,
+		status VARCHAR NOT NULL,
+		worker VARCHAR,
+		lease_expires TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS checkpoints (
+		job_id VARCHAR PRIMARY KEY,
+		state BLOB,
+		saved_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS active_runs (
+		job_id VARCHAR PRIMARY KEY,
+		namespace VARCHAR,
+		start_time TIMESTAMP NOT NULL,
+		scheduled_time TIMESTAMP
+	)`,
+	`ALTER TABLE job_results ADD COLUMN IF NOT EXISTS resumed BOOLEAN`,
+	`ALTER TABLE job_results ADD COLUMN IF NOT EXISTS pool_wait_micro BIGINT`,
+	`ALTER TABLE job_results ADD COLUMN IF NOT EXISTS trigger_source VARCHAR`,
+	`ALTER TABLE run_queue ADD COLUMN IF NOT EXISTS params JSON`,
+	`ALTER TABLE run_queue ADD COLUMN IF NOT EXISTS trigger_source VARCHAR`,
+	`CREATE TABLE IF NOT EXISTS watched_files (
+		watch VARCHAR NOT NULL,
+		path VARCHAR NOT NULL,
+		size BIGINT NOT NULL,
+		mod_time TIMESTAMP NOT NULL,
+		job_id VARCHAR NOT NULL,
+		status VARCHAR NOT NULL,
+		seen_at TIMESTAMP NOT NULL,
+		moved_to VARCHAR,
+		PRIMARY KEY (watch, path, size, mod_time)
+	)`,
+}
+
+// migrate applies the migrations, each of which must be idempotent
+func (s *DuckDBStore) migrate() error {
+	for _, m := range migrations {
+		if _, err := s.db.Exec(m); err != nil {
+			return fmt.Errorf("failed to migrate schema (%s): %w", m, err)
+		}
+	}
 	return nil
 }
 
+// ForNamespace returns a view of the store restricted to the namespace
+// The view shares the database connection, so closing it is a no-op.
+func (s *DuckDBStore) ForNamespace(namespace string) JobStore {
+	return &DuckDBStore{db: s.db, namespace: namespace, cache: s.cache}
+}
+
+// Namespace returns the namespace the store is restricted to, or "" if it sees all namespaces
+func (s *DuckDBStore) Namespace() string {
+	return s.namespace
+}
+
+// nsFilter returns a condition restricting column to the store's namespace
+// and its args, or an empty condition if the store is not restricted
+func (s *DuckDBStore) nsFilter(column string) (string, []any) {
+	if s.namespace == "" {
+		return "", nil
+	}
+	return column + " = ?", []any{s.namespace}
+}
+
+// andNs appends the namespace condition on column to a query's where clause and args
+func (s *DuckDBStore) andNs(where string, args []any, column string) (string, []any) {
+	cond, nsArgs := s.nsFilter(column)
+	if cond == "" {
+		return where, args
+	}
+	return where + " AND " + cond, append(args, nsArgs...)
+}
+
+// ListNamespaces returns the namespaces having jobs or results
+func (s *DuckDBStore) ListNamespaces() ([]string, error) {
+	if s.namespace != "" {
+		return []string{s.namespace}, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT namespace FROM jobs
+		UNION
+		SELECT namespace FROM job_results
+		ORDER BY namespace
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	defer rows.Close()
+
+	namespaces := []string{}
+	for rows.Next() {
+		var ns sql.NullString
+		if err := rows.Scan(&ns); err != nil {
+			return nil, fmt.Errorf("failed to scan namespace: %w", err)
+		}
+		if ns.Valid {
+			namespaces = append(namespaces, ns.String)
+		}
+	}
+
+	return namespaces, rows.Err()
+}
+
 // SaveJob persists a job definition
+// A namespaced store saves into its namespace; otherwise the job's namespace
+// (or the default) is used. A job can't be moved to another namespace by saving it.
 func (s *DuckDBStore) SaveJob(job JobDef) error {
-	_, err := s.db.Exec(`
+	// Dropped once written, so the next read caches the saved definition
+	defer s.cache.drop(job.JobID)
+
+	tags, err := encodeJSON(job.Tags)
+	if err != nil {
+		return err
+	}
+	var calendars any
+	if len(job.Calendars) > 0 {
+		byts, err := json.Marshal(job.Calendars)
+		if err != nil {
+			return fmt.Errorf("failed to encode job calendars: %w", err)
+		}
+		calendars = string(byts)
+	}
+	var edits any
+	if !job.Edits.empty() {
+		byts, err := json.Marshal(job.Edits)
+		if err != nil {
+			return fmt.Errorf("failed to encode job edits: %w", err)
+		}
+		edits = string(byts)
+	}
+	var definition any
+	if job.Definition != nil {
+		byts, err := json.Marshal(job.Definition)
+		if err != nil {
+			return fmt.Errorf("failed to encode job definition: %w", err)
+		}
+		definition = string(byts)
+	}
+
+	namespace := s.namespace
+	if namespace == "" {
+		namespace = util.If(job.Namespace == "", DefaultNamespace, job.Namespace)
+	}
+
+	res, err := s.db.Exec(`
 		INSERT INTO jobs (
 			job_id, job_name, schedule_type, schedule, 
-			next_run_time, status, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			next_run_time, status, created_at, updated_at, tags, namespace, timezone,
+			calendars, calendar_policy, edits, definition
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, CAST(?::VARCHAR AS JSON), ?, ?, CAST(?::VARCHAR AS JSON), ?,
+			CAST(?::VARCHAR AS JSON), CAST(?::VARCHAR AS JSON))
 		ON CONFLICT (job_id) DO UPDATE SET
 			job_name = excluded.job_name,
 			schedule_type = excluded.schedule_type,
 			schedule = excluded.schedule,
 			next_run_time = excluded.next_run_time,
 			status = excluded.status,
-			updated_at = excluded.updated_at
+			updated_at = excluded.updated_at,
+			tags = excluded.tags,
+			timezone = excluded.timezone,
+			calendars = excluded.calendars,
+			calendar_policy = excluded.calendar_policy,
+			edits = excluded.edits,
+			definition = excluded.definition
+		WHERE jobs.namespace = excluded.namespace
 	`,
 		job.JobID, job.JobName, job.SchedType, job.Schedule,
-		job.NextRunTime, job.Status, job.CreatedAt, job.UpdatedAt,
+		job.NextRunTime, job.Status, job.CreatedAt, job.UpdatedAt, tags, namespace, job.Timezone,
+		calendars, job.CalendarPolicy, edits, definition,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to save job: %w", err)
 	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to save job: job %s exists in another namespace", job.JobID)
+	}
 	return nil
 }
 
-// GetJob retrieves a job definition by Id
-func (s *DuckDBStore) GetJob(id string) (JobDef, error) {
-	row := s.db.QueryRow(`
-		SELECT job_id, job_name, schedule_type, schedule, 
-		       next_run_time, status, created_at, updated_at
-		FROM jobs WHERE job_id = ?
-	`, id)
+// jobColumns are the jobs columns read into a JobDef, in the order scanJobDef expects
+const jobColumns = `job_id, job_name, schedule_type, schedule,
+		       next_run_time, status, created_at, updated_at, tags::VARCHAR, namespace, timezone,
+		       calendars::VARCHAR, calendar_policy, edits::VARCHAR,
+		       definition::VARCHAR`
 
+// scanJobDef scans a row selected with jobColumns
+func scanJobDef(row interface{ Scan(...any) error }) (JobDef, error) {
 	var job JobDef
+	var tags, namespace, timezone, calendars, calendarPolicy, edits, definition sql.NullString
+
 	err := row.Scan(
 		&job.JobID, &job.JobName, &job.SchedType, &job.Schedule,
-		&job.NextRunTime, &job.Status, &job.CreatedAt, &job.UpdatedAt,
+		&job.NextRunTime, &job.Status, &job.CreatedAt, &job.UpdatedAt, &tags, &namespace, &timezone,
+		&calendars, &calendarPolicy, &edits, &definition,
 	)
+	if err != nil {
+		return job, err
+	}
+	job.Namespace = namespace.String
+	job.Timezone = timezone.String
+	job.CalendarPolicy = CalendarPolicy(calendarPolicy.String)
+
+	if calendars.Valid && calendars.String != "" {
+		if err := json.Unmarshal([]byte(calendars.String), &job.Calendars); err != nil {
+			return job, fmt.Errorf("failed to decode job calendars: %w", err)
+		}
+	}
+
+	if tags.Valid && tags.String != "" {
+		if err := json.Unmarshal([]byte(tags.String), &job.Tags); err != nil {
+			return job, fmt.Errorf("failed to decode job tags: %w", err)
+		}
+	}
+
+	if edits.Valid && edits.String != "" {
+		if err := json.Unmarshal([]byte(edits.String), &job.Edits); err != nil {
+			return job, fmt.Errorf("failed to decode job edits: %w", err)
+		}
+	}
+
+	if definition.Valid && definition.String != "" {
+		if err := json.Unmarshal([]byte(definition.String), &job.Definition); err != nil {
+			return job, fmt.Errorf("failed to decode job definition: %w", err)
+		}
+	}
+
+	return job, nil
+}
+
+// GetJob retrieves a job definition by Id
+// Definitions are cached, so only the first read of a job after it is written queries the database.
+func (s *DuckDBStore) GetJob(id string) (JobDef, error) {
+	if job, ok := s.cache.get(id); ok && (s.namespace == "" || job.Namespace == s.namespace) {
+		return job, nil
+	}
+	gen := s.cache.generation()
+
+	where, args := s.andNs("job_id = ?", []any{id}, "namespace")
+	row := s.db.QueryRow(`
+		SELECT `+jobColumns+`
+		FROM jobs WHERE `+where, args...)
+
+	job, err := scanJobDef(row)
 	if err != nil {
 		return JobDef{}, fmt.Errorf("failed to get job: %w", err)
 	}
+	s.cache.put(job, gen)
 	return job, nil
 }
 
 // ListJobs retrieves all job definitions with optional filters
-func (s *DuckDBStore) ListJobs(status JobStatus, schedType FreqType) ([]JobDef, error) {
+// A nil or empty selector matches all jobs
+func (s *DuckDBStore) ListJobs(status JobStatus, schedType FreqType, sel Selector) ([]JobDef, error) {
 	query := `
-		SELECT job_id, job_name, schedule_type, schedule, 
-		       next_run_time, status, created_at, updated_at
+		SELECT ` + jobColumns + `
 		FROM jobs
 	`
 	args := []interface{}{}
@@ -145,6 +391,17 @@ func (s *DuckDBStore) ListJobs(status JobStatus, schedType FreqType) ([]JobDef,
 		args = append(args, schedType)
 	}
 
+	if !sel.Empty() {
+		cond, selArgs := sel.sqlWhere("tags")
+		where = append(where, cond)
+		args = append(args, selArgs...)
+	}
+
+	if cond, nsArgs := s.nsFilter("namespace"); cond != "" {
+		where = append(where, cond)
+		args = append(args, nsArgs...)
+	}
+
 	if len(where) > 0 {
 		query += " WHERE " + where[0]
 		for i := 1; i < len(where); i++ {
@@ -162,11 +419,7 @@ func (s *DuckDBStore) ListJobs(status JobStatus, schedType FreqType) ([]JobDef,
 
 	jobs := []JobDef{}
 	for rows.Next() {
-		var job JobDef
-		err := rows.Scan(
-			&job.JobID, &job.JobName, &job.SchedType, &job.Schedule,
-			&job.NextRunTime, &job.Status, &job.CreatedAt, &job.UpdatedAt,
-		)
+		job, err := scanJobDef(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan job row: %w", err)
 		}
@@ -178,28 +431,50 @@ func (s *DuckDBStore) ListJobs(status JobStatus, schedType FreqType) ([]JobDef,
 
 // UpdateJobStatus updates the status of a job
 func (s *DuckDBStore) UpdateJobStatus(id string, status JobStatus) error {
+	now := time.Now().UTC()
+	where, args := s.andNs("job_id = ?", []any{status, now, id}, "namespace")
 	_, err := s.db.Exec(`
-		UPDATE jobs SET status = ?, updated_at = ? WHERE job_id = ?
-	`, status, time.Now().UTC(), id)
+		UPDATE jobs SET status = ?, updated_at = ? WHERE `+where, args...)
 	if err != nil {
+		s.cache.drop(id)
 		return fmt.Errorf("failed to update job status: %w", err)
 	}
+	s.cache.update(id, func(job *JobDef) {
+		if s.namespace == "" || job.Namespace == s.namespace {
+			job.Status, job.UpdatedAt = status, now
+		}
+	})
 	return nil
 }
 
 // UpdateNextRunTime updates when a job should next run
 func (s *DuckDBStore) UpdateNextRunTime(id string, nextRun time.Time) error {
+	now := time.Now().UTC()
+	where, args := s.andNs("job_id = ?", []any{nextRun, now, id}, "namespace")
 	_, err := s.db.Exec(`
-		UPDATE jobs SET next_run_time = ?, updated_at = ? WHERE job_id = ?
-	`, nextRun, time.Now().UTC(), id)
+		UPDATE jobs SET next_run_time = ?, updated_at = ? WHERE `+where, args...)
 	if err != nil {
+		s.cache.drop(id)
 		return fmt.Errorf("failed to update next run time: %w", err)
 	}
+	s.cache.update(id, func(job *JobDef) {
+		if s.namespace == "" || job.Namespace == s.namespace {
+			job.NextRunTime, job.UpdatedAt = nextRun, now
+		}
+	})
 	return nil
 }
 
 // DeleteJob removes a job definition
 func (s *DuckDBStore) DeleteJob(id string) error {
+	defer s.cache.drop(id)
+
+	if s.namespace != "" { // make sure the job belongs to the namespace
+		if _, err := s.GetJob(id); err != nil {
+			return fmt.Errorf("failed to delete job: %w", err)
+		}
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -212,6 +487,13 @@ func (s *DuckDBStore) DeleteJob(id string) error {
 		return fmt.Errorf("failed to delete job results: %w", err)
 	}
 
+	// Along with its saved state
+	for _, table := range []string{"checkpoints", "active_runs"} {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE job_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete job %s: %w", table, err)
+		}
+	}
+
 	// Delete the job
 	_, err = tx.Exec("DELETE FROM jobs WHERE job_id = ?", id)
 	if err != nil {
@@ -222,34 +504,126 @@ func (s *DuckDBStore) DeleteJob(id string) error {
 }
 
 // RecordJobResult stores the outcome of a job execution
+// The result is recorded in the store's namespace if it has one, else in the result's namespace,
+// falling back to the namespace of the job.
 func (s *DuckDBStore) RecordJobResult(result JobResult) error {
-	durationMicro := result.Duration.Microseconds()
+	return s.RecordJobResults([]JobResult{result})
+}
 
-	_, err := s.db.Exec(`
-		INSERT INTO job_results (
-			result_id, job_id, start_time, end_time, duration_micro, 
-			status, success_msg, error_msg
-		) VALUES (nextval('job_results_id_seq'), ?, ?, ?, ?, ?, ?, ?)
-	`,
-		result.JobID, result.StartTime, result.EndTime, durationMicro,
-		result.Status, result.SuccessMsg, result.ErrorMsg,
-	)
+// RecordJobResults stores the outcomes of several job executions in one transaction,
+// so either all of them are recorded or none are. Rows are added with DuckDB's appender,
+// which is much faster than INSERT statements for batches.
+// Results violating a constraint, such as those of a deleted job, fail with ErrResultRejected.
+func (s *DuckDBStore) RecordJobResults(results []JobResult) error {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to record job result: %w", err)
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN TRANSACTION"); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := s.appendResults(ctx, conn, results); err != nil {
+		_, _ = conn.ExecContext(ctx, "ROLLBACK")
+		if isConstraintError(err) {
+			return fmt.Errorf("%w: %w", ErrResultRejected, err)
+		}
+		return fmt.Errorf("failed to record job results: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return fmt.Errorf("failed to commit job results: %w", err)
 	}
 	return nil
 }
 
+// isConstraintError reports whether err is a constraint violation. The appender doesn't always report them
+// with the constraint error type when it flushes, so their message is checked too.
+func isConstraintError(err error) bool {
+	var dErr *duckdb.Error
+	if errors.As(err, &dErr) && dErr.Type == duckdb.ErrorTypeConstraint {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "constraint")
+}
+
+// appendResults appends results to job_results on the connection
+func (s *DuckDBStore) appendResults(ctx context.Context, conn *sql.Conn, results []JobResult) error {
+	// The appender doesn't evaluate defaults, so take the Ids from the sequence up front
+	ids := make([]int64, 0, len(results))
+	rows, err := conn.QueryContext(ctx, "SELECT nextval('job_results_id_seq') FROM range(?)", len(results))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return conn.Raw(func(driverConn any) error {
+		appender, err := duckdb.NewAppenderFromConn(driverConn.(driver.Conn), "", "job_results")
+		if err != nil {
+			return err
+		}
+
+		for i, result := range results {
+			var output any // the appender encodes JSON columns itself
+			if len(result.Output) > 0 {
+				output = result.Output
+			}
+			var scheduled any
+			if !result.ScheduledTime.IsZero() {
+				scheduled = result.ScheduledTime
+			}
+
+			// Every column of job_results, in table order - columns added by migrations must be added here
+			err = appender.AppendRow(int32(ids[i]), result.JobID, result.StartTime, result.EndTime,
+				result.Duration.Microseconds(), string(result.Status), result.SuccessMsg, result.ErrorMsg,
+				output, s.resultNamespace(result), scheduled, result.Resumed, result.PoolWait.Microseconds(),
+				string(result.Trigger))
+			if err != nil {
+				appender.Close()
+				return err
+			}
+		}
+		return appender.Close() // flushes the rows
+	})
+}
+
+// resultNamespace returns the namespace a result is recorded in: the store's if it has one, else the result's,
+// falling back to the namespace of the job
+func (s *DuckDBStore) resultNamespace(result JobResult) string {
+	if s.namespace != "" {
+		return s.namespace
+	}
+	if result.Namespace != "" {
+		return result.Namespace
+	}
+	if job, err := s.GetJob(result.JobID); err == nil && job.Namespace != "" {
+		return job.Namespace
+	}
+	return DefaultNamespace
+}
+
 // GetJobResults retrieves historical results for a job
 func (s *DuckDBStore) GetJobResults(jobID string, limit int) ([]JobResult, error) {
+	where, args := s.andNs("job_id = ?", []any{jobID}, "namespace")
 	rows, err := s.db.Query(`
-		SELECT job_id, start_time, end_time, duration_micro, 
-		       status, success_msg, error_msg
+		SELECT `+jobResultColumns+`
 		FROM job_results
-		WHERE job_id = ?
+		WHERE `+where+`
 		ORDER BY start_time DESC
 		LIMIT ?
-	`, jobID, limit)
+	`, append(args, limit)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job results: %w", err)
 	}
@@ -257,22 +631,62 @@ func (s *DuckDBStore) GetJobResults(jobID string, limit int) ([]JobResult, error
 
 	results := []JobResult{}
 	for rows.Next() {
-		var result JobResult
-		var durationMicro int64
-		err := rows.Scan(
-			&result.JobID, &result.StartTime, &result.EndTime, &durationMicro,
-			&result.Status, &result.SuccessMsg, &result.ErrorMsg,
-		)
+		result, err := scanJobResult(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan result row: %w", err)
+			return nil, err
 		}
-		result.Duration = time.Duration(durationMicro) * time.Microsecond
 		results = append(results, result)
 	}
 
 	return results, nil
 }
 
+// jobResultColumns are the job_results columns read into a JobResult, in the order scanJobResult expects
+const jobResultColumns = `job_id, start_time, end_time, duration_micro,
+		       status, success_msg, error_msg, output::VARCHAR, namespace, scheduled_time, COALESCE(resumed, false),
+		       COALESCE(pool_wait_micro, 0), COALESCE(trigger_source, '')`
+
+// scanJobResult scans a row selected with jobResultColumns
+func scanJobResult(row interface{ Scan(...any) error }) (JobResult, error) {
+	var result JobResult
+	var durationMicro, poolWaitMicro int64
+	var output, namespace sql.NullString
+	var scheduled sql.NullTime
+
+	err := row.Scan(
+		&result.JobID, &result.StartTime, &result.EndTime, &durationMicro,
+		&result.Status, &result.SuccessMsg, &result.ErrorMsg, &output, &namespace, &scheduled, &result.Resumed,
+		&poolWaitMicro, &result.Trigger,
+	)
+	if err != nil {
+		return result, fmt.Errorf("failed to scan result row: %w", err)
+	}
+	result.Namespace = namespace.String
+	result.ScheduledTime = scheduled.Time
+	result.Duration = time.Duration(durationMicro) * time.Microsecond
+	result.PoolWait = time.Duration(poolWaitMicro) * time.Microsecond
+
+	if output.Valid && output.String != "" {
+		if err := json.Unmarshal([]byte(output.String), &result.Output); err != nil {
+			return result, fmt.Errorf("failed to decode result output: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// encodeJSON returns the JSON for a run output or tags, or nil (NULL) if there are none
+func encodeJSON[V any](m map[string]V) (any, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	byts, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON column: %w", err)
+	}
+	return string(byts), nil
+}
+
 type JobRun struct {
 	JobID        string
 	JobName      string
@@ -289,6 +703,20 @@ type JobRun struct {
 	ResultStatus string
 	ErrorMsg     string
 	RunNumber    int
+	Tags         map[string]string // set on main job rows only
+	Namespace    string            // set on main job rows only
+	Timezone     string            // set on main job rows only
+	// ScheduledTime is when a run was due, set on result rows of scheduled runs only
+	ScheduledTime time.Time
+	// Progress, RunID and RunStart describe the job's run in progress, set on main rows of running jobs only
+	Progress *Progress
+	RunID    string
+	RunStart time.Time
+	// Resumed tells a run that resumed an interrupted one, PoolWait how long the run waited
+	// for its resource pools and Trigger what started it, set on result rows only
+	Resumed  bool
+	PoolWait time.Duration
+	Trigger  TriggerSource
 }
 
 type JobRunDBRow struct {
@@ -307,19 +735,29 @@ type JobRunDBRow struct {
 	ResultStatus sql.NullString
 	ErrorMsg     sql.NullString
 	RunNumber    sql.NullInt64
+	Tags         sql.NullString
+	Namespace    sql.NullString
+	Timezone     sql.NullString
+	// ScheduledTime is when a run was due
+	ScheduledTime sql.NullTime
+	Resumed       bool
+	PoolWaitMicro int64
+	Trigger       sql.NullString
 }
 
-// GetJobRunsWithPagination retrieves jobs with limited results per job
-func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map[string]int, error) {
+// GetJobRunsWithPagination retrieves jobs matching the selector with limited results per job
+// A nil or empty selector matches all jobs
+func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int, sel Selector) ([]JobRun, map[string]int, error) {
 	// Map to store total result count per job
 	resultCounts := make(map[string]int)
 
 	// Get total count of results per job
+	countWhere, countArgs := s.nsFilter("namespace")
 	countRows, err := s.db.Query(`
 		SELECT job_id, COUNT(*) as total_count 
-		FROM job_results 
+		FROM job_results `+util.If(countWhere != "", "WHERE "+countWhere, "")+`
 		GROUP BY job_id
-	`)
+	`, countArgs...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get result counts: %w", err)
 	}
@@ -334,6 +772,20 @@ func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map
 		resultCounts[jobID] = count
 	}
 
+	// Restrict jobs (and so their results) to those of the namespace matching the selector
+	conds, selArgs := []string{}, []any{}
+	if !sel.Empty() {
+		cond, args := sel.sqlWhere("tags")
+		conds, selArgs = append(conds, cond), append(selArgs, args...)
+	}
+	if cond, args := s.nsFilter("namespace"); cond != "" {
+		conds, selArgs = append(conds, cond), append(selArgs, args...)
+	}
+	jobsWhere := ""
+	if len(conds) > 0 {
+		jobsWhere = " WHERE " + strings.Join(conds, " AND ")
+	}
+
 	// Build the query with pagination per job
 	// First get all job main rows, then union with limited results per job
 	query := `
@@ -352,8 +804,10 @@ func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map
 			   j.schedule, j.next_run_time, j.status, j.schedule_type, j.created_at, j.updated_at,
 			   NULL::BIGINT as result_id, NULL::TIMESTAMP as start_time, NULL::BIGINT as duration_micro, 
 			   NULL::VARCHAR as result_status, NULL::VARCHAR as error_msg,
-			   0 as row_type, NULL::INT as run_number
-		FROM jobs j
+			   0 as row_type, NULL::INT as run_number, j.tags::VARCHAR as tags, j.namespace, j.timezone,
+			   NULL::TIMESTAMP as scheduled_time, false as resumed, 0::BIGINT as pool_wait_micro,
+			   NULL::VARCHAR as trigger_source
+		FROM jobs j` + jobsWhere + `
 	),
 	ranked_results AS (
 		SELECT r.job_id, NULL as job_name, NULL as frequency, NULL as schedule, 
@@ -362,11 +816,14 @@ func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map
 			   r.result_id, r.start_time, r.duration_micro, r.status as result_status, r.error_msg,
 			   1 as row_type,
 			   ROW_NUMBER() OVER (PARTITION BY r.job_id ORDER BY r.start_time DESC) as rn,
-			   (jc.total_count - ROW_NUMBER() OVER (PARTITION BY r.job_id ORDER BY r.start_time DESC) + 1) as run_number
+			   (jc.total_count - ROW_NUMBER() OVER (PARTITION BY r.job_id ORDER BY r.start_time DESC) + 1) as run_number,
+			   NULL::VARCHAR as tags, NULL::VARCHAR as namespace, NULL::VARCHAR as timezone,
+			   r.scheduled_time, COALESCE(r.resumed, false) as resumed, COALESCE(r.pool_wait_micro, 0) as pool_wait_micro,
+			   r.trigger_source
 		FROM job_results r
 		JOIN jobs j ON r.job_id = j.job_id
 		JOIN job_counts jc ON r.job_id = jc.job_id
-		WHERE r.job_id IN (SELECT job_id FROM jobs)
+		WHERE r.job_id IN (SELECT job_id FROM jobs` + jobsWhere + `)
 	),
 	limited_results AS (
 		SELECT * FROM ranked_results WHERE rn <= ?
@@ -376,17 +833,22 @@ func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map
 		UNION ALL
 		SELECT job_id, job_name, frequency, schedule, next_run_time, status, 
 			   schedule_type, created_at, updated_at, result_id, start_time, 
-			   duration_micro, result_status, error_msg, row_type, run_number
+			   duration_micro, result_status, error_msg, row_type, run_number, tags, namespace, timezone,
+			   scheduled_time, resumed, pool_wait_micro, trigger_source
 		FROM limited_results
 	)
 	SELECT job_id, job_name, frequency, schedule, next_run_time, status,
 		   schedule_type, created_at, updated_at, result_id, start_time, 
-		   duration_micro, result_status, error_msg, run_number
+		   duration_micro, result_status, error_msg, run_number, tags, namespace, timezone, scheduled_time, resumed,
+		   pool_wait_micro, trigger_source
 	FROM all_rows
 	ORDER BY created_at DESC, job_id, row_type, start_time DESC
 	`
 
-	rows, err := s.db.Query(query, resultsPerJob)
+	// The selector args are bound twice - for the main rows and for the results
+	args := append(append(append([]any{}, selArgs...), selArgs...), resultsPerJob)
+
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to execute paginated query: %w", err)
 	}
@@ -402,7 +864,8 @@ func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map
 			&result.JobID, &result.JobName, &result.FreqType, &result.Schedule, &result.NextRunTime, &result.JobStatus,
 			&result.ScheduleType, &result.CreatedAt, &result.UpdatedAt,
 			&result.ResultId, &result.StartTime, &durationMicro,
-			&result.ResultStatus, &result.ErrorMsg, &result.RunNumber,
+			&result.ResultStatus, &result.ErrorMsg, &result.RunNumber, &result.Tags, &result.Namespace, &result.Timezone,
+			&result.ScheduledTime, &result.Resumed, &result.PoolWaitMicro, &result.Trigger,
 		)
 		if err != nil {
 			return nil, nil, serr.Wrap(err, "failed to scan result row")
@@ -423,12 +886,25 @@ func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map
 			ResultStatus: result.ResultStatus.String,
 			ErrorMsg:     result.ErrorMsg.String,
 			RunNumber:    int(result.RunNumber.Int64),
+			Namespace:    result.Namespace.String,
+			Timezone:     result.Timezone.String,
+			// Zero for runs on demand
+			ScheduledTime: result.ScheduledTime.Time,
+			Resumed:       result.Resumed,
+			PoolWait:      time.Duration(result.PoolWaitMicro) * time.Microsecond,
+			Trigger:       TriggerSource(result.Trigger.String),
 		}
 
 		if durationMicro.Valid {
 			jr.Duration = time.Duration(durationMicro.Int64) * time.Microsecond
 		}
 
+		if result.Tags.Valid && result.Tags.String != "" {
+			if err := json.Unmarshal([]byte(result.Tags.String), &jr.Tags); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode job tags: %w", err)
+			}
+		}
+
 		results = append(results, jr)
 	}
 
@@ -438,20 +914,22 @@ func (s *DuckDBStore) GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map
 // GetJobResultsPaginated retrieves paginated results for a specific job
 func (s *DuckDBStore) GetJobResultsPaginated(jobID string, offset, limit int) ([]JobResult, int, error) {
 	// Get total count
+	where, args := s.andNs("job_id = ?", []any{jobID}, "namespace")
+
 	var totalCount int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM job_results WHERE job_id = ?`, jobID).Scan(&totalCount)
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM job_results WHERE `+where, args...).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
 	}
 
 	// Get paginated results
 	rows, err := s.db.Query(`
-		SELECT job_id, start_time, end_time, duration_micro, status, success_msg, error_msg
+		SELECT `+jobResultColumns+`
 		FROM job_results 
-		WHERE job_id = ?
+		WHERE `+where+`
 		ORDER BY start_time DESC
 		LIMIT ? OFFSET ?
-	`, jobID, limit, offset)
+	`, append(args, limit, offset)...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get job results: %w", err)
 	}
@@ -459,16 +937,10 @@ func (s *DuckDBStore) GetJobResultsPaginated(jobID string, offset, limit int) ([
 
 	results := make([]JobResult, 0, limit)
 	for rows.Next() {
-		var result JobResult
-		var durationMicro int64
-		err := rows.Scan(
-			&result.JobID, &result.StartTime, &result.EndTime, &durationMicro,
-			&result.Status, &result.SuccessMsg, &result.ErrorMsg,
-		)
+		result, err := scanJobResult(rows)
 		if err != nil {
-			return nil, 0, fmt.Errorf("failed to scan result row: %w", err)
+			return nil, 0, err
 		}
-		result.Duration = time.Duration(durationMicro) * time.Microsecond
 		results = append(results, result)
 	}
 
@@ -478,6 +950,13 @@ func (s *DuckDBStore) GetJobResultsPaginated(jobID string, offset, limit int) ([
 // GetJobRuns retrieves historical results for a job
 // Set limit to 0 for all
 func (s *DuckDBStore) GetJobRuns(limit int) ([]JobRun, error) {
+	// Parameters bind to the final statement, so the namespace is applied there
+	where, args := "", []any{}
+	if s.namespace != "" {
+		where = "where job_id in (select job_id from jobs where namespace = ?)"
+		args = append(args, s.namespace)
+	}
+
 	rows, err := s.db.Query(`
 drop table if exists runs;
 create temp table runs as (with results as (
@@ -499,8 +978,8 @@ create temp table runs as (with results as (
               j.schedule_type, j.created_at, j.updated_at,
                null as result_id, null as start_time, null as duration_micro, null as result_status, null as error_msg
       from jobs j);
-select * from runs order by created_at desc, result_id desc nulls first
-  LIMIT ?`, limit)
+select * from runs `+where+` order by created_at desc, result_id desc nulls first
+  LIMIT ?`, append(args, limit)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job results: %w", err)
 	}
@@ -555,10 +1034,10 @@ select * from runs order by created_at desc, result_id desc nulls first
 func (s *DuckDBStore) CleanupJobResults(olderThan time.Duration) error {
 	cutoffTime := time.Now().Add(-olderThan)
 
+	where, args := s.andNs("end_time < ?", []any{cutoffTime}, "namespace")
 	result, err := s.db.Exec(`
 		DELETE FROM job_results 
-		WHERE end_time < ?
-	`, cutoffTime)
+		WHERE `+where, args...)
 
 	if err != nil {
 		return fmt.Errorf("failed to cleanup old job results: %w", err)
@@ -570,13 +1049,18 @@ func (s *DuckDBStore) CleanupJobResults(olderThan time.Duration) error {
 	}
 
 	if rowsAffected > 0 {
-		fmt.Printf("Cleaned up %d job results older than %s\n", rowsAffected, olderThan)
+		fmt.Printf("Cleaned up %d job results older than %s%s\n", rowsAffected, olderThan,
+			util.If(s.namespace != "", " in namespace "+s.namespace, ""))
 	}
 
 	return nil
 }
 
 // Close closes the database connection
+// Namespace views share the connection of their parent store, so closing them does nothing
 func (s *DuckDBStore) Close() error {
+	if s.namespace != "" {
+		return nil
+	}
 	return s.db.Close()
 }
@@ -0,0 +1,381 @@
+package jobpro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// ExportFormat is the file format of an export
+type ExportFormat string
+
+const (
+	ExportJSON    ExportFormat = "json"
+	ExportCSV     ExportFormat = "csv"
+	ExportParquet ExportFormat = "parquet"
+)
+
+// ExportTable selects which table is written by CSV and Parquet exports,
+// as those formats hold a single table per file. JSON exports always include both.
+type ExportTable string
+
+const (
+	ExportJobs    ExportTable = "jobs"
+	ExportResults ExportTable = "results"
+)
+
+// ExportOptions controls what is exported
+type ExportOptions struct {
+	Format ExportFormat
+	Table  ExportTable // CSV and Parquet only, defaults to results
+	From   time.Time   // Include results that started at or after From (zero for no lower bound)
+	To     time.Time   // Include results that started before To (zero for no upper bound)
+}
+
+// Validate checks the format and table of an export
+func (o ExportOptions) Validate() error {
+	switch o.Format {
+	case ExportJSON, ExportCSV, ExportParquet, "":
+	default:
+		return fmt.Errorf("unsupported export format: %q", o.Format)
+	}
+	switch o.Table {
+	case ExportJobs, ExportResults, "":
+	default:
+		return fmt.Errorf("unsupported export table: %q", o.Table)
+	}
+	return nil
+}
+
+// ExportBundle is the JSON export document - job definitions plus their results
+type ExportBundle struct {
+	ExportedAt time.Time
+	From       time.Time
+	To         time.Time
+	Jobs       []JobDef
+	Results    []JobResult
+}
+
+// ImportOptions controls how an ExportBundle is applied to a store
+type ImportOptions struct {
+	Overwrite     bool // Overwrite existing definitions that differ from the imported ones
+	ReplayResults bool // Record the bundle's results through RecordJobResult
+}
+
+// ImportConflict describes an imported job definition that differs from the existing one
+type ImportConflict struct {
+	JobID      string
+	Field      string
+	Existing   string
+	Incoming   string
+	Resolution string // "skipped" or "overwritten"
+}
+
+// ImportReport summarizes the outcome of an import
+type ImportReport struct {
+	JobsCreated     int
+	JobsUpdated     int
+	JobsUnchanged   int
+	JobsSkipped     int
+	ResultsReplayed int
+	ResultsSkipped  int // duplicates, or results of jobs that were skipped
+	Conflicts       []ImportConflict
+}
+
+// GetJobResultsInRange retrieves the results of all jobs that started within [from, to)
+// Zero times leave that side of the range open
+func (s *DuckDBStore) GetJobResultsInRange(from, to time.Time) ([]JobResult, error) {
+	where, args := s.rangeFilter(from, to)
+
+	rows, err := s.db.Query(`
+		SELECT `+jobResultColumns+`
+		FROM job_results`+where+`
+		ORDER BY job_id, start_time
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job results in range: %w", err)
+	}
+	defer rows.Close()
+
+	results := []JobResult{}
+	for rows.Next() {
+		result, err := scanJobResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// Export writes job definitions and the results in the requested range in the requested format
+func (s *DuckDBStore) Export(opts ExportOptions) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch opts.Format {
+	case ExportJSON, "":
+		bundle, err := s.exportBundle(opts)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(bundle, "", "  ")
+
+	case ExportCSV, ExportParquet:
+		return s.exportCopy(opts)
+
+	default:
+		return nil, serr.F("unsupported export format: %q", opts.Format)
+	}
+}
+
+// exportBundle collects all job definitions and the results in range
+func (s *DuckDBStore) exportBundle(opts ExportOptions) (ExportBundle, error) {
+	bundle := ExportBundle{ExportedAt: time.Now().UTC(), From: opts.From, To: opts.To}
+
+	jobs, err := s.ListJobs("", "", nil)
+	if err != nil {
+		return bundle, err
+	}
+	bundle.Jobs = jobs
+
+	bundle.Results, err = s.GetJobResultsInRange(opts.From, opts.To)
+	if err != nil {
+		return bundle, err
+	}
+
+	return bundle, nil
+}
+
+// exportCopy uses DuckDB's COPY to write a single table to a temp file in CSV or Parquet and returns its contents
+func (s *DuckDBStore) exportCopy(opts ExportOptions) ([]byte, error) {
+	// COPY does not take bind parameters, so filters are inlined as literals
+	var query string
+	switch opts.Table {
+	case ExportJobs:
+		where, args := s.nsFilter("namespace")
+		if where != "" {
+			where = " WHERE " + where
+		}
+		query = `SELECT job_id, job_name, schedule_type, schedule, next_run_time,
+		                status, created_at, updated_at, tags, namespace, timezone,
+		                calendars, calendar_policy, edits, definition
+		         FROM jobs` + inlineArgs(where, args) + ` ORDER BY job_id`
+	case ExportResults, "":
+		where, args := s.rangeFilter(opts.From, opts.To)
+		query = `SELECT r.result_id, r.job_id, j.job_name, r.start_time, r.end_time,
+		                r.duration_micro, r.status, r.success_msg, r.error_msg, r.output, r.namespace,
+		                r.scheduled_time, COALESCE(r.resumed, false) AS resumed,
+		                COALESCE(r.pool_wait_micro, 0) AS pool_wait_micro, r.trigger_source
+		         FROM (SELECT * FROM job_results` + inlineArgs(where, args) + `) r
+		         LEFT JOIN jobs j ON r.job_id = j.job_id
+		         ORDER BY r.job_id, r.start_time`
+	default:
+		return nil, serr.F("unsupported export table: %q", opts.Table)
+	}
+
+	tmp, err := os.CreateTemp("", "jobpro-export-*."+string(opts.Format))
+	if err != nil {
+		return nil, serr.Wrap(err, "failed to create export file")
+	}
+	tmpPath := tmp.Name()
+	_ = tmp.Close()
+	defer os.Remove(tmpPath)
+
+	copyOpts := "FORMAT CSV, HEADER"
+	if opts.Format == ExportParquet {
+		copyOpts = "FORMAT PARQUET"
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(`COPY (%s) TO '%s' (%s)`,
+		query, strings.ReplaceAll(tmpPath, "'", "''"), copyOpts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", opts.Format, err)
+	}
+
+	return os.ReadFile(tmpPath)
+}
+
+// rangeFilter returns a where clause (possibly empty) and args restricting start_time to [from, to)
+// and results to the store's namespace
+func (s *DuckDBStore) rangeFilter(from, to time.Time) (string, []any) {
+	where := []string{}
+	args := []any{}
+	if !from.IsZero() {
+		where = append(where, "start_time >= ?")
+		args = append(args, from.UTC())
+	}
+	if !to.IsZero() {
+		where = append(where, "start_time < ?")
+		args = append(args, to.UTC())
+	}
+	if cond, nsArgs := s.nsFilter("namespace"); cond != "" {
+		where = append(where, cond)
+		args = append(args, nsArgs...)
+	}
+	if len(where) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// inlineArgs replaces the placeholders of a where clause with SQL literals of args, in order
+func inlineArgs(where string, args []any) string {
+	for _, arg := range args {
+		var lit string
+		switch v := arg.(type) {
+		case time.Time:
+			lit = "TIMESTAMP '" + v.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+		default:
+			lit = "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+		}
+		where = strings.Replace(where, "?", lit, 1)
+	}
+	return where
+}
+
+// ReadExportBundle decodes a JSON export
+func ReadExportBundle(r io.Reader) (ExportBundle, error) {
+	var bundle ExportBundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return bundle, serr.Wrap(err, "failed to decode export bundle")
+	}
+	return bundle, nil
+}
+
+// ImportBundle upserts the bundle's job definitions through SaveJob and optionally
+// replays its results through RecordJobResult. Definitions that differ from
+// existing ones are reported as conflicts and only applied if opts.Overwrite is set.
+// Results already present (same job and start time) are skipped. Command jobs are imported,
+// but only loaded by a manager allowing them (see AllowCommandJobs).
+func ImportBundle(store JobStore, bundle ExportBundle, opts ImportOptions) (ImportReport, error) {
+	return importBundle(store, bundle, opts, true, nil)
+}
+
+// importBundle is ImportBundle refusing bundles with command jobs unless commands is set,
+// with a hook to refuse definitions that can't be changed (e.g. loaded jobs)
+func importBundle(store JobStore, bundle ExportBundle, opts ImportOptions, commands bool,
+	locked func(jobID string) bool) (ImportReport, error) {
+	report := ImportReport{}
+	imported := make(map[string]bool, len(bundle.Jobs)) // jobs whose results may be replayed
+
+	// Refused before anything is imported, so the import is all or nothing
+	for _, incoming := range bundle.Jobs {
+		if !commands && incoming.Definition != nil && incoming.Definition.Command != "" {
+			return report, fmt.Errorf("%w: job %s runs a command", ErrCommandJobsNotAllowed, incoming.JobID)
+		}
+	}
+
+	for _, incoming := range bundle.Jobs {
+		if incoming.JobID == "" {
+			return report, serr.New("import contains a job without an id")
+		}
+
+		existing, err := store.GetJob(incoming.JobID)
+		if err != nil { // not found - create it
+			if err := store.SaveJob(incoming); err != nil {
+				return report, serr.Wrap(err, "failed to import job", "jobID", incoming.JobID)
+			}
+			report.JobsCreated++
+			imported[incoming.JobID] = true
+			continue
+		}
+
+		conflicts := diffJobDefs(existing, incoming)
+		if len(conflicts) == 0 {
+			report.JobsUnchanged++
+			imported[incoming.JobID] = true
+			continue
+		}
+
+		resolution := "skipped"
+		if opts.Overwrite && (locked == nil || !locked(incoming.JobID)) {
+			resolution = "overwritten"
+		}
+		for i := range conflicts {
+			conflicts[i].Resolution = resolution
+		}
+		report.Conflicts = append(report.Conflicts, conflicts...)
+
+		if resolution == "skipped" {
+			report.JobsSkipped++
+			continue
+		}
+
+		incoming.CreatedAt = existing.CreatedAt
+		incoming.UpdatedAt = time.Now().UTC()
+		if err := store.SaveJob(incoming); err != nil {
+			return report, serr.Wrap(err, "failed to import job", "jobID", incoming.JobID)
+		}
+		report.JobsUpdated++
+		imported[incoming.JobID] = true
+	}
+
+	if !opts.ReplayResults || len(bundle.Results) == 0 {
+		return report, nil
+	}
+
+	// Load the existing results over the bundle's span so duplicates can be skipped
+	from, to := bundle.Results[0].StartTime, bundle.Results[0].StartTime
+	for _, r := range bundle.Results {
+		if r.StartTime.Before(from) {
+			from = r.StartTime
+		}
+		if r.StartTime.After(to) {
+			to = r.StartTime
+		}
+	}
+	existingResults, err := store.GetJobResultsInRange(from, to.Add(time.Microsecond))
+	if err != nil {
+		return report, err
+	}
+	seen := make(map[string]bool, len(existingResults))
+	for _, r := range existingResults {
+		seen[resultKey(r)] = true
+	}
+
+	for _, r := range bundle.Results {
+		if !imported[r.JobID] || seen[resultKey(r)] {
+			report.ResultsSkipped++
+			continue
+		}
+		if err := store.RecordJobResult(r); err != nil {
+			return report, serr.Wrap(err, "failed to replay job result", "jobID", r.JobID)
+		}
+		seen[resultKey(r)] = true
+		report.ResultsReplayed++
+	}
+
+	return report, nil
+}
+
+// diffJobDefs lists the user-defined fields that differ between two job definitions
+func diffJobDefs(existing, incoming JobDef) []ImportConflict {
+	var conflicts []ImportConflict
+	check := func(field, a, b string) {
+		if a != b {
+			conflicts = append(conflicts, ImportConflict{
+				JobID: incoming.JobID, Field: field, Existing: a, Incoming: b,
+			})
+		}
+	}
+	check("JobName", existing.JobName, incoming.JobName)
+	check("SchedType", string(existing.SchedType), string(incoming.SchedType))
+	check("Schedule", existing.Schedule, incoming.Schedule)
+	check("Tags", FormatTags(existing.Tags), FormatTags(incoming.Tags))
+	check("Timezone", existing.Timezone, incoming.Timezone)
+	check("Calendars", strings.Join(existing.Calendars, ","), strings.Join(incoming.Calendars, ","))
+	return conflicts
+}
+
+// resultKey identifies a result for duplicate detection (timestamps are stored at microsecond precision)
+func resultKey(r JobResult) string {
+	return r.JobID + "|" + r.StartTime.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
@@ -0,0 +1,107 @@
+package jobpro
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer src.Close()
+
+	now := time.Now().UTC()
+	for _, id := range []string{"job-a", "job-b"} {
+		if err := src.SaveJob(JobDef{JobID: id, JobName: "Job " + id, SchedType: Periodic,
+			Schedule: "0 * * * * *", Status: StatusCreated, CreatedAt: now, UpdatedAt: now, NextRunTime: now}); err != nil {
+			t.Fatalf("Failed to save job: %v", err)
+		}
+		for i := 1; i <= 3; i++ {
+			start := now.Add(-time.Duration(i) * time.Hour)
+			if err := src.RecordJobResult(JobResult{JobID: id, StartTime: start, EndTime: start.Add(time.Second),
+				Duration: time.Second, Status: StatusComplete}); err != nil {
+				t.Fatalf("Failed to record result: %v", err)
+			}
+		}
+	}
+
+	// Only the two most recent results of each job fall in range
+	data, err := src.Export(ExportOptions{Format: ExportJSON, From: now.Add(-150 * time.Minute)})
+	if err != nil {
+		t.Fatalf("Failed to export JSON: %v", err)
+	}
+	bundle, err := ReadExportBundle(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+	if len(bundle.Jobs) != 2 || len(bundle.Results) != 4 {
+		t.Fatalf("Expected 2 jobs and 4 results, got %d and %d", len(bundle.Jobs), len(bundle.Results))
+	}
+
+	csv, err := src.Export(ExportOptions{Format: ExportCSV, Table: ExportResults})
+	if err != nil {
+		t.Fatalf("Failed to export CSV: %v", err)
+	}
+	if lines := strings.Count(strings.TrimSpace(string(csv)), "\n"); lines != 6 {
+		t.Errorf("Expected header plus 6 result lines, got %d lines", lines+1)
+	}
+
+	parquet, err := src.Export(ExportOptions{Format: ExportParquet, Table: ExportJobs})
+	if err != nil {
+		t.Fatalf("Failed to export Parquet: %v", err)
+	}
+	if !bytes.HasPrefix(parquet, []byte("PAR1")) {
+		t.Errorf("Expected Parquet magic bytes")
+	}
+	for _, opts := range []ExportOptions{{Format: "xml"}, {Format: ExportCSV, Table: "runs"}, {Table: "runs"}} {
+		if _, err := src.Export(opts); err == nil || opts.Validate() == nil {
+			t.Errorf("Expected export options %+v to be refused", opts)
+		}
+	}
+
+	// Import into a store holding a conflicting definition of job-b
+	dst, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer dst.Close()
+	if err := dst.SaveJob(JobDef{JobID: "job-b", JobName: "Job job-b", SchedType: Periodic,
+		Schedule: "*/5 * * * * *", Status: StatusCreated, CreatedAt: now, UpdatedAt: now, NextRunTime: now}); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	report, err := ImportBundle(dst, bundle, ImportOptions{ReplayResults: true})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.JobsCreated != 1 || report.JobsSkipped != 1 || len(report.Conflicts) != 1 {
+		rpt, _ := json.Marshal(report)
+		t.Fatalf("Unexpected import report: %s", rpt)
+	}
+	if report.Conflicts[0].Field != "Schedule" || report.Conflicts[0].Resolution != "skipped" {
+		t.Errorf("Expected a skipped Schedule conflict, got %+v", report.Conflicts[0])
+	}
+	if report.ResultsReplayed != 2 || report.ResultsSkipped != 2 {
+		t.Errorf("Expected 2 replayed and 2 skipped results, got %d and %d",
+			report.ResultsReplayed, report.ResultsSkipped)
+	}
+
+	// Importing again with overwrite updates job-b and skips already replayed results
+	report, err = ImportBundle(dst, bundle, ImportOptions{Overwrite: true, ReplayResults: true})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.JobsUpdated != 1 || report.JobsUnchanged != 1 || report.ResultsReplayed != 2 || report.ResultsSkipped != 2 {
+		rpt, _ := json.Marshal(report)
+		t.Errorf("Unexpected overwrite import report: %s", rpt)
+	}
+	jobB, err := dst.GetJob("job-b")
+	if err != nil || jobB.Schedule != "0 * * * * *" {
+		t.Errorf("Expected job-b schedule to be overwritten, got %q (%v)", jobB.Schedule, err)
+	}
+}
@@ -30,6 +30,11 @@ func Init(dbFilePath string) (manager *DefaultJobManager) {
 	// Initialize job manager
 	jobMgr := NewJobManager(store)
 
+	// Results that can't be recorded are spooled next to the database, and recorded on the next start
+	if dbFilePath != "" {
+		jobMgr.SetResultSpool(dbFilePath + ".results-spool")
+	}
+
 	shutdown.RegisterHook(func(gracePeriod time.Duration) error {
 		err := jobMgr.Shutdown(gracePeriod)
 		if err != nil {
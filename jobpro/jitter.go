@@ -0,0 +1,93 @@
+package jobpro
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+)
+
+// JitteredJob is implemented by jobs whose scheduled runs are delayed,
+// so that jobs on the same schedule don't all fire at once
+type JitteredJob interface {
+	// Jitter is the maximum random delay of a scheduled run
+	Jitter() time.Duration
+	// Spread is a window scheduled runs are delayed within, by an offset hashed from the job Id.
+	// The offset is the same for every run, so the job keeps a steady interval.
+	Spread() time.Duration
+}
+
+// JitterConfig is the delay of scheduled runs of jobs setting neither jitter nor spread
+type JitterConfig struct {
+	Jitter time.Duration // maximum random delay
+	Spread time.Duration // window to spread runs over by job Id
+}
+
+// SetJitter sets the default delay of scheduled runs
+func (m *DefaultJobManager) SetJitter(cfg JitterConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jitter = cfg
+}
+
+// runDelay returns how long to hold a scheduled run of the job: its spread offset plus a random jitter
+// The caller must hold m.mu
+func (m *DefaultJobManager) runDelay(id string) time.Duration {
+	jitter, spread := m.jitter.Jitter, m.jitter.Spread
+	if jj, ok := m.jobs[id].(JitteredJob); ok && (jj.Jitter() > 0 || jj.Spread() > 0) {
+		jitter, spread = jj.Jitter(), jj.Spread()
+	}
+
+	var delay time.Duration
+	if spread > 0 {
+		delay = spreadOffset(id, spread)
+	}
+	if jitter > 0 {
+		delay += rand.N(jitter)
+	}
+	return delay
+}
+
+// spreadOffset deterministically maps a job Id to an offset within the window
+func spreadOffset(id string, window time.Duration) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return time.Duration(h.Sum64() % uint64(window)).Truncate(time.Millisecond)
+}
+
+// runTick runs a cron tick of a periodic job, after the job's run delay.
+// A tick arriving while the previous one is still delayed is dropped.
+func (m *DefaultJobManager) runTick(id string) {
+	// Cron fires on the second, just after the scheduled time
+	scheduled := time.Now().Truncate(time.Second)
+
+	m.mu.Lock()
+	delay := m.runDelay(id)
+	if delay <= 0 {
+		m.mu.Unlock()
+		m.runScheduled(id, scheduled)
+		return
+	}
+	defer m.mu.Unlock()
+
+	if _, pending := m.scheduledJobs[id]; pending || m.shutdown {
+		return
+	}
+	m.scheduledJobs[id] = time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		delete(m.scheduledJobs, id)
+		m.mu.Unlock()
+
+		m.runScheduled(id, scheduled)
+	})
+}
+
+// cancelPendingRun stops a delayed or deferred run of the job, reporting whether there was one
+// The caller must hold m.mu
+func (m *DefaultJobManager) cancelPendingRun(id string) bool {
+	timer, pending := m.scheduledJobs[id]
+	if pending {
+		timer.Stop()
+		delete(m.scheduledJobs, id)
+	}
+	return pending
+}
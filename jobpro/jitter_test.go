@@ -0,0 +1,119 @@
+package jobpro
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpreadOffset(t *testing.T) {
+	window := 5 * time.Minute
+	for _, id := range []string{"a", "report", "cleanup-logs", "tenant1/sync"} {
+		offset := spreadOffset(id, window)
+		if offset < 0 || offset >= window {
+			t.Errorf("Offset of %q should be within the window, got %s", id, offset)
+		}
+		if again := spreadOffset(id, window); again != offset {
+			t.Errorf("Offset of %q should be deterministic, got %s then %s", id, offset, again)
+		}
+	}
+
+	if spreadOffset("report", window) == spreadOffset("cleanup-logs", window) {
+		t.Error("Expected different jobs to get different offsets")
+	}
+}
+
+func TestRunDelay(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+	mgr.SetJitter(JitterConfig{Spread: time.Hour})
+
+	for _, jc := range []JobConfig{
+		{Id: "global", Name: "Global", IsPeriodic: true, Schedule: "0 0 * * * *"},
+		{Id: "own-spread", Name: "Own Spread", IsPeriodic: true, Schedule: "0 0 * * * *", Spread: 60},
+		{Id: "own-jitter", Name: "Own Jitter", IsPeriodic: true, Schedule: "0 0 * * * *", Jitter: 10},
+	} {
+		jc.JobFunction = func() error { return nil }
+		if err := setupJob(mgr, jc); err != nil {
+			t.Fatalf("Failed to setup job %s: %v", jc.Id, err)
+		}
+	}
+
+	delay := func(id string) time.Duration {
+		mgr.mu.Lock()
+		defer mgr.mu.Unlock()
+		return mgr.runDelay(id)
+	}
+
+	if got, want := delay("global"), spreadOffset("global", time.Hour); got != want {
+		t.Errorf("Expected the global spread offset %s, got %s", want, got)
+	}
+	// Per-job settings replace the global ones
+	if got, want := delay("own-spread"), spreadOffset("own-spread", time.Minute); got != want {
+		t.Errorf("Expected the job's own spread offset %s, got %s", want, got)
+	}
+	for range 20 {
+		if got := delay("own-jitter"); got < 0 || got >= 10*time.Second {
+			t.Fatalf("Expected a jitter below 10s, got %s", got)
+		}
+	}
+}
+
+func TestScheduledTimeRecorded(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	jc := JobConfig{Id: "spread-once", Name: "Spread Once", Schedule: "in 1s", Spread: 2, AutoStart: true,
+		JobFunction: func() error { return nil }}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	jobDef, err := store.GetJob(jc.Id)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+
+	var results []JobResult
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if results, err = store.GetJobResults(jc.Id, 1); err == nil && len(results) > 0 {
+			break
+		}
+	}
+	if len(results) == 0 {
+		t.Fatal("Expected the job to have run")
+	}
+
+	result := results[0]
+	if !result.ScheduledTime.Equal(jobDef.NextRunTime) {
+		t.Errorf("Expected scheduled time %s, got %s", jobDef.NextRunTime, result.ScheduledTime)
+	}
+	offset := spreadOffset(jc.Id, 2*time.Second)
+	if lateness := result.Lateness(); lateness < offset || lateness > offset+time.Second {
+		t.Errorf("Expected lateness of about %s, got %s", offset, lateness)
+	}
+
+	// Runs on demand have no scheduled time
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if results, err = store.GetJobResults(jc.Id, 2); err == nil && len(results) > 1 {
+			break
+		}
+	}
+	if len(results) < 2 {
+		t.Fatal("Expected the triggered run to have a result")
+	}
+	if !results[0].ScheduledTime.IsZero() || results[0].Lateness() != 0 {
+		t.Errorf("Expected no scheduled time on a run on demand, got %s", results[0].ScheduledTime)
+	}
+}
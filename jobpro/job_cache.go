@@ -0,0 +1,83 @@
+package jobpro
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// jobCache holds the job definitions read from the store, so hot paths such as recording results and
+// scheduled ticks don't query DuckDB for them. A store and its namespace views share one cache.
+// Each write to a job through the store updates or drops its entry.
+type jobCache struct {
+	mu   sync.RWMutex
+	jobs map[string]JobDef
+	// gen is bumped by every write, so a definition read before a write is not cached after it
+	gen uint64
+}
+
+func newJobCache() *jobCache {
+	return &jobCache{jobs: make(map[string]JobDef)}
+}
+
+// get returns a copy of the cached definition of a job
+func (c *jobCache) get(id string) (JobDef, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	job, ok := c.jobs[id]
+	if !ok {
+		return JobDef{}, false
+	}
+	return job.clone(), true
+}
+
+// generation returns the current generation, to be passed to put with a definition read from the store
+func (c *jobCache) generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.gen
+}
+
+// put caches a definition read from the store, unless a job was written since gen
+func (c *jobCache) put(job JobDef, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		c.jobs[job.JobID] = job.clone()
+	}
+}
+
+// update applies a write to the cached definition of a job, if it is cached
+func (c *jobCache) update(id string, apply func(*JobDef)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if job, ok := c.jobs[id]; ok {
+		apply(&job)
+		c.jobs[id] = job
+	}
+}
+
+// drop removes a job's definition, to be read from the store again
+func (c *jobCache) drop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	delete(c.jobs, id)
+}
+
+// clone returns a copy of the definition sharing no maps or slices with it
+func (j JobDef) clone() JobDef {
+	j.Tags = maps.Clone(j.Tags)
+	j.Calendars = slices.Clone(j.Calendars)
+	j.Edits.Tags = maps.Clone(j.Edits.Tags)
+	j.Edits.Calendars = slices.Clone(j.Edits.Calendars)
+	if j.Definition != nil {
+		def := *j.Definition
+		def.Tags = maps.Clone(def.Tags)
+		def.Calendars = slices.Clone(def.Calendars)
+		def.Pools = slices.Clone(def.Pools)
+		j.Definition = &def
+	}
+	return j
+}
@@ -0,0 +1,64 @@
+package jobpro
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobCache(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC()
+	job := JobDef{JobID: "cached", JobName: "Cached", SchedType: OneTime, Status: StatusCreated,
+		CreatedAt: now, UpdatedAt: now, NextRunTime: now, Namespace: "team-a", Tags: map[string]string{"env": "dev"}}
+	if err := store.SaveJob(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	// Changing a returned definition doesn't change the cached one
+	first, err := store.GetJob(job.JobID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	first.Tags["env"] = "prod"
+	if again, _ := store.GetJob(job.JobID); again.Tags["env"] != "dev" {
+		t.Errorf("Expected the cached tags to be unchanged, got %v", again.Tags)
+	}
+
+	// Writes show in the next read
+	if err := store.UpdateJobStatus(job.JobID, StatusComplete); err != nil {
+		t.Fatalf("Failed to update job status: %v", err)
+	}
+	if got, _ := store.GetJob(job.JobID); got.Status != StatusComplete {
+		t.Errorf("Expected status %s, got %s", StatusComplete, got.Status)
+	}
+	job.JobName = "Renamed"
+	if err := store.SaveJob(job); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+	if got, _ := store.GetJob(job.JobID); got.JobName != "Renamed" {
+		t.Errorf("Expected the saved name, got %q", got.JobName)
+	}
+
+	// A namespace view doesn't see the cached jobs of other namespaces
+	if _, err := store.ForNamespace("team-b").GetJob(job.JobID); err == nil {
+		t.Error("Expected the job to be hidden from another namespace")
+	}
+	if err := store.ForNamespace("team-b").UpdateJobStatus(job.JobID, StatusFailed); err != nil {
+		t.Fatalf("Failed to update job status: %v", err)
+	}
+	if got, _ := store.GetJob(job.JobID); got.Status != job.Status {
+		t.Errorf("Expected another namespace's update to have no effect, got %s", got.Status)
+	}
+
+	if err := store.DeleteJob(job.JobID); err != nil {
+		t.Fatalf("Failed to delete job: %v", err)
+	}
+	if _, err := store.GetJob(job.JobID); err == nil {
+		t.Error("Expected a deleted job not to be found")
+	}
+}
@@ -2,15 +2,17 @@ package jobpro
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
 // Stats represents runtime metrics for a job execution
 type Stats struct {
-	StartTimeUTC time.Time     // When the job started
-	Duration     time.Duration // How long the job ran
-	SuccessMsg   string        // Message on success
-	ErrorTrace   error         // Error details, nil on success
+	StartTimeUTC time.Time      // When the job started
+	Duration     time.Duration  // How long the job ran
+	SuccessMsg   string         // Message on success
+	ErrorTrace   error          `json:"-"` // Error details, nil on success
+	Output       map[string]any // Structured output of the run (record counts, bytes processed, etc.)
 }
 
 // JobStatus represents the current state of a job
@@ -24,9 +26,33 @@ const (
 	StatusStopped   JobStatus = "stopped"
 	StatusComplete  JobStatus = "complete"
 	StatusFailed    JobStatus = "failed"
+	// StatusCancelled is the status of a one-time job stopped before it ran,
+	// and the result status of a run stopped by an operator
 	StatusCancelled JobStatus = "cancelled"
+	// StatusTimedOut is the result status of a run cancelled for exceeding the job's MaxRunTime
+	StatusTimedOut JobStatus = "timed_out"
+	// StatusSkippedCalendar is the result status of a scheduled run that fell in a calendar blackout window
+	StatusSkippedCalendar JobStatus = "skipped_calendar"
+	// StatusSkippedOverlap is the result status of a run not started because the previous run of the job was still going
+	StatusSkippedOverlap JobStatus = "skipped_overlap"
+	// StatusSkippedPool is the result status of a run not started because a resource pool of the job was exhausted
+	StatusSkippedPool JobStatus = "skipped_pool"
+	// StatusInterruptedByShutdown is the result status of a run cancelled by the job manager shutting down
+	StatusInterruptedByShutdown JobStatus = "interrupted_by_shutdown"
 )
 
+// ResultStatuses are the terminal statuses recorded in job results
+var ResultStatuses = []JobStatus{StatusComplete, StatusFailed, StatusTimedOut, StatusCancelled,
+	StatusInterruptedByShutdown, StatusSkippedOverlap, StatusSkippedCalendar, StatusSkippedPool}
+
+// Skipped reports whether the status is that of a run which did not start
+func (s JobStatus) Skipped() bool {
+	return strings.HasPrefix(string(s), "skipped_")
+}
+
+// DefaultNamespace is the namespace of jobs that are not given one
+const DefaultNamespace = "default"
+
 // FreqType defines whether a job runs once or periodically
 type FreqType string
 
@@ -47,6 +73,17 @@ type Job interface {
 	Type() FreqType
 }
 
+// NamespacedJob is implemented by jobs that belong to a namespace (tenant)
+type NamespacedJob interface {
+	Namespace() string
+}
+
+// TaggedJob is implemented by jobs that carry tags (labels)
+// Tags are saved with the job definition when the job is set up
+type TaggedJob interface {
+	Tags() map[string]string
+}
+
 // JobDef contains metadata about a job
 type JobDef struct {
 	JobID     string   // Unique identifier
@@ -54,21 +91,49 @@ type JobDef struct {
 	SchedType FreqType // Type of schedule
 	// Cron expression for periodic jobs or time.Time to run for one-time jobs
 	Schedule    string
-	NextRunTime time.Time // When to next run this job
-	Status      JobStatus // Current status
-	CreatedAt   time.Time // When the job was created
-	UpdatedAt   time.Time // When the job was last updated
+	NextRunTime time.Time         // When to next run this job
+	Status      JobStatus         // Current status
+	CreatedAt   time.Time         // When the job was created
+	UpdatedAt   time.Time         // When the job was last updated
+	Tags        map[string]string // Labels used to select jobs, e.g. team=etl
+	Namespace   string            // Tenant owning the job, DefaultNamespace if not set
+	Timezone    string            // IANA timezone of the schedule, server local time if not set
+	// Calendars whose blackout windows the job's scheduled runs are skipped or deferred in
+	Calendars      []string
+	CalendarPolicy CalendarPolicy // CalendarSkip (default) or CalendarDefer
+	// Edits are the settings changed with UpdateJob, applied over the job's config when it is set up
+	Edits JobSettings
+	// Definition is the config of a job created with CreateJob, which LoadJobs sets up again
+	Definition *JobConfig
 }
 
 // JobResult contains the outcome of a job execution
 type JobResult struct {
-	JobID      string        // Id of the job
-	StartTime  time.Time     // When the job started
-	EndTime    time.Time     // When the job completed
-	Duration   time.Duration // How long it took
-	Status     JobStatus     // Outcome status
-	SuccessMsg string        // Success message if any
-	ErrorMsg   string        // Error message if any
+	JobID      string         // Id of the job
+	StartTime  time.Time      // When the job started
+	EndTime    time.Time      // When the job completed
+	Duration   time.Duration  // How long it took
+	Status     JobStatus      // Outcome status
+	SuccessMsg string         // Success message if any
+	ErrorMsg   string         // Error message if any
+	Output     map[string]any // Structured output of the run, stored as JSON
+	Namespace  string         // Namespace of the job
+	// ScheduledTime is when the run was due, before any jitter or spread delay. Zero for runs on demand.
+	ScheduledTime time.Time
+	// Resumed marks a run that resumed one interrupted by a shutdown or crash, or carried on from its checkpoint
+	Resumed bool
+	// PoolWait is how long the run waited for the resource pools of its job before it started
+	PoolWait time.Duration
+	// Trigger tells what started the run: its schedule, an operator, a webhook or the resumption of an interrupted run
+	Trigger TriggerSource
+}
+
+// Lateness is how long after its scheduled time the run started, or zero for runs on demand
+func (r JobResult) Lateness() time.Duration {
+	if r.ScheduledTime.IsZero() {
+		return 0
+	}
+	return r.StartTime.Sub(r.ScheduledTime)
 }
 
 // JobStore defines the interface for job persistence
@@ -78,7 +143,7 @@ type JobStore interface {
 	// GetJob retrieves a job definition by Id
 	GetJob(id string) (JobDef, error)
 	// ListJobs retrieves all job definitions with optional filters
-	ListJobs(status JobStatus, freqType FreqType) ([]JobDef, error)
+	ListJobs(status JobStatus, freqType FreqType, sel Selector) ([]JobDef, error)
 	// UpdateJobStatus updates the status of a job
 	UpdateJobStatus(id string, status JobStatus) error
 	// UpdateNextRunTime updates when a job should next run
@@ -87,16 +152,74 @@ type JobStore interface {
 	DeleteJob(id string) error
 	// RecordJobResult stores the outcome of a job execution
 	RecordJobResult(result JobResult) error
+	// RecordJobResults stores the outcomes of several job executions at once
+	RecordJobResults(results []JobResult) error
 	// GetJobResults retrieves historical results for a job
 	GetJobResults(jobID string, limit int) ([]JobResult, error)
 	// GetJobRuns retrieves historical runs for all jobs
 	GetJobRuns(limit int) ([]JobRun, error)
-	// GetJobRunsWithPagination retrieves jobs with limited results per job
-	GetJobRunsWithPagination(resultsPerJob int) ([]JobRun, map[string]int, error)
+	// GetJobRunsWithPagination retrieves jobs matching the selector with limited results per job
+	GetJobRunsWithPagination(resultsPerJob int, sel Selector) ([]JobRun, map[string]int, error)
 	// GetJobResultsPaginated retrieves paginated results for a specific job
 	GetJobResultsPaginated(jobID string, offset, limit int) ([]JobResult, int, error)
 	// CleanupOldJobResults deletes job results older than the specified duration
 	CleanupJobResults(olderThan time.Duration) error
+	// GetDurationStats returns duration percentiles per job over a window
+	GetDurationStats(jobID string, window time.Duration) ([]DurationStats, error)
+	// GetSuccessRateSeries returns run counts and success rate by hour or day over a window
+	GetSuccessRateSeries(jobID string, bucket TimeBucket, window time.Duration) ([]RatePoint, error)
+	// GetTopErrors returns the most frequent normalized error messages over a window
+	GetTopErrors(jobID string, window time.Duration, limit int) ([]ErrorCluster, error)
+	// GetJobSummary returns counts, success rate, percentiles and recent runs of a job
+	GetJobSummary(jobID string, window time.Duration, recent int) (JobSummary, error)
+	// GetOutputSeries returns a numeric structured output value per run of a job
+	GetOutputSeries(jobID, key string, window time.Duration) ([]OutputPoint, error)
+	// GetOutputKeys returns the structured output keys recorded for a job
+	GetOutputKeys(jobID string, window time.Duration) ([]string, error)
+	// GetJobResultsInRange retrieves the results of all jobs that started within a time range
+	GetJobResultsInRange(from, to time.Time) ([]JobResult, error)
+	// Export writes job definitions and results in JSON, CSV or Parquet
+	Export(opts ExportOptions) ([]byte, error)
+	// SaveCalendar creates or replaces a calendar of blackout windows
+	SaveCalendar(cal Calendar) error
+	// ListCalendars returns all calendars
+	ListCalendars() ([]Calendar, error)
+	// DeleteCalendar removes a calendar
+	DeleteCalendar(name string) error
+	// ForNamespace returns a view of the store restricted to a namespace
+	ForNamespace(namespace string) JobStore
+	// ListNamespaces returns the namespaces having jobs or results
+	ListNamespaces() ([]string, error)
+	// EnqueueRun adds a run of a remote job to the queue workers claim runs from
+	EnqueueRun(run QueuedRun) error
+	// SaveQueuedRun updates the status, lease and attempts of a queued run
+	SaveQueuedRun(run QueuedRun) error
+	// DeleteQueuedRun removes a run from the queue
+	DeleteQueuedRun(runID string) error
+	// ListQueuedRuns returns the runs in the queue, oldest first
+	ListQueuedRuns() ([]QueuedRun, error)
+	// SaveCheckpoint stores the checkpoint state of a job, replacing the previous one
+	SaveCheckpoint(jobID string, state []byte) error
+	// GetCheckpoint returns the checkpoint state of a job, or nil if it has none
+	GetCheckpoint(jobID string) ([]byte, error)
+	// DeleteCheckpoint removes the checkpoint of a job
+	DeleteCheckpoint(jobID string) error
+	// SaveActiveRun records the run in progress of a job that resumes interrupted runs
+	SaveActiveRun(run ActiveRun) error
+	// DeleteActiveRun removes the run in progress of a job
+	DeleteActiveRun(jobID string) error
+	// ListActiveRuns returns the runs recorded as in progress, which were interrupted if listed on startup
+	ListActiveRuns() ([]ActiveRun, error)
+	// ClaimWatchedFile records a file picked up by a watch, reporting false if that version of it already was
+	ClaimWatchedFile(file WatchedFile) (bool, error)
+	// SaveWatchedFile updates the status of a file picked up by a watch, and where it was moved
+	SaveWatchedFile(file WatchedFile) error
+	// DeleteWatchedFile forgets a file picked up by a watch, so it is picked up again
+	DeleteWatchedFile(file WatchedFile) error
+	// ReleaseWatchedFiles forgets the files of a watch in the given status
+	ReleaseWatchedFiles(watch string, status WatchedFileStatus) error
+	// ListWatchedFiles returns the latest files picked up by a watch, newest first
+	ListWatchedFiles(watch string, limit int) ([]WatchedFile, error)
 	// Close closes the database connection
 	Close() error
 }
@@ -115,12 +238,14 @@ type JobMgr interface {
 	ResumeJob(id string) error
 	// RescheduleJob changes the execution time of a scheduled one-time job
 	RescheduleJob(id string, newSchedule string) error
+	// UpdateJob changes the schedule, timeout and other settings of a job
+	UpdateJob(id string, settings JobSettings) error
 	// DeleteJob removes a job from the system
 	DeleteJob(id string) error
 	// ListJobs lists all jobs
 	ListJobs() ([]JobRun, error)
-	// ListJobsWithPagination lists jobs with limited results per job
-	ListJobsWithPagination(resultsPerJob int) ([]JobRun, map[string]int, error)
+	// ListJobsWithPagination lists jobs matching the selector with limited results per job
+	ListJobsWithPagination(resultsPerJob int, sel Selector) ([]JobRun, map[string]int, error)
 	// GetJobResultsPaginated returns paginated results for a specific job
 	GetJobResultsPaginated(jobID string, offset, limit int) ([]JobResult, int, error)
 	// GetJobStatus retrieves the current status of a job
@@ -1,10 +1,12 @@
 package jobpro
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
-	"job_processor/util"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
@@ -14,35 +16,126 @@ import (
 	"github.com/rohanthewiz/serr"
 )
 
-// DefaultJobManager implements the JobMgr interface
+// ErrCancelled is the cause of the cancellation of runs stopped by an operator
+var ErrCancelled = errors.New("run cancelled")
+
+// ErrShutdown is the cause of the cancellation of runs interrupted by the job manager shutting down
+var ErrShutdown = errors.New("job manager shut down")
+
+// ErrRunNotFound is returned when cancelling a run that is not in progress
+var ErrRunNotFound = errors.New("run not found")
+
+// runningJob is a run in progress
+type runningJob struct {
+	id       string                  // the run Id, also the Id of remote runs in the queue
+	started  time.Time               // when the run started, in UTC
+	cancel   context.CancelCauseFunc // stops the run, the cause telling why
+	progress *ProgressReporter       // the progress reported by the job
+	dropped  bool                    // set when the job is deleted, so the run's result is not recorded
+}
+
+// RunningRun describes a run in progress
+type RunningRun struct {
+	RunID     string
+	JobID     string
+	StartTime time.Time
+	Progress  Progress
+}
+
+// DefaultJobManager implements the JobMgr interface.
+// A manager either sees all namespaces, or is a view of one namespace sharing the manager's registry.
 type DefaultJobManager struct {
-	store         JobStore
+	*jobRegistry          // state shared by the manager and its namespace views
+	store        JobStore // the store, restricted to the namespace for namespace views
+	namespace    string   // empty for the manager itself, which sees all namespaces
+}
+
+// jobRegistry holds the scheduling state shared by a manager and its namespace views
+type jobRegistry struct {
+	rootStore     JobStore // the unrestricted store
 	cron          *cron.Cron
-	jobs          map[string]Job                // keep track of active jobs
-	cronEntries   map[string]cron.EntryID       // keep track of jobs scheduled with cron
-	runningJobs   map[string]context.CancelFunc // keep track of running jobs and a cancel function to stop each
-	scheduledJobs map[string]*time.Timer        // keep track of scheduled one-time jobs for cancellation
+	jobs          map[string]Job             // keep track of active jobs
+	jobNamespaces map[string]string          // namespace of each active job
+	cronEntries   map[string]cron.EntryID    // keep track of jobs scheduled with cron
+	runningJobs   map[string]*runningJob     // keep track of running jobs and a cancel function to stop each
+	scheduledJobs map[string]*time.Timer     // keep track of scheduled one-time jobs for cancellation
+	nsConfigs     map[string]NamespaceConfig // per namespace quota and retention
+	nsSlots       map[string]chan struct{}   // concurrency slots of namespaces with a quota
+	calendars     map[string]Calendar        // blackout calendars by name
+	interrupted   map[string]ActiveRun       // runs left in progress by the last shutdown or crash, by job Id
+	pools         map[string]*pool           // resource pools shared by jobs, by name
+	hooks         map[string]HookConfig      // inbound webhooks, by name
+	hookRuns      map[string]*pendingHook    // runs waiting for the windows of their webhook, by job Id
+	watches       map[string]*dirWatch       // directory watches, by name
+	jitter        JitterConfig               // default delay of scheduled runs
 	mu            sync.RWMutex
 	wg            sync.WaitGroup
 	results       chan JobResult
-	jobsUpdated   chan any // Channel to signal that there has been at least one job update
-	shutdown      bool
+	resultsMu     sync.RWMutex  // held to send results, so closing the channel can't race a send
+	resultsClosed bool          // set once Shutdown has closed results
+	resultsDone   chan struct{} // closed when processResults has recorded the last result
+	spool         resultSpool   // results that could not be recorded in the store yet
+	queue         *runQueue     // runs of remote jobs, for workers to claim
+	// results are recorded in batches of up to resultBatchSize, at most resultFlushInterval after the first
+	resultBatchSize     int
+	resultFlushInterval time.Duration
+	jobsUpdated         chan any // Channel to signal that there has been at least one job update
+	progressUpdates     chan any // Channel of the progress of runs, see GetProgressChan
+	shutdown            bool
+	// Only the leader schedules jobs; see SetLeaderElection
+	leading      bool                // always set without leader election
+	instanceID   string              // the Id of this instance in the election
+	leader       string              // the Id of the leading instance
+	standby      map[string]struct{} // jobs started while following, to start once elected
+	electionStop chan struct{}       // closed to stop the election
+	electionDone chan struct{}       // closed once the election has stopped
+
+	// Jobs running shell commands are only created, imported and loaded once allowed; see AllowCommandJobs
+	allowCommands bool
 }
 
 // NewJobManager creates a new job manager with the provided store
 func NewJobManager(store JobStore) *DefaultJobManager {
-	cronParser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
 	cronScheduler := cron.New(cron.WithParser(cronParser), cron.WithChain())
 
 	mgr := &DefaultJobManager{
-		store:         store,
-		cron:          cronScheduler,
-		jobs:          make(map[string]Job),
-		cronEntries:   make(map[string]cron.EntryID),
-		runningJobs:   make(map[string]context.CancelFunc),
-		scheduledJobs: make(map[string]*time.Timer),
-		results:       make(chan JobResult, 256), // Buffer for job results - perhaps make this configurable
-		jobsUpdated:   make(chan any, 1),
+		jobRegistry: &jobRegistry{
+			rootStore:           store,
+			cron:                cronScheduler,
+			jobs:                make(map[string]Job),
+			jobNamespaces:       make(map[string]string),
+			cronEntries:         make(map[string]cron.EntryID),
+			runningJobs:         make(map[string]*runningJob),
+			scheduledJobs:       make(map[string]*time.Timer),
+			nsConfigs:           make(map[string]NamespaceConfig),
+			nsSlots:             make(map[string]chan struct{}),
+			calendars:           make(map[string]Calendar),
+			results:             make(chan JobResult, 256), // Buffer for job results - perhaps make this configurable
+			resultsDone:         make(chan struct{}),
+			queue:               newRunQueue(),
+			interrupted:         make(map[string]ActiveRun),
+			pools:               make(map[string]*pool),
+			hooks:               make(map[string]HookConfig),
+			hookRuns:            make(map[string]*pendingHook),
+			watches:             make(map[string]*dirWatch),
+			resultBatchSize:     defaultResultBatchSize,
+			resultFlushInterval: defaultResultFlushInterval,
+			jobsUpdated:         make(chan any, 1),
+			progressUpdates:     make(chan any, 64),
+			leading:             true,
+			standby:             make(map[string]struct{}),
+		},
+		store: store,
+	}
+
+	if err := mgr.loadCalendars(); err != nil {
+		logger.LogErr(err, "Error loading calendars")
+	}
+	if err := mgr.loadRunQueue(); err != nil {
+		logger.LogErr(err, "Error loading the queue of remote runs")
+	}
+	if err := mgr.loadInterruptedRuns(); err != nil {
+		logger.LogErr(err, "Error loading interrupted runs")
 	}
 
 	// Start the results processor
@@ -51,19 +144,22 @@ func NewJobManager(store JobStore) *DefaultJobManager {
 	// Start the cron scheduler
 	cronScheduler.Start()
 
+	// Queue again the runs of workers that stopped sending heartbeats
+	go mgr.reapRunLeases()
+
+	// Push the progress of runs and flag those that stopped sending heartbeats
+	go mgr.watchProgress()
+
 	// Start the job results cleanup goroutine
 	go func() {
 		logger.Info("Launching cleanup goroutine")
-		// Clean up job results older than one week
-		oneWeek := 7 * 24 * time.Hour
+		// Clean up job results older than each namespace's retention (one week by default)
 		ticker := time.NewTicker(1 * time.Hour)
 
 		defer ticker.Stop()
 
 		// Run cleanup immediately on startup
-		if err := store.CleanupJobResults(oneWeek); err != nil {
-			logger.F("Error cleaning up old job results: %v", err)
-		}
+		mgr.cleanupResults()
 
 		// Then run every hour
 		for {
@@ -72,9 +168,7 @@ func NewJobManager(store JobStore) *DefaultJobManager {
 				if mgr.shutdown {
 					return
 				}
-				if err := store.CleanupJobResults(oneWeek); err != nil {
-					logger.F("Error cleaning up old job results: %v", err)
-				}
+				mgr.cleanupResults()
 			}
 		}
 	}()
@@ -87,66 +181,210 @@ func (m *DefaultJobManager) GetJobsUpdatedChan() <-chan any {
 	return m.jobsUpdated
 }
 
+// Default batching of results: recorded when this many are waiting, or this long after the first of them
+const (
+	defaultResultBatchSize     = 500
+	defaultResultFlushInterval = 50 * time.Millisecond
+)
+
+// spoolRetryInterval is how often recording spooled results is retried
+const spoolRetryInterval = 10 * time.Second
+
+// SetResultBatching sets how many results are recorded together at most, and how long after the first
+// of a batch it is recorded at the latest. A size of 1 records each result on its own, as it arrives.
+// It must be called before any job runs.
+func (m *DefaultJobManager) SetResultBatching(size int, flushInterval time.Duration) {
+	m.resultBatchSize, m.resultFlushInterval = max(size, 1), flushInterval
+}
+
 // processResults handles job completion results
+// Results are recorded in batches, in one transaction, when resultBatchSize of them are waiting or
+// resultFlushInterval after the first. If the store fails, they are spooled and replayed once it is
+// available again, so results are never dropped.
 func (m *DefaultJobManager) processResults() {
-	for result := range m.results { // range over the results channel
-		// Store the job result
-		if err := m.store.RecordJobResult(result); err != nil {
-			log.Printf("Error recording job result for %s: %v", result.JobID, err)
-		}
+	defer close(m.resultsDone)
 
-		// Update job status in store if job was successful or failed (not if stopped)
-		if result.Status == StatusComplete || result.Status == StatusFailed {
-			fmt.Println("Job completed - updating job status in store")
+	retry := time.NewTicker(spoolRetryInterval)
+	defer retry.Stop()
 
-			jobDef, err := m.store.GetJob(result.JobID)
-			if err != nil {
-				log.Printf("Error getting job definition for %s: %v", result.JobID, err)
-				continue
+	for {
+		select {
+		case result, ok := <-m.results:
+			if !ok {
+				return
+			}
+			batch, open := m.collectResults(result)
+			m.recordResults(batch)
+			m.resultsRecorded(batch)
+			for range batch {
+				m.wg.Done() // Mark this job as done
+			}
+			if !open {
+				return
 			}
-			isPeriodic := jobDef.SchedType == Periodic
 
-			// Periodic jobs should not be updated here
-			if !isPeriodic {
-				if err := m.store.UpdateJobStatus(result.JobID, result.Status); err != nil {
-					log.Printf("Error updating job status for %s: %v", result.JobID, err)
-				}
-			} /* we will only run periodic jobs with cron so ignore this block
-				// else { // For periodic jobs that completed, update next run time if not already scheduled via cron
-				m.mu.RLock()
-				_, inCron := m.cronEntries[result.JobID]
-				m.mu.RUnlock()
-
-				if !inCron { // ~ why would the job have a schedule and not be in cron?
-					// If not scheduled via cron (e.g., a manually triggered run), calculate next run
-					scheduler, err := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom |
-						cron.Month | cron.Dow).Parse(jobDef.Schedule)
-					if err == nil {
-						nextRun := scheduler.Next(time.Now())
-						if err := m.store.UpdateNextRunTime(result.JobID, nextRun); err != nil {
-							log.Printf("Error updating next run time for %s: %v", result.JobID, err)
-						}
-						// what about actually executing the job again in cron?
-					}
-				}
-			}*/
+		case <-retry.C:
+			if m.spool.pending() {
+				m.replaySpool()
+			}
+		}
+	}
+}
 
-			// Let the system know that jobs have been updated
-			select {
-			case m.jobsUpdated <- "updated":
-				fmt.Println("Job update notification sent")
-			default: // Non-blocking send to avoid blocking if no one is listening
-				// If the channel is full, we don't want to block
+// collectResults batches a received result with those arriving until the batch is full or the flush interval
+// has passed. open is false if the channel was found closed.
+func (m *DefaultJobManager) collectResults(first JobResult) (batch []JobResult, open bool) {
+	batch = append(make([]JobResult, 0, m.resultBatchSize), first)
+	if m.resultBatchSize == 1 {
+		return batch, true
+	}
+
+	flush := time.NewTimer(m.resultFlushInterval)
+	defer flush.Stop()
+
+	for len(batch) < m.resultBatchSize {
+		select {
+		case result, ok := <-m.results:
+			if !ok {
+				return batch, false
 			}
+			batch = append(batch, result)
+		case <-flush.C:
+			return batch, true
 		}
+	}
+	return batch, true
+}
 
-		// Remove from running jobs map
-		m.mu.Lock()
-		delete(m.runningJobs, result.JobID)
-		m.mu.Unlock()
+// recordResults stores a batch of results, spooling them if the store fails.
+// Results the store rejects are set aside, so they don't hold up the others.
+func (m *DefaultJobManager) recordResults(batch []JobResult) {
+	rejected, rest, reason, err := recordBatch(m.rootStore.RecordJobResults, batch)
+	m.spool.reject(rejected, reason)
+	if err != nil {
+		log.Printf("Error recording %d job results, spooling them: %v", len(rest), err)
+		m.spool.add(rest)
+		return
+	}
+
+	// The store is working - catch up on results spooled while it wasn't
+	if m.spool.pending() {
+		m.replaySpool()
+	}
+}
+
+// replaySpool records spooled results in the store
+func (m *DefaultJobManager) replaySpool() {
+	n, err := m.spool.replay(m.rootStore.RecordJobResults)
+	if n > 0 {
+		log.Printf("Recorded %d spooled job results", n)
+	}
+	if err != nil {
+		log.Printf("Error recording spooled job results: %v", err)
+	}
+}
+
+// SetResultSpool sets the file results are spooled to while they can't be recorded in the store,
+// and records any results spooled there before a restart
+func (m *DefaultJobManager) SetResultSpool(path string) {
+	m.spool.mu.Lock()
+	m.spool.path = path
+	m.spool.spooled = path != "" // the file may hold results from before a restart
+	m.spool.mu.Unlock()
 
-		m.wg.Done() // Mark this job as done
+	m.replaySpool()
+}
+
+// sendResult queues a result for processResults, waiting while the queue is full.
+// The result of a run finishing after Shutdown stopped processing results is spooled instead.
+func (m *DefaultJobManager) sendResult(result JobResult) {
+	m.resultsMu.RLock()
+	defer m.resultsMu.RUnlock()
+
+	if m.resultsClosed {
+		log.Printf("Results are no longer processed, spooling result for job %s", result.JobID)
+		m.spool.add([]JobResult{result})
+		m.wg.Done()
+		return
 	}
+	m.results <- result
+}
+
+// resultsRecorded updates the jobs of a batch of recorded results. A job with several results in the batch
+// is updated once, for the latest, and one notification is sent for the batch.
+func (m *DefaultJobManager) resultsRecorded(batch []JobResult) {
+	latest := make(map[string]JobResult)
+	for _, result := range batch {
+		if updatesJob(result.Status) {
+			latest[result.JobID] = result
+		}
+	}
+	if len(latest) == 0 {
+		return
+	}
+
+	for _, result := range latest {
+		m.updateAfterResult(result)
+	}
+
+	// Let the system know that jobs have been updated
+	select {
+	case m.jobsUpdated <- "updated":
+		fmt.Println("Job update notification sent")
+	default: // Non-blocking send to avoid blocking if no one is listening
+		// If the channel is full, we don't want to block
+	}
+}
+
+// updatesJob reports whether a result of the status updates its job: successful, failed, timed out or skipped
+// (not if stopped or interrupted). A run skipped for overlapping leaves the status to the run still going.
+func updatesJob(status JobStatus) bool {
+	return status == StatusComplete || status == StatusFailed || status == StatusTimedOut ||
+		(status.Skipped() && status != StatusSkippedOverlap)
+}
+
+// updateAfterResult updates a job's status, or a periodic job's next run time, after a run
+func (m *DefaultJobManager) updateAfterResult(result JobResult) {
+	fmt.Println("Job completed - updating job status in store")
+
+	jobDef, err := m.rootStore.GetJob(result.JobID)
+	if err != nil {
+		log.Printf("Error getting job definition for %s: %v", result.JobID, err)
+		return
+	}
+	isPeriodic := jobDef.SchedType == Periodic
+
+	// Periodic jobs should not be updated here, other than their next run time
+	if !isPeriodic {
+		if err := m.rootStore.UpdateJobStatus(result.JobID, result.Status); err != nil {
+			log.Printf("Error updating job status for %s: %v", result.JobID, err)
+		}
+	} else {
+		m.mu.RLock()
+		entryID, inCron := m.cronEntries[result.JobID]
+		m.mu.RUnlock()
+		if inCron {
+			m.updateNextRun(result.JobID, entryID)
+		}
+	} /* we will only run periodic jobs with cron so ignore this block
+		// else { // For periodic jobs that completed, update next run time if not already scheduled via cron
+		m.mu.RLock()
+		_, inCron := m.cronEntries[result.JobID]
+		m.mu.RUnlock()
+
+		if !inCron { // ~ why would the job have a schedule and not be in cron?
+			// If not scheduled via cron (e.g., a manually triggered run), calculate next run
+			scheduler, err := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom |
+				cron.Month | cron.Dow).Parse(jobDef.Schedule)
+			if err == nil {
+				nextRun := scheduler.Next(time.Now())
+				if err := m.store.UpdateNextRunTime(result.JobID, nextRun); err != nil {
+					log.Printf("Error updating next run time for %s: %v", result.JobID, err)
+				}
+				// what about actually executing the job again in cron?
+			}
+		}
+	}*/
 }
 
 // SetupJob adds a new job to the system
@@ -169,28 +407,72 @@ func (m *DefaultJobManager) SetupJob(job Job, schedule string) (string, error) {
 		return "", serr.F("job with Id %s already exists", jobID)
 	}
 
+	// Settings changed with UpdateJob outlive restarts, as do the definitions of jobs created with CreateJob
+	var edits JobSettings
+	var definition *JobConfig
+	if stored, err := m.store.GetJob(jobID); err == nil {
+		definition = stored.Definition
+		if cj, ok := job.(ConfigurableJob); ok && !stored.Edits.empty() {
+			edits = stored.Edits
+			cfg := edits.applyTo(cj.Config())
+			job, schedule = NewScheduledJob(cfg), cfg.Schedule
+		}
+	}
+
+	// A namespace view sets up jobs in its own namespace
+	namespace := m.namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+		if nj, ok := job.(NamespacedJob); ok && nj.Namespace() != "" {
+			namespace = nj.Namespace()
+		}
+	}
+
+	// The schedule is in the job's timezone, if any
+	var timezone string
+	if zj, ok := job.(ZonedJob); ok {
+		timezone = zj.Timezone()
+	}
+	timezone = scheduleTimezone(schedule, timezone)
+
 	// Determine next run time
 	var nextRun time.Time
 
 	if job.Type() == Periodic && schedule != "" {
-		// Parse the cron schedule
-		scheduler, err := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow).Parse(schedule)
-		if err != nil {
+		var err error
+		if nextRun, err = nextRunTime(Periodic, schedule, timezone, time.Now()); err != nil {
 			return "", serr.F("unable to parse schedule: %w", err)
 		}
-		nextRun = scheduler.Next(time.Now())
 
 	} else if job.Type() == OneTime {
-		// For one-time jobs, use the current time if no specific time is provided
-		if schedule == "" {
-			nextRun = time.Now()
-		} else {
-			// Parse the schedule using our flexible parser
-			parsedTime, err := util.ParseSchedule(schedule)
-			if err != nil {
-				return "", serr.F("invalid time format for one-time job: %w", err)
-			}
-			nextRun = parsedTime
+		// For one-time jobs, use the current time if no specific time is provided.
+		// Otherwise parse the schedule using our flexible parser
+		var err error
+		if nextRun, err = nextRunTime(OneTime, schedule, timezone, time.Now()); err != nil {
+			return "", serr.F("invalid time format for one-time job: %w", err)
+		}
+	}
+
+	// Calendars must exist
+	var calendars []string
+	var calendarPolicy CalendarPolicy
+	if cj, ok := job.(CalendarJob); ok {
+		calendars, calendarPolicy = cj.Calendars(), cj.CalendarPolicy()
+		if err := m.checkCalendars(calendars); err != nil {
+			return "", serr.Wrap(err)
+		}
+		if calendarPolicy != "" && calendarPolicy != CalendarSkip && calendarPolicy != CalendarDefer {
+			return "", serr.F("invalid calendar policy %q", calendarPolicy)
+		}
+	}
+
+	// So must pools
+	if pools, policy := jobPools(job); len(pools) > 0 {
+		if err := m.checkPools(pools); err != nil {
+			return "", serr.Wrap(err)
+		}
+		if err := checkPoolPolicy(policy); err != nil {
+			return "", serr.Wrap(err)
 		}
 	}
 
@@ -201,10 +483,20 @@ func (m *DefaultJobManager) SetupJob(job Job, schedule string) (string, error) {
 		SchedType:   job.Type(),
 		Schedule:    schedule,
 		NextRunTime: nextRun,
+		Timezone:    timezone,
 		Status:      StatusCreated,
-		CreatedAt:   time.Now().UTC(),
-		UpdatedAt:   time.Now().UTC(),
+		Calendars:   calendars,
+		// Scheduled runs in a blackout window are skipped unless deferred
+		CalendarPolicy: calendarPolicy,
+		Edits:          edits,
+		Definition:     definition,
+		CreatedAt:      time.Now().UTC(),
+		UpdatedAt:      time.Now().UTC(),
 	}
+	if tj, ok := job.(TaggedJob); ok {
+		jobDef.Tags = tj.Tags()
+	}
+	jobDef.Namespace = namespace
 
 	// Save to store
 	if err := m.store.SaveJob(jobDef); err != nil {
@@ -213,6 +505,7 @@ func (m *DefaultJobManager) SetupJob(job Job, schedule string) (string, error) {
 
 	// Add to in-memory map
 	m.jobs[jobID] = job
+	m.jobNamespaces[jobID] = namespace
 
 	return jobID, nil
 }
@@ -227,7 +520,7 @@ func (m *DefaultJobManager) StartJob(id string) error {
 		return fmt.Errorf("job manager is shutting down")
 	}
 
-	job, exists := m.jobs[id]
+	job, exists := m.job(id)
 	if !exists {
 		// Try to load from store
 		_, err := m.store.GetJob(id)
@@ -244,6 +537,12 @@ func (m *DefaultJobManager) StartJob(id string) error {
 		return fmt.Errorf("job %s is already running", id)
 	}
 
+	// Followers leave scheduling to the leader, and start the job if they are elected
+	if !m.leading {
+		m.standby[id] = struct{}{}
+		return nil
+	}
+
 	// Update job status
 	if err := m.store.UpdateJobStatus(id, StatusRunning); err != nil {
 		return serr.Wrap(err, "failed to update job status")
@@ -256,21 +555,26 @@ func (m *DefaultJobManager) StartJob(id string) error {
 
 	// If it's a periodic job, schedule it with cron
 	if job.Type() == Periodic {
+		// Resume a run interrupted by the last shutdown or crash at once, rather than at the next tick
+		if _, ok := m.interrupted[id]; ok && resumesInterrupted(job) {
+			go m.executeJob(id, runTrigger{source: TriggerResume})
+		}
 
 		// Schedule with cron if not already scheduled
 		if _, exists := m.cronEntries[id]; !exists {
-			entryID, err := m.cron.AddFunc(jobDef.Schedule, func() {
-				m.executeJob(id)
+			entryID, err := m.cron.AddFunc(cronSpec(jobDef.Schedule, jobDef.Timezone), func() {
+				m.runTick(id)
 			})
 			if err != nil {
 				return serr.Wrap(err, "failed to schedule job")
 			}
 			m.cronEntries[id] = entryID
+			m.updateNextRun(id, entryID)
 		}
 	} else { // For one-time jobs
 		// For manual start jobs (no schedule), execute immediately
 		if jobDef.Schedule == "" {
-			go m.executeJob(id)
+			go m.executeJob(id, runTrigger{source: TriggerManual})
 		} else {
 			// For scheduled one-time jobs, check if we need to schedule or execute
 			if jobDef.NextRunTime.After(time.Now()) {
@@ -279,25 +583,10 @@ func (m *DefaultJobManager) StartJob(id string) error {
 					return serr.Wrap(err, "failed to update job status to scheduled")
 				}
 
-				// Schedule the job in a goroutine and store the timer
-				go func() {
-					timer := RunAt(jobDef.NextRunTime, func() {
-						// Remove the timer reference when the job starts
-						m.mu.Lock()
-						delete(m.scheduledJobs, id)
-						m.mu.Unlock()
-
-						m.executeJob(id)
-					})
-
-					// Store the timer reference
-					m.mu.Lock()
-					m.scheduledJobs[id] = timer
-					m.mu.Unlock()
-				}()
+				m.scheduleOnce(id, jobDef.NextRunTime)
 			} else {
 				// If the scheduled time has passed, execute immediately
-				go m.executeJob(id)
+				go m.runScheduled(id, jobDef.NextRunTime)
 			}
 		}
 	}
@@ -305,8 +594,37 @@ func (m *DefaultJobManager) StartJob(id string) error {
 	return nil
 }
 
+// updateNextRun records the next run time of a job scheduled with cron
+func (m *DefaultJobManager) updateNextRun(id string, entryID cron.EntryID) {
+	entry := m.cron.Entry(entryID)
+	if !entry.Valid() || entry.Next.IsZero() {
+		return
+	}
+	if err := m.rootStore.UpdateNextRunTime(id, entry.Next); err != nil {
+		log.Printf("Error updating next run time for %s: %v", id, err)
+	}
+}
+
+// scheduleOnce sets the timer of a one-time job's run, replacing any pending one.
+// The run starts after the job's run delay.
+// The caller must hold m.mu
+func (m *DefaultJobManager) scheduleOnce(id string, at time.Time) {
+	m.cancelPendingRun(id)
+	m.scheduledJobs[id] = RunAt(at.Add(m.runDelay(id)), func() {
+		// Remove the timer reference when the job starts
+		m.mu.Lock()
+		delete(m.scheduledJobs, id)
+		m.mu.Unlock()
+
+		m.runScheduled(id, at)
+	})
+}
+
 // executeJob runs a job and processes its result
-func (m *DefaultJobManager) executeJob(id string) {
+// trigger tells what started the run, and when it was due for scheduled runs
+func (m *DefaultJobManager) executeJob(id string, trigger runTrigger) {
+	scheduled := trigger.scheduled
+
 	m.mu.Lock()
 	if m.shutdown {
 		m.mu.Unlock()
@@ -319,20 +637,120 @@ func (m *DefaultJobManager) executeJob(id string) {
 		log.Printf("Job %s not found for execution", id)
 		return
 	}
+	namespace := m.jobNamespaces[id]
 
-	// Create a context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	m.runningJobs[id] = cancel
+	// Runs of a job don't overlap - skip this one if the previous is still going
+	if _, running := m.runningJobs[id]; running {
+		m.mu.Unlock()
+		now := time.Now().UTC()
+		msg := "Skipped: the previous run was still in progress"
+		log.Printf("Job %s: %s", id, msg)
+		result := JobResult{
+			JobID:         id,
+			StartTime:     now,
+			EndTime:       now,
+			Status:        StatusSkippedOverlap,
+			SuccessMsg:    msg,
+			Namespace:     namespace,
+			ScheduledTime: scheduled.UTC(),
+			Trigger:       trigger.source,
+		}
+		trigger.finished(result)
+		m.queueResult(result)
+		return
+	}
+
+	// Create a context with cancellation, its cause telling why the run was cancelled
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	// Give the job its parameters, and somewhere to report its progress and heartbeats
+	ctx = withParams(ctx, trigger.params)
+	ctx, progress := withProgress(ctx, id, namespace, heartbeatTimeout(job), m.publishProgress)
+	run := &runningJob{id: uuid.New().String(), started: time.Now().UTC(), cancel: cancel, progress: progress}
+	m.runningJobs[id] = run
 	m.wg.Add(1) // Track this running job
 	m.mu.Unlock()
 
+	// Let the system know, to list the run in progress
+	select {
+	case m.jobsUpdated <- "updated":
+		fmt.Println("Job update (run started) notification sent")
+	default: // Non-blocking send to avoid blocking if no one is listening
+	}
+
+	// Wait for a slot if the job's namespace has a concurrency quota, then take a slot of each resource pool
+	// of the job, waiting for them unless the job skips or fails the run. The run can be cancelled while it waits.
+	releaseSlot, err := m.acquireSlot(ctx, id)
+	defer releaseSlot()
+	var releasePools func()
+	var poolWait time.Duration
+	if err == nil {
+		releasePools, poolWait, err = m.acquirePools(ctx, id, job)
+	}
+	if err != nil {
+		if m.endRun(id, run) {
+			return
+		}
+		now := time.Now().UTC()
+		result := JobResult{JobID: id, StartTime: now, EndTime: now, Namespace: namespace,
+			ScheduledTime: scheduled.UTC(), PoolWait: poolWait, Trigger: trigger.source}
+		switch _, policy := jobPools(job); {
+		case errors.Is(err, ErrPoolExhausted) && policy == PoolSkip:
+			result.Status, result.SuccessMsg = StatusSkippedPool, "Skipped: "+err.Error()
+		case errors.Is(err, ErrPoolExhausted):
+			result.Status, result.ErrorMsg = StatusFailed, err.Error()
+		default: // cancelled while waiting
+			result.Status, result.ErrorMsg = resultStatus(err, context.Cause(ctx))
+		}
+		log.Printf("Job %s: not started, %s%s", id, result.SuccessMsg, result.ErrorMsg)
+		trigger.finished(result)
+		m.sendResult(result)
+		return
+	}
+	progress.begin() // the heartbeat deadline runs from the actual start, after any wait
+
+	// The first run after a shutdown or crash interrupted one resumes it
+	m.mu.Lock()
+	interrupted, resumed := m.takeInterrupted(id, job)
+	m.mu.Unlock()
+	if resumed {
+		log.Printf("Job %s: resuming the run started at %s", id, interrupted.StartTime.Format(time.RFC3339))
+	}
+	// Give the job somewhere to save its state
+	ctx, checkpoint := withCheckpoint(ctx, m.rootStore, id, resumed)
+
 	startTime := time.Now().UTC()
+	cfg, remote := remoteConfig(job)
+
+	// Keep track of the run until it ends, so it can be resumed if it doesn't. Remote runs go on without the scheduler.
+	tracked := resumesInterrupted(job) && !remote
+	if tracked {
+		active := ActiveRun{JobID: id, Namespace: namespace, StartTime: startTime, ScheduledTime: scheduled}
+		if err := m.rootStore.SaveActiveRun(active); err != nil {
+			log.Printf("Error recording the run of job %s as in progress: %v", id, err)
+		}
+	}
 
-	// EXECUTE the job
-	stats, err := job.Run(ctx) // DoIt
+	// EXECUTE the job, here or on a worker
+	var stats Stats
+	if remote {
+		stats, err = m.runRemote(ctx, run.id, id, namespace, cfg, trigger)
+	} else {
+		stats, err = job.Run(ctx) // DoIt
+	}
 	endTime := time.Now().UTC()
+	releasePools()
 	duration := endTime.Sub(startTime)
 
+	// Workers report when the run started, after waiting in the queue
+	if remote && !stats.StartTimeUTC.IsZero() {
+		startTime, duration = stats.StartTimeUTC, stats.Duration
+		endTime = startTime.Add(duration)
+	}
+
+	// The run is over, though its result may wait to be recorded with others
+	dropped := m.endRun(id, run)
+
 	// Prepare result
 	result := JobResult{
 		JobID:      id,
@@ -340,23 +758,75 @@ func (m *DefaultJobManager) executeJob(id string) {
 		EndTime:    endTime,
 		Duration:   duration,
 		SuccessMsg: stats.SuccessMsg,
+		Output:     stats.Output,
+		Namespace:  namespace,
+		// Recorded next to the start time, so lateness can be measured
+		ScheduledTime: scheduled.UTC(),
+		PoolWait:      poolWait,
+		Trigger:       trigger.source,
+	}
+
+	cause := context.Cause(ctx)
+	result.Status, result.ErrorMsg = resultStatus(err, cause)
+	result.Resumed = checkpoint.Resumed()
+
+	// A completed run clears its checkpoint. A run interrupted by the shutdown stays in progress, to be resumed.
+	checkpoint.finish(result.Status)
+	if tracked && result.Status != StatusInterruptedByShutdown {
+		if err := m.rootStore.DeleteActiveRun(id); err != nil {
+			log.Printf("Error removing the run of job %s from those in progress: %v", id, err)
+		}
 	}
 
-	if err != nil {
-		result.Status = StatusFailed
-		result.ErrorMsg = err.Error()
-	} else {
-		result.Status = StatusComplete
+	if dropped {
+		return
 	}
 
-	// Send result for processing
-	select {
-	case m.results <- result:
-		// Result queued for processing
+	// Remote runs go on without the scheduler, and are recorded when reported after a restart
+	if remote && err != nil && errors.Is(cause, ErrShutdown) {
+		log.Printf("Job %s: leaving the remote run to its worker during shutdown", id)
+		m.wg.Done()
+		return
+	}
+
+	trigger.finished(result)
+
+	// Send result for processing, waiting if results are backed up
+	m.sendResult(result)
+}
+
+// endRun forgets a run that is over, reporting whether its result is dropped as the job was deleted.
+// If it was stopped, a new run may have taken its place.
+func (m *DefaultJobManager) endRun(id string, run *runningJob) (dropped bool) {
+	m.mu.Lock()
+	if m.runningJobs[id] == run {
+		delete(m.runningJobs, id)
+	}
+	dropped = run.dropped
+	m.mu.Unlock()
+	run.progress.finish()
+
+	if dropped {
+		log.Printf("Job %s was deleted, dropping the result of its run", id)
+		m.wg.Done()
+	}
+	return dropped
+}
+
+// resultStatus returns the status of a finished run and its error message, given the error of the run
+// and the cause of the cancellation of its context, if any
+func resultStatus(err, cause error) (JobStatus, string) {
+	switch {
+	case errors.Is(err, ErrTimedOut):
+		return StatusTimedOut, err.Error()
+	case err != nil && errors.Is(cause, ErrShutdown):
+		return StatusInterruptedByShutdown, cause.Error()
+	case err != nil && errors.Is(cause, ErrCancelled):
+		return StatusCancelled, cause.Error()
+	case err != nil:
+		return StatusFailed, err.Error()
 	default:
-		// Results channel is full, log and continue
-		log.Printf("Results channel full, dropping result for job %s", id)
-		m.wg.Done() // Still mark as done even if we couldn't queue the result
+		return StatusComplete, ""
 	}
 }
 
@@ -365,8 +835,12 @@ func (m *DefaultJobManager) StopJob(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := m.checkLeader(); err != nil {
+		return err
+	}
+
 	// Check if job exists
-	job, exists := m.jobs[id]
+	job, exists := m.job(id)
 	if !exists {
 		return fmt.Errorf("job %s not found", id)
 	}
@@ -379,16 +853,18 @@ func (m *DefaultJobManager) StopJob(id string) error {
 
 	var finalStatus JobStatus
 
-	// If it's a periodic job, remove from cron
+	// If it's a periodic job, remove from cron, along with any delayed or deferred run
 	if entryID, exists := m.cronEntries[id]; exists {
 		m.cron.Remove(entryID)
 		delete(m.cronEntries, id)
+		m.cancelPendingRun(id)
 		finalStatus = StatusStopped
 	}
+	m.cancelHookRun(id)
 
 	// If it's running, cancel its context
-	if cancel, running := m.runningJobs[id]; running {
-		cancel() // This signals the job to stop
+	if run, running := m.runningJobs[id]; running {
+		run.cancel(ErrCancelled) // This signals the job to stop
 		delete(m.runningJobs, id)
 		finalStatus = StatusStopped
 		// Note: The job will complete and call wg.Done() when it processes the cancellation
@@ -425,13 +901,63 @@ func (m *DefaultJobManager) StopJob(id string) error {
 	return nil
 }
 
+// CancelRun cancels one run in progress, by run Id, which is recorded as cancelled.
+// Unlike StopJob, the job stays scheduled, and its next run starts as usual.
+func (m *DefaultJobManager) CancelRun(runID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkLeader(); err != nil {
+		return err
+	}
+
+	for id, run := range m.runningJobs {
+		if run.id != runID {
+			continue
+		}
+		if _, exists := m.job(id); !exists { // a run of another namespace
+			break
+		}
+
+		log.Printf("Cancelling run %s of job %s", runID, id)
+		run.cancel(ErrCancelled) // the run is recorded once it returns
+
+		select {
+		case m.jobsUpdated <- "updated":
+			fmt.Println("Job update (run cancelled) notification sent")
+		default: // Non-blocking send to avoid blocking if no one is listening
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+}
+
+// RunningRuns returns the runs in progress, by job Id
+func (m *DefaultJobManager) RunningRuns() []RunningRun {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	runs := make([]RunningRun, 0, len(m.runningJobs))
+	for id, run := range m.runningJobs {
+		if _, exists := m.job(id); exists {
+			runs = append(runs, RunningRun{RunID: run.id, JobID: id, StartTime: run.started, Progress: run.progress.Progress()})
+		}
+	}
+	slices.SortFunc(runs, func(a, b RunningRun) int { return cmp.Compare(a.JobID, b.JobID) })
+	return runs
+}
+
 // PauseJob temporarily suspends a job
 func (m *DefaultJobManager) PauseJob(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := m.checkLeader(); err != nil {
+		return err
+	}
+
 	// Check if job exists
-	job, exists := m.jobs[id]
+	job, exists := m.job(id)
 	if !exists {
 		return fmt.Errorf("job %s not found", id)
 	}
@@ -440,6 +966,7 @@ func (m *DefaultJobManager) PauseJob(id string) error {
 	if entryID, exists := m.cronEntries[id]; exists {
 		m.cron.Remove(entryID)
 		// We keep the entry in the m.cronEntries map to remember it was scheduled
+		m.cancelPendingRun(id)
 	}
 
 	if job.Type() != Periodic {
@@ -448,6 +975,7 @@ func (m *DefaultJobManager) PauseJob(id string) error {
 			return fmt.Errorf("job %s is currently running and cannot be paused", id)
 		}
 	}
+	m.cancelHookRun(id)
 
 	// Update job status
 	if err := m.store.UpdateJobStatus(id, StatusPaused); err != nil {
@@ -470,8 +998,12 @@ func (m *DefaultJobManager) ResumeJob(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := m.checkLeader(); err != nil {
+		return err
+	}
+
 	// Check if job exists
-	job, exists := m.jobs[id]
+	job, exists := m.job(id)
 	if !exists {
 		return fmt.Errorf("job %s not found", id)
 	}
@@ -495,13 +1027,14 @@ func (m *DefaultJobManager) ResumeJob(id string) error {
 	if job.Type() == Periodic {
 		_, wasScheduled := m.cronEntries[id]
 		if wasScheduled {
-			entryID, err := m.cron.AddFunc(jobDef.Schedule, func() {
-				m.executeJob(id)
+			entryID, err := m.cron.AddFunc(cronSpec(jobDef.Schedule, jobDef.Timezone), func() {
+				m.runTick(id)
 			})
 			if err != nil {
 				return fmt.Errorf("failed to reschedule job: %w", err)
 			}
 			m.cronEntries[id] = entryID
+			m.updateNextRun(id, entryID)
 		}
 	}
 
@@ -521,8 +1054,12 @@ func (m *DefaultJobManager) RescheduleJob(id string, newSchedule string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := m.checkLeader(); err != nil {
+		return err
+	}
+
 	// Check if job exists
-	job, exists := m.jobs[id]
+	job, exists := m.job(id)
 	if !exists {
 		return fmt.Errorf("job %s not found", id)
 	}
@@ -543,18 +1080,12 @@ func (m *DefaultJobManager) RescheduleJob(id string, newSchedule string) error {
 		return fmt.Errorf("job %s cannot be rescheduled in status %s", id, jobDef.Status)
 	}
 
-	// Parse the new schedule
-	newTime, err := util.ParseSchedule(newSchedule)
+	// Parse the new schedule - times without a timezone are in the job's timezone
+	newTime, err := nextRunTime(OneTime, newSchedule, jobDef.Timezone, time.Now())
 	if err != nil {
 		return fmt.Errorf("invalid time format: %w", err)
 	}
 
-	// Cancel existing timer if exists
-	if timer, exists := m.scheduledJobs[id]; exists {
-		timer.Stop()
-		delete(m.scheduledJobs, id)
-	}
-
 	// Update the job in the store
 	jobDef.Schedule = newSchedule
 	jobDef.NextRunTime = newTime
@@ -568,22 +1099,8 @@ func (m *DefaultJobManager) RescheduleJob(id string, newSchedule string) error {
 		return fmt.Errorf("failed to update next run time: %w", err)
 	}
 
-	// Reschedule the job with new time
-	go func() {
-		timer := RunAt(newTime, func() {
-			// Remove the timer reference when the job starts
-			m.mu.Lock()
-			delete(m.scheduledJobs, id)
-			m.mu.Unlock()
-
-			m.executeJob(id)
-		})
-
-		// Store the new timer reference
-		m.mu.Lock()
-		m.scheduledJobs[id] = timer
-		m.mu.Unlock()
-	}()
+	// Reschedule the job with new time, replacing any existing timer
+	m.scheduleOnce(id, newTime)
 
 	// Send notification
 	select {
@@ -601,7 +1118,7 @@ func (m *DefaultJobManager) TriggerJobNow(id string) error {
 	m.mu.Lock()
 
 	// Check if job exists
-	_, exists := m.jobs[id]
+	_, exists := m.job(id)
 	if !exists {
 		m.mu.Unlock()
 		return fmt.Errorf("job %s not found", id)
@@ -613,10 +1130,15 @@ func (m *DefaultJobManager) TriggerJobNow(id string) error {
 		return fmt.Errorf("job manager is shutting down")
 	}
 
+	if err := m.checkLeader(); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+
 	m.mu.Unlock()
 
 	// Execute the job in a goroutine
-	go m.executeJob(id)
+	go m.executeJob(id, runTrigger{source: TriggerManual})
 
 	// Let the system know that jobs have been updated
 	select {
@@ -634,8 +1156,12 @@ func (m *DefaultJobManager) DeleteJob(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if err := m.checkLeader(); err != nil {
+		return err
+	}
+
 	// Check if job exists
-	if _, exists := m.jobs[id]; !exists {
+	if _, exists := m.job(id); !exists {
 		return fmt.Errorf("job %s not found", id)
 	}
 
@@ -645,9 +1171,10 @@ func (m *DefaultJobManager) DeleteJob(id string) error {
 		delete(m.cronEntries, id)
 	}
 
-	// If it's running, cancel it
-	if cancel, running := m.runningJobs[id]; running {
-		cancel()
+	// If it's running, cancel it. Its result can't be recorded once the job is gone.
+	if run, running := m.runningJobs[id]; running {
+		run.dropped = true
+		run.cancel(ErrCancelled)
 		delete(m.runningJobs, id)
 	}
 
@@ -657,8 +1184,12 @@ func (m *DefaultJobManager) DeleteJob(id string) error {
 		delete(m.scheduledJobs, id)
 	}
 
+	// Along with any run waiting for the windows of its webhook
+	m.cancelHookRun(id)
+
 	// Remove from maps
 	delete(m.jobs, id)
+	delete(m.jobNamespaces, id)
 
 	// Delete from store
 	if err := m.store.DeleteJob(id); err != nil {
@@ -674,7 +1205,7 @@ func (m *DefaultJobManager) GetJobStatus(id string) (JobStatus, error) {
 	defer m.mu.RUnlock()
 
 	// Check if job exists in memory
-	if _, exists := m.jobs[id]; !exists {
+	if _, exists := m.job(id); !exists {
 		// Try to get from store
 		jobDef, err := m.store.GetJob(id)
 		if err != nil {
@@ -697,14 +1228,31 @@ func (m *DefaultJobManager) GetJobStatus(id string) (JobStatus, error) {
 	return jobDef.Status, nil
 }
 
-// LoadJobs loads jobs from store
+// LoadJobs sets up the stored jobs created with CreateJob that are not loaded yet,
+// starting those with AutoStart, as registered jobs are
 func (m *DefaultJobManager) LoadJobs() error {
-	jobs, err := m.store.ListJobs("", "")
+	jobs, err := m.store.ListJobs("", "", nil)
 	if err != nil {
 		return fmt.Errorf("failed to list jobs: %w", err)
 	}
 
-	log.Printf("Loaded %d jobs from store", len(jobs))
+	var loaded int
+	for _, jobDef := range jobs {
+		if _, exists := m.job(jobDef.JobID); exists || jobDef.Definition == nil {
+			continue
+		}
+		if jobDef.Definition.Command != "" && !m.CommandJobsAllowed() {
+			log.Printf("Not loading job %s: %v", jobDef.JobID, ErrCommandJobsNotAllowed)
+			continue
+		}
+		if err := setupJob(m, *jobDef.Definition); err != nil {
+			logger.LogErr(err, "Failed to load stored job", "jobID", jobDef.JobID)
+			continue
+		}
+		loaded++
+	}
+
+	log.Printf("Loaded %d of %d jobs from store", loaded, len(jobs))
 	return nil
 }
 
@@ -720,15 +1268,27 @@ func (m *DefaultJobManager) ListJobs() (jobs []JobRun, err error) {
 	return
 }
 
-// ListJobsWithPagination returns jobs with limited results per job and result counts
-func (m *DefaultJobManager) ListJobsWithPagination(resultsPerJob int) (jobs []JobRun, resultCounts map[string]int, err error) {
-	jobs, resultCounts, err = m.store.GetJobRunsWithPagination(resultsPerJob)
+// ListJobsWithPagination returns jobs matching the selector with limited results per job and result counts
+// A nil selector lists all jobs
+func (m *DefaultJobManager) ListJobsWithPagination(resultsPerJob int, sel Selector) (jobs []JobRun, resultCounts map[string]int, err error) {
+	jobs, resultCounts, err = m.store.GetJobRunsWithPagination(resultsPerJob, sel)
 	if err != nil {
 		return nil, nil, serr.Wrap(err, "error listing jobs with pagination")
 	}
 
 	log.Printf("Loaded %d jobs with pagination from store", len(jobs))
 
+	// Main rows of running jobs show their run in progress
+	m.mu.RLock()
+	for i := range jobs {
+		if run, running := m.runningJobs[jobs[i].JobID]; running && jobs[i].ResultId == 0 {
+			progress := run.progress.Progress()
+			jobs[i].Progress = &progress
+			jobs[i].RunID, jobs[i].RunStart = run.id, run.started
+		}
+	}
+	m.mu.RUnlock()
+
 	return
 }
 
@@ -738,20 +1298,28 @@ func (m *DefaultJobManager) GetJobResultsPaginated(jobID string, offset, limit i
 }
 
 // Shutdown gracefully stops all running jobs
+// Shutting down a namespace view shuts down the whole manager.
 func (m *DefaultJobManager) Shutdown(timeout time.Duration) error {
+	// Let another instance take over while this one winds down
+	m.stopElection()
+
 	m.mu.Lock()
 	m.shutdown = true
 
-	// Stop the cron scheduler
+	// Stop the cron scheduler, and the directory watches
 	cronContext := m.cron.Stop()
+	m.stopWatches()
 
 	// Cancel all running jobs
-	for id, cancel := range m.runningJobs {
+	for id, run := range m.runningJobs {
 		log.Printf("Cancelling job %s during shutdown", id)
-		cancel()
+		run.cancel(ErrShutdown)
 	}
 	m.mu.Unlock()
 
+	// Workers waiting for a run get none
+	m.queue.endClaims()
+
 	// Create a channel to signal timeout
 	done := make(chan struct{})
 
@@ -769,13 +1337,18 @@ func (m *DefaultJobManager) Shutdown(timeout time.Duration) error {
 		log.Println("Shutdown timed out, some jobs may not have completed")
 	}
 
-	// Close the results channel to stop the processor
+	// Close the results channel to stop the processor, once it has recorded what was queued.
+	// Results of runs still going are spooled, and recorded after a restart.
+	m.resultsMu.Lock()
+	m.resultsClosed = true
 	close(m.results)
+	m.resultsMu.Unlock()
+	<-m.resultsDone
 
 	// Wait for cron context to be done
 	<-cronContext.Done()
 
-	err := m.store.Close()
+	err := m.rootStore.Close()
 	if err != nil {
 		return serr.Wrap(err, "error closing job store")
 	}
@@ -789,7 +1362,7 @@ func (m *DefaultJobManager) GetJobHistory(jobID string, limit int) ([]JobResult,
 	defer m.mu.RUnlock()
 
 	// Check if job exists
-	if _, exists := m.jobs[jobID]; !exists {
+	if _, exists := m.job(jobID); !exists {
 		return nil, fmt.Errorf("job %s not found", jobID)
 	}
 
@@ -801,3 +1374,62 @@ func (m *DefaultJobManager) GetJobHistory(jobID string, limit int) ([]JobResult,
 
 	return results, nil
 }
+
+// GetDurationStats returns duration percentiles per job over the window (all jobs if jobID is empty)
+func (m *DefaultJobManager) GetDurationStats(jobID string, window time.Duration) ([]DurationStats, error) {
+	return m.store.GetDurationStats(jobID, window)
+}
+
+// GetSuccessRateSeries returns run counts and success rate by hour or day over the window
+func (m *DefaultJobManager) GetSuccessRateSeries(jobID string, bucket TimeBucket, window time.Duration) ([]RatePoint, error) {
+	return m.store.GetSuccessRateSeries(jobID, bucket, window)
+}
+
+// GetTopErrors returns the most frequent normalized error messages over the window
+func (m *DefaultJobManager) GetTopErrors(jobID string, window time.Duration, limit int) ([]ErrorCluster, error) {
+	return m.store.GetTopErrors(jobID, window, limit)
+}
+
+// GetJobSummary returns counts, success rate, percentiles and the most recent runs of a job
+func (m *DefaultJobManager) GetJobSummary(jobID string, window time.Duration, recent int) (JobSummary, error) {
+	return m.store.GetJobSummary(jobID, window, recent)
+}
+
+// GetOutputSeries returns the numeric value of a structured output key per run of a job
+func (m *DefaultJobManager) GetOutputSeries(jobID, key string, window time.Duration) ([]OutputPoint, error) {
+	return m.store.GetOutputSeries(jobID, key, window)
+}
+
+// GetOutputKeys returns the structured output keys recorded for a job
+func (m *DefaultJobManager) GetOutputKeys(jobID string, window time.Duration) ([]string, error) {
+	return m.store.GetOutputKeys(jobID, window)
+}
+
+// Export writes job definitions and results in the requested format
+func (m *DefaultJobManager) Export(opts ExportOptions) ([]byte, error) {
+	return m.store.Export(opts)
+}
+
+// Import applies an export bundle to the store.
+// Jobs currently loaded in the manager are never overwritten, as their schedule is live -
+// such differences are reported as skipped conflicts.
+func (m *DefaultJobManager) Import(bundle ExportBundle, opts ImportOptions) (ImportReport, error) {
+	report, err := importBundle(m.store, bundle, opts, m.CommandJobsAllowed(), func(jobID string) bool {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		_, loaded := m.jobs[jobID]
+		return loaded
+	})
+	if err != nil {
+		return report, serr.Wrap(err, "error importing jobs")
+	}
+
+	// Let the system know that jobs have been updated
+	select {
+	case m.jobsUpdated <- "updated":
+		fmt.Println("Job update (imported) notification sent")
+	default: // Non-blocking send to avoid blocking if no one is listening
+	}
+
+	return report, nil
+}
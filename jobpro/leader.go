@@ -0,0 +1,206 @@
+package jobpro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrNotLeader is returned on followers by the operations only the leader carries out
+var ErrNotLeader = errors.New("not the leader")
+
+// LeaderLease is a lease on leadership, held by at most one processor instance at a time
+type LeaderLease interface {
+	// Acquire takes the lease for holder if it is free or has expired, or renews it if holder has it,
+	// so that it lasts ttl from now. It returns the holder of the lease, which is holder if it got it.
+	Acquire(ctx context.Context, holder string, ttl time.Duration) (leader string, err error)
+	// Release gives up the lease if holder has it, so another instance can take it at once
+	Release(ctx context.Context, holder string) error
+}
+
+// Leadership tells which instance leads, when leader election is used
+type Leadership struct {
+	Elected  bool   // whether leader election is used; without it the manager always leads
+	Instance string // the Id of this instance
+	Leader   string // the Id of the leading instance, empty while unknown
+	IsLeader bool
+}
+
+// SetLeaderElection makes the manager a follower until it holds the lease, so several instances can run
+// against the same jobs without double firing them. Only the leader adds cron entries and timers.
+// Followers set up jobs, serve them read-only and start them if they are elected.
+// The lease is renewed every third of ttl. A leader that could not renew it for two thirds of ttl steps down,
+// and a follower takes over within ttl and a third once the leader's lease has expired.
+// It must be called before jobs are started. Only the scheduling is handed over: the instances keep their own stores.
+func (m *DefaultJobManager) SetLeaderElection(lease LeaderLease, instanceID string, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.leading = false
+	m.instanceID = instanceID
+	m.electionStop = make(chan struct{})
+	m.electionDone = make(chan struct{})
+	go m.runElection(lease, ttl)
+}
+
+// Leadership returns which instance leads
+func (m *DefaultJobManager) Leadership() Leadership {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return Leadership{
+		Elected:  m.electionStop != nil,
+		Instance: m.instanceID,
+		Leader:   m.leader,
+		IsLeader: m.leading,
+	}
+}
+
+// IsLeader reports whether the manager leads, which it always does without leader election
+func (m *DefaultJobManager) IsLeader() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.leading
+}
+
+// checkLeader returns ErrNotLeader on followers
+// The caller must hold m.mu
+func (m *DefaultJobManager) checkLeader() error {
+	if m.leading {
+		return nil
+	}
+	if m.leader == "" {
+		return fmt.Errorf("%w: this instance is a read-only follower, and no leader is elected yet", ErrNotLeader)
+	}
+	return fmt.Errorf("%w: this instance is a read-only follower of %s", ErrNotLeader, m.leader)
+}
+
+// runElection acquires or renews the lease until the election is stopped, leading while it holds the lease.
+// On leaving, the lease is released.
+func (m *DefaultJobManager) runElection(lease LeaderLease, ttl time.Duration) {
+	defer close(m.electionDone)
+
+	renewEvery := ttl / 3
+	var renewed time.Time // when the lease was last acquired or renewed
+	ticker := time.NewTicker(renewEvery)
+	defer ticker.Stop()
+
+	for {
+		attempt := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), renewEvery)
+		leader, err := lease.Acquire(ctx, m.instanceID, ttl)
+		cancel()
+
+		switch {
+		case err != nil:
+			log.Printf("Failed to acquire the leader lease: %v", err)
+			// Step down while the lease surely still holds, so no one else leads alongside
+			if m.IsLeader() && time.Since(renewed) > ttl-renewEvery {
+				m.follow("")
+			}
+		case leader == m.instanceID:
+			renewed = attempt // the lease lasts at least ttl from the attempt
+			if !m.IsLeader() {
+				m.lead()
+			}
+		default:
+			m.follow(leader)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-m.electionStop:
+			ctx, cancel := context.WithTimeout(context.Background(), renewEvery)
+			if err := lease.Release(ctx, m.instanceID); err != nil {
+				log.Printf("Failed to release the leader lease: %v", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// stopElection stops the election, releasing the lease. The manager stops leading.
+func (m *DefaultJobManager) stopElection() {
+	m.mu.Lock()
+	stop, done := m.electionStop, m.electionDone
+	m.mu.Unlock()
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	m.follow("")
+}
+
+// lead makes the manager the leader, starting the jobs started while it followed
+func (m *DefaultJobManager) lead() {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return
+	}
+	m.leading, m.leader = true, m.instanceID
+	ids := make([]string, 0, len(m.standby))
+	for id := range m.standby {
+		ids = append(ids, id)
+	}
+	clear(m.standby)
+	m.mu.Unlock()
+
+	log.Printf("Instance %s is now the leader, starting %d jobs", m.instanceID, len(ids))
+	for _, id := range ids {
+		if err := m.StartJob(id); err != nil {
+			log.Printf("Failed to start job %s: %v", id, err)
+		}
+	}
+
+	m.notifyLeadership()
+}
+
+// follow makes the manager a follower of leader. A manager stepping down removes its cron entries
+// and timers, keeping the jobs to start again if it is elected. Runs in progress finish.
+func (m *DefaultJobManager) follow(leader string) {
+	m.mu.Lock()
+	steppingDown, changed := m.leading, m.leader != leader
+	m.leading, m.leader = false, leader
+
+	if steppingDown {
+		for id, entryID := range m.cronEntries {
+			m.cron.Remove(entryID)
+			// Paused jobs keep their entry, to be added back on resume
+			if jobDef, err := m.rootStore.GetJob(id); err == nil && jobDef.Status == StatusPaused {
+				continue
+			}
+			delete(m.cronEntries, id)
+			m.standby[id] = struct{}{}
+		}
+		for id, timer := range m.scheduledJobs {
+			timer.Stop()
+			delete(m.scheduledJobs, id)
+			if job, ok := m.jobs[id]; ok && job.Type() == OneTime {
+				m.standby[id] = struct{}{}
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if steppingDown {
+		log.Printf("Instance %s stepped down as leader", m.instanceID)
+	}
+	if steppingDown || changed {
+		m.notifyLeadership()
+	}
+}
+
+// notifyLeadership lets the system know that the leader changed
+func (m *DefaultJobManager) notifyLeadership() {
+	select {
+	case m.jobsUpdated <- "updated":
+		fmt.Println("Job update (leader changed) notification sent")
+	default: // Non-blocking send to avoid blocking if no one is listening
+	}
+}
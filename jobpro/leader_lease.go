@@ -0,0 +1,142 @@
+package jobpro
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// SQLLease is a leader lease kept in a table of a SQL database shared by the instances. Only the DuckDB driver
+// is built in, and a DuckDB file can't be shared by processes, so the binary doesn't offer it. It only elects
+// the leader: the instances' job stores are not shared. Expiry times are written by the instances,
+// so their clocks must agree to well within a third of the lease's ttl.
+type SQLLease struct {
+	db   *sql.DB
+	name string // the row of the lease, so several groups of instances can share the table
+}
+
+// NewSQLLease returns the lease of the given name in db, creating the lease table if needed.
+// db must be opened with a driver taking $1 style placeholders, as Postgres, SQLite and DuckDB do.
+func NewSQLLease(db *sql.DB, name string) (*SQLLease, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS leader_leases (
+		name VARCHAR PRIMARY KEY,
+		holder VARCHAR NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return nil, serr.Wrap(err, "failed to create leader lease table")
+	}
+	return &SQLLease{db: db, name: name}, nil
+}
+
+// Acquire takes or renews the lease. The update only matches a lease that is held by holder or has expired,
+// so of instances racing for it only one gets it.
+func (l *SQLLease) Acquire(ctx context.Context, holder string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	expires := now.Add(ttl).UnixMilli()
+
+	if _, err := l.db.ExecContext(ctx, `INSERT INTO leader_leases (name, holder, expires_at)
+		VALUES ($1, $2, $3) ON CONFLICT (name) DO NOTHING`, l.name, holder, expires); err != nil {
+		return "", serr.Wrap(err, "failed to insert leader lease")
+	}
+
+	if _, err := l.db.ExecContext(ctx, `UPDATE leader_leases SET holder = $1, expires_at = $2
+		WHERE name = $3 AND (holder = $4 OR expires_at < $5)`,
+		holder, expires, l.name, holder, now.UnixMilli()); err != nil {
+		return "", serr.Wrap(err, "failed to update leader lease")
+	}
+
+	var leader string
+	if err := l.db.QueryRowContext(ctx, `SELECT holder FROM leader_leases WHERE name = $1`,
+		l.name).Scan(&leader); err != nil {
+		return "", serr.Wrap(err, "failed to read leader lease")
+	}
+	return leader, nil
+}
+
+// Release expires the lease if holder has it
+func (l *SQLLease) Release(ctx context.Context, holder string) error {
+	if _, err := l.db.ExecContext(ctx, `UPDATE leader_leases SET expires_at = 0 WHERE name = $1 AND holder = $2`,
+		l.name, holder); err != nil {
+		return serr.Wrap(err, "failed to release leader lease")
+	}
+	return nil
+}
+
+// FileLease is a leader lease held as an exclusive lock on a file, for instances on one host.
+// The lock is held until it is released or the holding process exits, so it needs no expiry:
+// an instance takes over at its next attempt after the leader is gone. The holder's Id is written
+// to the file, so the others can tell who leads.
+type FileLease struct {
+	path   string
+	mu     sync.Mutex
+	locked *os.File // the open lock file while holding the lease
+}
+
+// NewFileLease returns the lease held as a lock on the file at path, which is created if needed
+func NewFileLease(path string) *FileLease {
+	return &FileLease{path: path}
+}
+
+// Acquire takes the lock if it is free. The ttl is not used.
+func (l *FileLease) Acquire(ctx context.Context, holder string, ttl time.Duration) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.locked != nil {
+		return holder, nil
+	}
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", serr.Wrap(err, "failed to open leader lock file")
+	}
+
+	ok, err := tryLock(f)
+	if err != nil || !ok {
+		defer f.Close()
+		if err != nil {
+			return "", serr.Wrap(err, "failed to lock leader lock file")
+		}
+		leader, err := io.ReadAll(f)
+		if err != nil {
+			return "", serr.Wrap(err, "failed to read leader lock file")
+		}
+		return strings.TrimSpace(string(leader)), nil
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, err = fmt.Fprintln(f, holder)
+	}
+	if err != nil {
+		f.Close() // closing the file releases the lock
+		return "", serr.Wrap(err, "failed to write leader lock file")
+	}
+	l.locked = f
+	return holder, nil
+}
+
+// Release unlocks the file if the lease is held
+func (l *FileLease) Release(ctx context.Context, holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.locked == nil {
+		return nil
+	}
+	f := l.locked
+	l.locked = nil
+
+	f.Truncate(0)
+	if err := f.Close(); err != nil {
+		return serr.Wrap(err, "failed to release leader lock file")
+	}
+	return nil
+}
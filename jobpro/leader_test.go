@@ -0,0 +1,177 @@
+package jobpro
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyLease is a lease that fails to be acquired while fail is set
+type flakyLease struct {
+	LeaderLease
+	fail atomic.Bool
+}
+
+func (l *flakyLease) Acquire(ctx context.Context, holder string, ttl time.Duration) (string, error) {
+	if l.fail.Load() {
+		return "", errors.New("lease store unavailable")
+	}
+	return l.LeaderLease.Acquire(ctx, holder, ttl)
+}
+
+// waitForLeader waits until the manager's view of the leader is leader
+func waitForLeader(t *testing.T, mgr *DefaultJobManager, leader string, wait time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(wait); ; time.Sleep(10 * time.Millisecond) {
+		l := mgr.Leadership()
+		if l.Leader == leader && l.IsLeader == (leader == l.Instance) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s to see %q as leader, got %+v", mgr.instanceID, leader, l)
+		}
+	}
+}
+
+func TestSQLLease(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	lease, err := NewSQLLease(db, "test")
+	if err != nil {
+		t.Fatalf("Failed to create lease: %v", err)
+	}
+	ctx := context.Background()
+
+	acquire := func(holder string, ttl time.Duration, want string) {
+		t.Helper()
+		if got, err := lease.Acquire(ctx, holder, ttl); err != nil || got != want {
+			t.Fatalf("Expected %s acquiring the lease to find %s holding it, got %q (%v)", holder, want, got, err)
+		}
+	}
+
+	acquire("a", time.Minute, "a")
+	acquire("b", time.Minute, "a")
+	acquire("a", 50*time.Millisecond, "a") // renewed, for a short while
+
+	// Once it expires, another instance takes it
+	time.Sleep(100 * time.Millisecond)
+	acquire("b", time.Minute, "b")
+	acquire("a", time.Minute, "b")
+
+	// A released lease is free at once, and releasing a lease held by another does nothing
+	if err := lease.Release(ctx, "a"); err != nil {
+		t.Fatalf("Failed to release lease: %v", err)
+	}
+	acquire("a", time.Minute, "b")
+	if err := lease.Release(ctx, "b"); err != nil {
+		t.Fatalf("Failed to release lease: %v", err)
+	}
+	acquire("a", time.Minute, "a")
+}
+
+func TestFileLease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	a, b := NewFileLease(path), NewFileLease(path)
+	ctx := context.Background()
+
+	if got, err := a.Acquire(ctx, "a", time.Minute); err != nil || got != "a" {
+		t.Fatalf("Expected a to get the lock, got %q (%v)", got, err)
+	}
+	if got, err := b.Acquire(ctx, "b", time.Minute); err != nil || got != "a" {
+		t.Fatalf("Expected b to find a holding the lock, got %q (%v)", got, err)
+	}
+	if got, err := a.Acquire(ctx, "a", time.Minute); err != nil || got != "a" {
+		t.Fatalf("Expected a to keep the lock, got %q (%v)", got, err)
+	}
+
+	if err := a.Release(ctx, "a"); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if got, err := b.Acquire(ctx, "b", time.Minute); err != nil || got != "b" {
+		t.Fatalf("Expected b to get the released lock, got %q (%v)", got, err)
+	}
+	b.Release(ctx, "b")
+}
+
+func TestLeaderElection(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	sqlLease, err := NewSQLLease(db, "processors")
+	if err != nil {
+		t.Fatalf("Failed to create lease: %v", err)
+	}
+	const ttl = 600 * time.Millisecond
+
+	// Two instances with the same periodic job, each with its own store and connection to the lease
+	newInstance := func(id string, lease LeaderLease, runs *atomic.Int32) *DefaultJobManager {
+		store, err := NewDuckDBStore("")
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		mgr := NewJobManager(store)
+		mgr.SetLeaderElection(lease, id, ttl)
+		jc := JobConfig{Id: "ticker", Name: "Ticker", IsPeriodic: true, Schedule: "* * * * * *", AutoStart: true,
+			RunFunction: func(ctx context.Context) error {
+				runs.Add(1)
+				return nil
+			}}
+		if err := setupJob(mgr, jc); err != nil {
+			t.Fatalf("Failed to setup job: %v", err)
+		}
+		return mgr
+	}
+
+	var runs1, runs2 atomic.Int32
+	lease1 := &flakyLease{LeaderLease: sqlLease}
+	mgr1 := newInstance("one", lease1, &runs1)
+	waitForLeader(t, mgr1, "one", time.Second)
+	mgr2 := newInstance("two", sqlLease, &runs2)
+	waitForLeader(t, mgr2, "one", time.Second)
+
+	// Only the leader fires the job, and the follower is read-only
+	time.Sleep(2100 * time.Millisecond)
+	if runs1.Load() == 0 || runs2.Load() != 0 {
+		t.Fatalf("Expected only the leader to run the job, got %d and %d runs", runs1.Load(), runs2.Load())
+	}
+	if err := mgr2.TriggerJobNow("ticker"); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Expected the follower to refuse to run the job, got %v", err)
+	}
+	if _, err := mgr2.CreateJob(JobConfig{Name: "New", Command: "true"}); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Expected the follower to refuse to create a job, got %v", err)
+	}
+
+	// A leader that can't renew its lease steps down, and the follower takes over once it expires
+	lease1.fail.Store(true)
+	waitForLeader(t, mgr1, "", ttl+ttl/3)
+	time.Sleep(200 * time.Millisecond) // let a tick that was due go by
+	stepped := runs1.Load()
+	waitForLeader(t, mgr2, "two", ttl+ttl/3)
+	lease1.fail.Store(false)
+	waitForLeader(t, mgr1, "two", time.Second)
+
+	time.Sleep(2100 * time.Millisecond)
+	if runs2.Load() == 0 || runs1.Load() != stepped {
+		t.Fatalf("Expected only the new leader to run the job, got %d and %d more runs",
+			runs1.Load()-stepped, runs2.Load())
+	}
+
+	// A leader shutting down releases the lease, for another instance to take it over at once
+	if err := mgr1.TriggerJobNow("ticker"); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("Expected the former leader to refuse to run the job, got %v", err)
+	}
+	if err := mgr2.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+	waitForLeader(t, mgr1, "one", ttl/3+300*time.Millisecond)
+	mgr1.Shutdown(5 * time.Second)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package jobpro
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLock fails where file locks are not supported, so file leases can't be used
+func tryLock(f *os.File) (bool, error) {
+	return false, errors.New("file locks are not supported on this platform")
+}
@@ -352,7 +352,7 @@ func TestManualJobPersistence(t *testing.T) {
 	defer mgr2.Shutdown(5 * time.Second)
 
 	// Load jobs from the store
-	jobs, err := store2.ListJobs("", "")
+	jobs, err := store2.ListJobs("", "", nil)
 	if err != nil {
 		t.Fatalf("Failed to list jobs: %v", err)
 	}
@@ -0,0 +1,130 @@
+package jobpro
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// defaultRetention is how long job results are kept when a namespace does not configure it
+const defaultRetention = 7 * 24 * time.Hour
+
+// NamespaceConfig holds the per tenant limits of a namespace
+type NamespaceConfig struct {
+	Name string
+	// MaxConcurrent limits how many of the namespace's jobs can run at once. Zero means no limit.
+	MaxConcurrent int
+	// RetentionDays is how long the namespace's job results are kept. Zero means the default of a week.
+	RetentionDays int
+}
+
+// retention returns how long job results are kept for the namespace
+func (c NamespaceConfig) retention() time.Duration {
+	if c.RetentionDays <= 0 {
+		return defaultRetention
+	}
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
+
+// ForNamespace returns a view of the manager restricted to the namespace.
+// The view shares jobs, scheduling and shutdown with the manager, but only sees and
+// operates on the namespace's jobs, and sets up new jobs in that namespace.
+func (m *DefaultJobManager) ForNamespace(namespace string) *DefaultJobManager {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return &DefaultJobManager{
+		jobRegistry: m.jobRegistry,
+		store:       m.rootStore.ForNamespace(namespace),
+		namespace:   namespace,
+	}
+}
+
+// Namespace returns the namespace of a manager view, or an empty string for the
+// manager itself, which sees all namespaces
+func (m *DefaultJobManager) Namespace() string {
+	return m.namespace
+}
+
+// ListNamespaces returns the namespaces visible to the manager
+func (m *DefaultJobManager) ListNamespaces() ([]string, error) {
+	return m.store.ListNamespaces()
+}
+
+// ConfigureNamespaces sets the quota and retention of each namespace given.
+// Jobs already waiting for a slot keep the quota they started waiting under.
+func (m *DefaultJobManager) ConfigureNamespaces(cfgs ...NamespaceConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			cfg.Name = DefaultNamespace
+		}
+		m.nsConfigs[cfg.Name] = cfg
+
+		if cfg.MaxConcurrent > 0 {
+			m.nsSlots[cfg.Name] = make(chan struct{}, cfg.MaxConcurrent)
+		} else {
+			delete(m.nsSlots, cfg.Name)
+		}
+	}
+}
+
+// job returns the loaded job with the id if it is visible to the manager
+// The caller must hold m.mu
+func (m *DefaultJobManager) job(id string) (Job, bool) {
+	job, exists := m.jobs[id]
+	if !exists {
+		return nil, false
+	}
+	if m.namespace != "" && m.jobNamespaces[id] != m.namespace {
+		return nil, false
+	}
+	return job, true
+}
+
+// acquireSlot waits until the job's namespace has a free concurrency slot
+// and returns a function releasing it. Namespaces without a quota never wait.
+// The wait ends with an error if ctx is cancelled first, as the run is stopped or the manager shuts down.
+func (m *DefaultJobManager) acquireSlot(ctx context.Context, id string) (release func(), err error) {
+	m.mu.RLock()
+	namespace := m.jobNamespaces[id]
+	slots := m.nsSlots[namespace]
+	m.mu.RUnlock()
+
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+	default:
+		log.Printf("Job %s waiting for a free slot in namespace %s (max %d concurrent)", id, namespace, cap(slots))
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return func() {}, ctx.Err()
+		}
+	}
+	return func() { <-slots }, nil
+}
+
+// cleanupResults removes job results older than each namespace's retention
+func (m *DefaultJobManager) cleanupResults() {
+	namespaces, err := m.rootStore.ListNamespaces()
+	if err != nil {
+		log.Printf("Error listing namespaces for cleanup: %v", err)
+		return
+	}
+
+	for _, ns := range namespaces {
+		m.mu.RLock()
+		cfg := m.nsConfigs[ns]
+		m.mu.RUnlock()
+
+		if err := m.rootStore.ForNamespace(ns).CleanupJobResults(cfg.retention()); err != nil {
+			log.Printf("Error cleaning up old job results in namespace %s: %v", ns, err)
+		}
+	}
+}
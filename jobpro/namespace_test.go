@@ -0,0 +1,212 @@
+package jobpro
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNamespaceIsolation(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	teamA := mgr.ForNamespace("team-a")
+	teamB := mgr.ForNamespace("team-b")
+
+	for _, jc := range []JobConfig{
+		{Id: "a1", Name: "A1", Namespace: "team-b"}, // the view's namespace wins
+		{Id: "b1", Name: "B1"},
+	} {
+		jc.RunFunction = func(ctx context.Context) error { return nil }
+		view := teamA
+		if jc.Id == "b1" {
+			view = teamB
+		}
+		if err := setupJob(view, jc); err != nil {
+			t.Fatalf("Failed to setup job %s: %v", jc.Id, err)
+		}
+	}
+
+	// Each scoped store only sees its own jobs
+	jobs, err := store.ForNamespace("team-a").ListJobs("", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].JobID != "a1" || jobs[0].Namespace != "team-a" {
+		t.Fatalf("Expected only job a1 in team-a, got %+v", jobs)
+	}
+	if _, err := store.ForNamespace("team-a").GetJob("b1"); err == nil {
+		t.Error("Expected team-a store not to find job b1")
+	}
+
+	// The unscoped store sees everything
+	all, err := store.ListJobs("", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("Expected 2 jobs in the unscoped store, got %d", len(all))
+	}
+
+	namespaces, err := mgr.ListNamespaces()
+	if err != nil {
+		t.Fatalf("Failed to list namespaces: %v", err)
+	}
+	if len(namespaces) != 2 || namespaces[0] != "team-a" || namespaces[1] != "team-b" {
+		t.Errorf("Expected namespaces [team-a team-b], got %v", namespaces)
+	}
+
+	// A job id cannot be taken over from another namespace
+	if err := store.ForNamespace("team-a").SaveJob(JobDef{JobID: "b1", JobName: "Hijack"}); err == nil {
+		t.Error("Expected saving job b1 from team-a to fail")
+	}
+	if def, err := store.GetJob("b1"); err != nil || def.JobName != "B1" {
+		t.Errorf("Expected job b1 to be unchanged, got %+v (err %v)", def, err)
+	}
+
+	// Manager views refuse jobs of other namespaces
+	if err := teamA.TriggerJobNow("b1"); err == nil {
+		t.Error("Expected team-a to be refused triggering job b1")
+	}
+	if err := teamA.DeleteJob("b1"); err == nil {
+		t.Error("Expected team-a to be refused deleting job b1")
+	}
+	if _, err := teamA.GetJobStatus("b1"); err == nil {
+		t.Error("Expected team-a to be refused the status of job b1")
+	}
+
+	// Results are recorded in the job's namespace
+	if err := teamB.TriggerJobNow("b1"); err != nil {
+		t.Fatalf("Failed to trigger job b1: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	results, err := store.ForNamespace("team-b").GetJobResults("b1", 10)
+	if err != nil || len(results) != 1 || results[0].Namespace != "team-b" {
+		t.Errorf("Expected one team-b result for b1, got %+v (err %v)", results, err)
+	}
+	if results, _ := store.ForNamespace("team-a").GetJobResults("b1", 10); len(results) != 0 {
+		t.Errorf("Expected team-a not to see results of b1, got %d", len(results))
+	}
+}
+
+func TestNamespaceQuota(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+	mgr.ConfigureNamespaces(NamespaceConfig{Name: "small", MaxConcurrent: 1})
+
+	var running, maxRunning int32
+	work := func(ctx context.Context) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+
+	small := mgr.ForNamespace("small")
+	for _, id := range []string{"q1", "q2", "q3"} {
+		if err := setupJob(small, JobConfig{Id: id, Name: id, RunFunction: work}); err != nil {
+			t.Fatalf("Failed to setup job %s: %v", id, err)
+		}
+	}
+	for _, id := range []string{"q1", "q2", "q3"} {
+		if err := small.TriggerJobNow(id); err != nil {
+			t.Fatalf("Failed to trigger job %s: %v", id, err)
+		}
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&maxRunning); got != 1 {
+		t.Errorf("Expected at most 1 concurrent job in namespace small, got %d", got)
+	}
+	results, err := store.ForNamespace("small").GetJobResults("q3", 10)
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected job q3 to have run once after waiting for a slot, got %d results (err %v)", len(results), err)
+	}
+}
+
+func TestNamespaceQuotaWaitCancellable(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	mgr.ConfigureNamespaces(NamespaceConfig{Name: "small", MaxConcurrent: 1})
+
+	small := mgr.ForNamespace("small")
+	started := make(chan struct{}, 1)
+	hold := JobConfig{Id: "hold", Name: "Hold", RunFunction: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	parked := JobConfig{Id: "parked", Name: "Parked", RunFunction: func(ctx context.Context) error { return nil }}
+	for _, jc := range []JobConfig{hold, parked} {
+		if err := setupJob(small, jc); err != nil {
+			t.Fatalf("Failed to setup job %s: %v", jc.Id, err)
+		}
+	}
+	if err := small.TriggerJobNow("hold"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	<-started
+
+	// A run waiting for a slot is a run in progress: another run of its job is skipped at once, and it can be cancelled
+	parkedRun := func() RunningRun {
+		t.Helper()
+		if err := small.TriggerJobNow("parked"); err != nil {
+			t.Fatalf("Failed to trigger job: %v", err)
+		}
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			for _, run := range small.RunningRuns() {
+				if run.JobID == "parked" {
+					return run
+				}
+			}
+		}
+		t.Fatal("Expected the parked run to be in progress")
+		return RunningRun{}
+	}
+	run := parkedRun()
+	if err := small.TriggerJobNow("parked"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if result := waitForResult(t, store, "parked", 2*time.Second); result.Status != StatusSkippedOverlap {
+		t.Errorf("Expected the second run to be skipped, got %s", result.Status)
+	}
+	if err := small.CancelRun(run.RunID); err != nil {
+		t.Fatalf("Failed to cancel the parked run: %v", err)
+	}
+	if results := waitForResults(t, store, "parked", 2); results[0].Status != StatusCancelled {
+		t.Errorf("Expected the parked run to be cancelled, got %s", results[0].Status)
+	}
+
+	// Shutting down doesn't wait for parked runs to get a slot
+	parkedRun()
+	begin := time.Now()
+	if err := mgr.Shutdown(5 * time.Second); err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 2*time.Second {
+		t.Errorf("Expected the shutdown to reach the parked run, took %s", elapsed)
+	}
+}
@@ -0,0 +1,329 @@
+package jobpro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PoolPolicy is what a run does when a resource pool of its job is exhausted
+type PoolPolicy string
+
+const (
+	// PoolWait waits for the pool to free up (the default)
+	PoolWait PoolPolicy = "wait"
+	// PoolSkip drops the run, recorded with StatusSkippedPool
+	PoolSkip PoolPolicy = "skip"
+	// PoolFail records the run as failed
+	PoolFail PoolPolicy = "fail"
+)
+
+// defaultRatePeriod is the period of a pool's RateLimit when it doesn't set one
+const defaultRatePeriod = time.Minute
+
+// ErrPoolExhausted is the error of a run that did not start as a pool of its job had no free slot,
+// or had reached its rate limit
+var ErrPoolExhausted = errors.New("pool exhausted")
+
+// PoolConfig declares a resource pool, such as a downstream service several jobs call.
+// Jobs reference pools by name, and each run holds a slot of its job's pools while it goes.
+type PoolConfig struct {
+	Name string
+	// MaxConcurrent limits how many runs of the pool's jobs go at once. Zero means no limit.
+	MaxConcurrent int
+	// RateLimit limits how many runs start per RatePeriod (in seconds, a minute by default).
+	// Up to Burst runs (RateLimit by default) can start at once after a quiet spell. Zero means no limit.
+	RateLimit  int
+	RatePeriod int `json:",omitempty"`
+	Burst      int `json:",omitempty"`
+}
+
+// Validate checks the limits of the pool
+func (c PoolConfig) Validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("pool has no name")
+	case c.MaxConcurrent < 0 || c.RateLimit < 0 || c.RatePeriod < 0 || c.Burst < 0:
+		return fmt.Errorf("pool %s: limits can't be negative", c.Name)
+	case c.MaxConcurrent == 0 && c.RateLimit == 0:
+		return fmt.Errorf("pool %s: needs a concurrency or rate limit", c.Name)
+	}
+	return nil
+}
+
+// PoolStats is the utilization of a pool
+type PoolStats struct {
+	PoolConfig
+	InUse   int     // runs holding a slot
+	Waiting int     // runs waiting for a slot or for the rate limit
+	Tokens  float64 // runs that could start now within the rate limit
+}
+
+// pool is a configured pool: a semaphore of MaxConcurrent slots and a token bucket of the rate limit
+type pool struct {
+	cfg   PoolConfig
+	slots chan struct{} // nil without MaxConcurrent
+
+	mu       sync.Mutex
+	inUse    int
+	waiting  int
+	tokens   float64
+	refilled time.Time
+}
+
+func newPool(cfg PoolConfig) *pool {
+	p := &pool{cfg: cfg, refilled: time.Now()}
+	if cfg.MaxConcurrent > 0 {
+		p.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	p.tokens = float64(p.burst())
+	return p
+}
+
+// burst is how many tokens the bucket holds
+func (p *pool) burst() int {
+	if p.cfg.Burst > 0 {
+		return p.cfg.Burst
+	}
+	return p.cfg.RateLimit
+}
+
+// period is the period of the rate limit
+func (p *pool) period() time.Duration {
+	if p.cfg.RatePeriod > 0 {
+		return time.Duration(p.cfg.RatePeriod) * time.Second
+	}
+	return defaultRatePeriod
+}
+
+// refill adds the tokens earned since the last refill
+// The caller must hold p.mu
+func (p *pool) refill(now time.Time) {
+	rate := float64(p.cfg.RateLimit) / p.period().Seconds()
+	p.tokens = min(p.tokens+now.Sub(p.refilled).Seconds()*rate, float64(p.burst()))
+	p.refilled = now
+}
+
+// takeToken takes a token of the rate limit, or returns how long until one is earned
+func (p *pool) takeToken() time.Duration {
+	if p.cfg.RateLimit <= 0 {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.refill(time.Now())
+	if p.tokens >= 1 {
+		p.tokens--
+		return 0
+	}
+	perToken := p.period() / time.Duration(p.cfg.RateLimit)
+	return time.Duration((1 - p.tokens) * float64(perToken))
+}
+
+func (p *pool) addWaiting(n int) {
+	p.mu.Lock()
+	p.waiting += n
+	p.mu.Unlock()
+}
+
+// acquire takes a slot and a token of the pool. Unless wait is set, an exhausted pool returns ErrPoolExhausted
+// at once. Otherwise onWait is called and acquire waits, returning the cause of ctx if it is cancelled first.
+func (p *pool) acquire(ctx context.Context, wait bool, onWait func()) error {
+	exhausted := fmt.Errorf("%w: %s", ErrPoolExhausted, p.cfg.Name)
+
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		default:
+			if !wait {
+				return exhausted
+			}
+			onWait()
+			p.addWaiting(1)
+			select {
+			case p.slots <- struct{}{}:
+				p.addWaiting(-1)
+			case <-ctx.Done():
+				p.addWaiting(-1)
+				return context.Cause(ctx)
+			}
+		}
+	}
+
+	for {
+		delay := p.takeToken()
+		if delay == 0 {
+			break
+		}
+		if !wait {
+			p.releaseSlot()
+			return exhausted
+		}
+
+		onWait()
+		p.addWaiting(1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			p.addWaiting(-1)
+		case <-ctx.Done():
+			timer.Stop()
+			p.addWaiting(-1)
+			p.releaseSlot()
+			return context.Cause(ctx)
+		}
+	}
+
+	p.mu.Lock()
+	p.inUse++
+	p.mu.Unlock()
+	return nil
+}
+
+// releaseSlot gives back the slot taken by acquire
+func (p *pool) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// release gives back the pool once the run is over. Tokens are not given back, they are earned over time.
+func (p *pool) release() {
+	p.mu.Lock()
+	p.inUse--
+	p.mu.Unlock()
+	p.releaseSlot()
+}
+
+// stats returns the utilization of the pool
+func (p *pool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cfg.RateLimit > 0 {
+		p.refill(time.Now())
+	}
+	return PoolStats{PoolConfig: p.cfg, InUse: p.inUse, Waiting: p.waiting, Tokens: p.tokens}
+}
+
+// ConfigurePools validates and sets the resource pools given, replacing any of the same name.
+// Runs already holding or waiting for a pool keep the one they started with.
+func (m *DefaultJobManager) ConfigurePools(cfgs ...PoolConfig) error {
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cfg := range cfgs {
+		m.pools[cfg.Name] = newPool(cfg)
+	}
+	return nil
+}
+
+// Pools returns the utilization of the resource pools, by name.
+// Pools are shared by all namespaces.
+func (m *DefaultJobManager) Pools() []PoolStats {
+	m.mu.RLock()
+	pools := make([]*pool, 0, len(m.pools))
+	for _, p := range m.pools {
+		pools = append(pools, p)
+	}
+	m.mu.RUnlock()
+
+	stats := make([]PoolStats, 0, len(pools))
+	for _, p := range pools {
+		stats = append(stats, p.stats())
+	}
+	slices.SortFunc(stats, func(a, b PoolStats) int { return strings.Compare(a.Name, b.Name) })
+	return stats
+}
+
+// checkPools returns an error if any of the pool names is unknown
+// The caller must hold m.mu
+func (m *DefaultJobManager) checkPools(names []string) error {
+	for _, name := range names {
+		if _, ok := m.pools[name]; !ok {
+			return fmt.Errorf("unknown pool %q", name)
+		}
+	}
+	return nil
+}
+
+// checkPoolPolicy returns an error if the policy is not one of the PoolPolicy values
+func checkPoolPolicy(policy PoolPolicy) error {
+	switch policy {
+	case "", PoolWait, PoolSkip, PoolFail:
+		return nil
+	}
+	return fmt.Errorf("invalid pool policy %q", policy)
+}
+
+// jobPools returns the pools of a job built from a config, and what its runs do when one is exhausted
+func jobPools(job Job) ([]string, PoolPolicy) {
+	if cj, ok := job.(ConfigurableJob); ok {
+		cfg := cj.Config()
+		return cfg.Pools, cfg.PoolPolicy
+	}
+	return nil, ""
+}
+
+// acquirePools takes a slot of each pool of the job, in name order so runs sharing pools don't deadlock,
+// and returns a function releasing them and how long the run waited for them.
+// Pools that are no longer configured are ignored.
+func (m *DefaultJobManager) acquirePools(ctx context.Context, id string, job Job) (release func(), waited time.Duration, err error) {
+	names, policy := jobPools(job)
+	if len(names) == 0 {
+		return func() {}, 0, nil
+	}
+	names = slices.Compact(slices.Sorted(slices.Values(names)))
+
+	m.mu.RLock()
+	pools := make([]*pool, 0, len(names))
+	for _, name := range names {
+		if p, ok := m.pools[name]; ok {
+			pools = append(pools, p)
+		} else {
+			log.Printf("Job %s: pool %s is not configured, ignoring it", id, name)
+		}
+	}
+	m.mu.RUnlock()
+
+	var held []*pool
+	release = func() {
+		for _, p := range held {
+			p.release()
+		}
+	}
+
+	start := time.Now()
+	waiting := false
+	for _, p := range pools {
+		err = p.acquire(ctx, policy == "" || policy == PoolWait, func() {
+			if !waiting {
+				log.Printf("Job %s waiting for pool %s", id, p.cfg.Name)
+			}
+			waiting = true
+		})
+		if err != nil {
+			break
+		}
+		held = append(held, p)
+	}
+
+	if waiting {
+		waited = time.Since(start)
+	}
+	if err != nil {
+		release()
+		return func() {}, waited, err
+	}
+	return release, waited, nil
+}
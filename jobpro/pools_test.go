@@ -0,0 +1,154 @@
+package jobpro
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// poolStats returns the utilization of the named pool
+func poolStats(t *testing.T, mgr *DefaultJobManager, name string) PoolStats {
+	t.Helper()
+	for _, p := range mgr.Pools() {
+		if p.Name == name {
+			return p
+		}
+	}
+	t.Fatalf("Expected pool %s to be configured", name)
+	return PoolStats{}
+}
+
+func TestPoolConcurrency(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	if err := mgr.ConfigurePools(PoolConfig{Name: "billing-api", MaxConcurrent: 1}); err != nil {
+		t.Fatalf("Failed to configure pools: %v", err)
+	}
+	if err := mgr.ConfigurePools(PoolConfig{Name: "unlimited"}); err == nil {
+		t.Error("Expected a pool without limits to be refused")
+	}
+
+	// Two jobs sharing the pool, each run lasting until released
+	started, release := make(chan string, 4), make(chan struct{})
+	for _, jc := range []JobConfig{
+		{Id: "invoices", Name: "Invoices", Pools: []string{"billing-api"}},
+		{Id: "refunds", Name: "Refunds", Pools: []string{"billing-api"}},
+		{Id: "skips", Name: "Skips", Pools: []string{"billing-api"}, PoolPolicy: PoolSkip},
+		{Id: "fails", Name: "Fails", Pools: []string{"billing-api"}, PoolPolicy: PoolFail},
+	} {
+		jc.RunFunction = func(ctx context.Context) error {
+			started <- jc.Id
+			<-release
+			return nil
+		}
+		if err := setupJob(mgr, jc); err != nil {
+			t.Fatalf("Failed to setup job: %v", err)
+		}
+	}
+	if err := setupJob(mgr, JobConfig{Id: "unknown", Name: "Unknown", Pools: []string{"nope"},
+		RunFunction: func(ctx context.Context) error { return nil }}); err == nil {
+		t.Error("Expected a job referencing an unknown pool to be refused")
+	}
+
+	if err := mgr.TriggerJobNow("invoices"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	<-started
+
+	// While the pool is full, the next run waits, and the others are skipped or failed
+	if err := mgr.TriggerJobNow("refunds"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); poolStats(t, mgr, "billing-api").Waiting != 1; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a run waiting for the pool, got %+v", poolStats(t, mgr, "billing-api"))
+		}
+	}
+	if stats := poolStats(t, mgr, "billing-api"); stats.InUse != 1 {
+		t.Errorf("Expected one run holding the pool, got %+v", stats)
+	}
+
+	for _, id := range []string{"skips", "fails"} {
+		if err := mgr.TriggerJobNow(id); err != nil {
+			t.Fatalf("Failed to trigger job: %v", err)
+		}
+	}
+	if result := waitForResult(t, store, "skips", 2*time.Second); result.Status != StatusSkippedPool {
+		t.Errorf("Expected the run to be %s, got %s", StatusSkippedPool, result.Status)
+	}
+	if result := waitForResult(t, store, "fails", 2*time.Second); result.Status != StatusFailed ||
+		!strings.Contains(result.ErrorMsg, "billing-api") {
+		t.Errorf("Expected the run to fail on the exhausted pool, got %s (%s)", result.Status, result.ErrorMsg)
+	}
+
+	// Once the first run is over, the waiting one goes, and records how long it waited
+	time.Sleep(100 * time.Millisecond)
+	release <- struct{}{}
+	if id := <-started; id != "refunds" {
+		t.Fatalf("Expected the waiting run to start, got %s", id)
+	}
+	release <- struct{}{}
+	if result := waitForResult(t, store, "refunds", 2*time.Second); result.Status != StatusComplete ||
+		result.PoolWait < 100*time.Millisecond {
+		t.Errorf("Expected a completed run that waited for the pool, got %s (waited %s)", result.Status, result.PoolWait)
+	}
+	waitNotRunning(t, mgr, 2*time.Second)
+	if stats := poolStats(t, mgr, "billing-api"); stats.InUse != 0 || stats.Waiting != 0 {
+		t.Errorf("Expected the pool to be free, got %+v", stats)
+	}
+}
+
+func TestPoolRateLimit(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	if err := mgr.ConfigurePools(PoolConfig{Name: "search-api", RateLimit: 1, RatePeriod: 60}); err != nil {
+		t.Fatalf("Failed to configure pools: %v", err)
+	}
+	jc := JobConfig{Id: "indexer", Name: "Indexer", Pools: []string{"search-api"},
+		RunFunction: func(ctx context.Context) error { return nil }}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	// The first run takes the only start of the minute
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if result := waitForResult(t, store, jc.Id, 2*time.Second); result.Status != StatusComplete || result.PoolWait != 0 {
+		t.Errorf("Expected the first run to start at once, got %s (waited %s)", result.Status, result.PoolWait)
+	}
+
+	// The next waits for the rate, until cancelled
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); poolStats(t, mgr, "search-api").Waiting != 1; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a run waiting for the rate limit, got %+v", poolStats(t, mgr, "search-api"))
+		}
+	}
+	runs := mgr.RunningRuns()
+	if len(runs) != 1 {
+		t.Fatalf("Expected the waiting run to be listed, got %+v", runs)
+	}
+	if err := mgr.CancelRun(runs[0].RunID); err != nil {
+		t.Fatalf("Failed to cancel run: %v", err)
+	}
+	if result := waitForResults(t, store, jc.Id, 2)[0]; result.Status != StatusCancelled || result.PoolWait == 0 {
+		t.Errorf("Expected the waiting run to be cancelled, got %s (waited %s)", result.Status, result.PoolWait)
+	}
+	if stats := poolStats(t, mgr, "search-api"); stats.Waiting != 0 || stats.InUse != 0 {
+		t.Errorf("Expected nothing left waiting, got %+v", stats)
+	}
+}
@@ -0,0 +1,154 @@
+package jobpro
+
+import (
+	"fmt"
+	"job_processor/util"
+	"time"
+)
+
+const (
+	defaultPreviewRuns = 10
+	maxPreviewRuns     = 100
+)
+
+// PreviewOptions are the settings a schedule is previewed with
+type PreviewOptions struct {
+	Timezone       string
+	Calendars      []string
+	CalendarPolicy CalendarPolicy
+	Count          int       // number of runs, defaults to 10
+	From           time.Time // defaults to now
+}
+
+// SchedulePreview is what a schedule will do
+type SchedulePreview struct {
+	Schedule    string
+	Timezone    string `json:",omitempty"`
+	Periodic    bool
+	Description string
+	Runs        []PreviewRun
+	Warnings    []string `json:",omitempty"`
+}
+
+// PreviewRun is an upcoming run. A run in a blackout window carries the window,
+// and with the defer policy, when the job runs instead.
+type PreviewRun struct {
+	Time       time.Time
+	Blackout   *Blackout `json:",omitempty"`
+	DeferredTo time.Time `json:",omitzero"`
+}
+
+// PreviewSchedule returns the next runs of a schedule - recurring, or a relative or absolute time -
+// with an English description, warnings, and the runs excluded by calendars
+func (m *DefaultJobManager) PreviewSchedule(schedule string, opts PreviewOptions) (SchedulePreview, error) {
+	preview := SchedulePreview{Schedule: schedule, Timezone: scheduleTimezone(schedule, opts.Timezone)}
+	if schedule == "" {
+		return preview, fmt.Errorf("schedule is required")
+	}
+
+	loc, err := loadLocation(preview.Timezone)
+	if err != nil {
+		return preview, err
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	switch opts.CalendarPolicy {
+	case "", CalendarSkip, CalendarDefer:
+	default:
+		return preview, fmt.Errorf("invalid calendar policy %q", opts.CalendarPolicy)
+	}
+
+	m.mu.RLock()
+	err = m.checkCalendars(opts.Calendars)
+	cals := make([]Calendar, 0, len(opts.Calendars))
+	for _, name := range opts.Calendars {
+		cals = append(cals, m.calendars[name])
+	}
+	m.mu.RUnlock()
+	if err != nil {
+		return preview, err
+	}
+
+	count := opts.Count
+	if count <= 0 {
+		count = defaultPreviewRuns
+	}
+	count = min(count, maxPreviewRuns)
+
+	from := opts.From
+	if from.IsZero() {
+		from = time.Now()
+	}
+
+	var times []time.Time
+	if sched, schedErr := ParseSchedule(schedule, opts.Timezone); schedErr == nil {
+		preview.Periodic = true
+		preview.Description = sched.Describe()
+		for t := sched.Next(from); !t.IsZero() && len(times) < count; t = sched.Next(t) {
+			times = append(times, t)
+		}
+	} else {
+		t, err := nextRunTime(OneTime, schedule, opts.Timezone, from)
+		if err != nil {
+			return preview, fmt.Errorf("not a recurring schedule (%v), or a time (%v)", schedErr, err)
+		}
+		preview.Description = "Once, at " + t.In(loc).Format("Mon Jan 2 2006 15:04:05 MST")
+		times = append(times, t)
+	}
+
+	blackedOut := 0
+	for _, t := range times {
+		run := PreviewRun{Time: t}
+		if b, ok := blackout(cals, t, loc); ok {
+			run.Blackout = &b
+			blackedOut++
+			if opts.CalendarPolicy == CalendarDefer {
+				run.DeferredTo = b.Until
+			}
+		}
+		preview.Runs = append(preview.Runs, run)
+	}
+
+	preview.Warnings = previewWarnings(preview, times, from, count, blackedOut)
+	return preview, nil
+}
+
+// previewWarnings flags schedules that probably don't do what was intended
+func previewWarnings(preview SchedulePreview, times []time.Time, from time.Time, count, blackedOut int) (warnings []string) {
+	if len(times) == 0 {
+		return []string{"never fires"}
+	}
+
+	if !preview.Periodic {
+		if !times[0].After(from) {
+			warnings = append(warnings, "is in the past, so runs as soon as it is scheduled")
+		}
+	} else if len(times) < count {
+		warnings = append(warnings, fmt.Sprintf("stops after %d runs", len(times)))
+	}
+
+	if times[0].Sub(from) > 366*24*time.Hour {
+		warnings = append(warnings, "first run is more than a year away ("+util.FormatDurationUntil(times[0])+")")
+	}
+
+	gap := time.Duration(0)
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); gap == 0 || d < gap {
+			gap = d
+		}
+	}
+	switch {
+	case len(times) < 2:
+	case gap < time.Second:
+		warnings = append(warnings, "runs more than once per second")
+	case gap == time.Second:
+		warnings = append(warnings, "runs every second")
+	}
+
+	if blackedOut == len(times) {
+		warnings = append(warnings, "every previewed run falls in a blackout window")
+	}
+	return warnings
+}
@@ -0,0 +1,142 @@
+package jobpro
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPreviewSchedule(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	if err := mgr.SaveCalendars(Calendar{Name: "weekends", Timezone: "UTC",
+		Recurring: []RecurringWindow{{Weekdays: []string{"sat", "sun"}, Reason: "weekend"}}}); err != nil {
+		t.Fatalf("Failed to save calendar: %v", err)
+	}
+
+	// Thursday
+	from := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		opts     PreviewOptions
+		runs     []string // in UTC
+		desc     string
+		warnings []string
+		excluded []int // indexes of runs in blackout windows
+	}{
+		{
+			name:     "Cron",
+			schedule: "0 30 9 * * 1-5",
+			opts:     PreviewOptions{Timezone: "UTC", Count: 2},
+			runs:     []string{"2026-10-16T09:30:00Z", "2026-10-19T09:30:00Z"},
+			desc:     "Weekdays at 9:30 (UTC)",
+		},
+		{
+			name:     "Calendar exclusions",
+			schedule: "every day at 12:00",
+			opts:     PreviewOptions{Timezone: "UTC", Count: 4, Calendars: []string{"weekends"}},
+			runs:     []string{"2026-10-15T12:00:00Z", "2026-10-16T12:00:00Z", "2026-10-17T12:00:00Z", "2026-10-18T12:00:00Z"},
+			excluded: []int{2, 3},
+		},
+		{
+			name:     "Every second",
+			schedule: "* * * * * *",
+			opts:     PreviewOptions{Count: 3},
+			warnings: []string{"runs every second"},
+		},
+		{
+			name:     "Never fires",
+			schedule: "0 0 0 30 2 *",
+			warnings: []string{"never fires"},
+		},
+		{
+			name:     "Rule that ends",
+			schedule: "DTSTART:20261016T060000Z RRULE:FREQ=DAILY;COUNT=2",
+			opts:     PreviewOptions{Count: 5},
+			runs:     []string{"2026-10-16T06:00:00Z", "2026-10-17T06:00:00Z"},
+			warnings: []string{"stops after 2 runs"},
+		},
+		{
+			name:     "Absolute time in zone",
+			schedule: "2026-12-25 09:00:00",
+			opts:     PreviewOptions{Timezone: "America/New_York"},
+			runs:     []string{"2026-12-25T14:00:00Z"},
+			desc:     "Once, at Fri Dec 25 2026 09:00:00 EST",
+		},
+		{
+			name:     "Time in the past",
+			schedule: "2020-01-01 00:00:00",
+			opts:     PreviewOptions{Timezone: "UTC"},
+			warnings: []string{"is in the past, so runs as soon as it is scheduled"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.From = from
+			preview, err := mgr.PreviewSchedule(tt.schedule, tt.opts)
+			if err != nil {
+				t.Fatalf("PreviewSchedule(%q): %v", tt.schedule, err)
+			}
+
+			if tt.runs != nil {
+				var got []string
+				for _, run := range preview.Runs {
+					got = append(got, run.Time.UTC().Format(time.RFC3339))
+				}
+				if !slices.Equal(got, tt.runs) {
+					t.Errorf("Expected runs %v, got %v", tt.runs, got)
+				}
+			}
+
+			if tt.desc != "" && preview.Description != tt.desc {
+				t.Errorf("Expected description %q, got %q", tt.desc, preview.Description)
+			}
+
+			for _, w := range tt.warnings {
+				if !slices.Contains(preview.Warnings, w) {
+					t.Errorf("Expected warning %q, got %v", w, preview.Warnings)
+				}
+			}
+
+			for i, run := range preview.Runs {
+				if excluded := slices.Contains(tt.excluded, i); excluded != (run.Blackout != nil) {
+					t.Errorf("Run %d: expected excluded %v, got blackout %+v", i, excluded, run.Blackout)
+				}
+			}
+		})
+	}
+
+	// Deferred runs say when they happen instead
+	preview, err := mgr.PreviewSchedule("0 0 12 * * 6", PreviewOptions{Timezone: "UTC", Count: 1, From: from,
+		Calendars: []string{"weekends"}, CalendarPolicy: CalendarDefer})
+	if err != nil {
+		t.Fatalf("Failed to preview: %v", err)
+	}
+	if got := preview.Runs[0].DeferredTo.UTC().Format(time.RFC3339); got != "2026-10-19T00:00:00Z" {
+		t.Errorf("Expected the run deferred to Monday, got %s", got)
+	}
+
+	for _, bad := range []struct {
+		schedule string
+		opts     PreviewOptions
+	}{
+		{"", PreviewOptions{}},
+		{"every fortnight", PreviewOptions{}},
+		{"0 0 9 * * *", PreviewOptions{Timezone: "Nowhere/Special"}},
+		{"0 0 9 * * *", PreviewOptions{Calendars: []string{"unknown"}}},
+		{"0 0 9 * * *", PreviewOptions{CalendarPolicy: "sometimes"}},
+	} {
+		if _, err := mgr.PreviewSchedule(bad.schedule, bad.opts); err == nil {
+			t.Errorf("Expected preview of %q with %+v to fail", bad.schedule, bad.opts)
+		}
+	}
+}
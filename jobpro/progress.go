@@ -0,0 +1,269 @@
+package jobpro
+
+import (
+	"context"
+	"encoding/json"
+	"job_processor/util"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHeartbeatTimeout is how long a run that has reported progress or a heartbeat can go without one
+	// before it is flagged as stuck, for jobs without a HeartbeatTimeout
+	defaultHeartbeatTimeout = 5 * time.Minute
+	// progressPublishInterval is the least time between two progress updates of a run pushed to the UI.
+	// Reports in between are pushed at the next check.
+	progressPublishInterval = 250 * time.Millisecond
+	// progressCheckInterval is how often runs are checked for heartbeats and progress not pushed yet
+	progressCheckInterval = time.Second
+)
+
+// ProgressUpdatePrefix starts the messages of the progress channel, followed by the Progress as JSON.
+// They go out on the same SSE channel as job updates, which carry no such prefix.
+const ProgressUpdatePrefix = "progress "
+
+// Progress is how far a run in progress has got, as reported by the job
+type Progress struct {
+	JobID         string
+	Namespace     string  // the namespace of the job, which alone sees the progress
+	Percent       float64 // 0 to 100, or -1 while unknown
+	Step          string  // the current step, e.g. "extract"
+	Message       string
+	LastHeartbeat time.Time // the last progress report or heartbeat, zero if none yet
+	Stuck         bool      // the run has not sent a heartbeat within the job's HeartbeatTimeout
+	Done          bool      // the run is over; pushed once, to clear its progress bar
+}
+
+// ProgressReporter reports the progress of a run. A work function gets it from its context
+// with ProgressFromContext. Every report counts as a heartbeat too.
+type ProgressReporter struct {
+	mu         sync.Mutex
+	progress   Progress
+	timeout    time.Duration  // how long the run can go without a heartbeat; 0 watches it only once it sent one
+	started    time.Time      // when the run started, the heartbeat deadline counting from it until the first
+	published  time.Time      // when the progress was last pushed
+	dirty      bool           // changed since it was last pushed
+	publish    func(Progress) // pushes the progress, nil for detached reporters
+	workerBeat time.Time      // the last heartbeat a worker forwarded, in the worker's clock
+}
+
+type progressKey struct{}
+
+// withProgress returns a context carrying a new ProgressReporter of the job's run, which pushes the progress with publish
+func withProgress(ctx context.Context, jobID, namespace string, timeout time.Duration,
+	publish func(Progress)) (context.Context, *ProgressReporter) {
+	p := &ProgressReporter{
+		progress: Progress{JobID: jobID, Namespace: namespace, Percent: -1},
+		timeout:  timeout,
+		started:  time.Now(),
+		publish:  publish,
+	}
+	return context.WithValue(ctx, progressKey{}, p), p
+}
+
+// ProgressFromContext returns the ProgressReporter of the run owning ctx.
+// Outside of a run a detached reporter is returned, so callers never need to nil check.
+func ProgressFromContext(ctx context.Context) *ProgressReporter {
+	if p, ok := ctx.Value(progressKey{}).(*ProgressReporter); ok {
+		return p
+	}
+	return &ProgressReporter{progress: Progress{Percent: -1}}
+}
+
+// Report sets the percentage done, the current step and a message
+func (p *ProgressReporter) Report(percent float64, step, message string) {
+	p.update(func(pr *Progress) {
+		pr.Percent, pr.Step, pr.Message = min(max(percent, 0), 100), step, message
+	})
+}
+
+// SetPercent sets the percentage done, from 0 to 100
+func (p *ProgressReporter) SetPercent(percent float64) {
+	p.update(func(pr *Progress) { pr.Percent = min(max(percent, 0), 100) })
+}
+
+// SetStep starts a step of the run, clearing the message
+func (p *ProgressReporter) SetStep(step string) {
+	p.update(func(pr *Progress) { pr.Step, pr.Message = step, "" })
+}
+
+// Heartbeat tells the manager the run is alive, without changing its progress.
+// Jobs with a HeartbeatTimeout must call it (or report progress) within the timeout to not be flagged as stuck.
+func (p *ProgressReporter) Heartbeat() {
+	p.update(func(*Progress) {})
+}
+
+// Progress returns the progress reported so far
+func (p *ProgressReporter) Progress() Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.progress
+}
+
+// update changes the progress and records a heartbeat, pushing the progress unless it was pushed just now
+func (p *ProgressReporter) update(change func(*Progress)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	change(&p.progress)
+	wasStuck := p.progress.Stuck
+	p.progress.LastHeartbeat = time.Now().UTC()
+	p.progress.Stuck = false
+	if wasStuck {
+		log.Printf("Job %s: the run is sending heartbeats again", p.progress.JobID)
+	}
+	p.dirty = true
+	if wasStuck || time.Since(p.published) >= progressPublishInterval {
+		p.flushLocked()
+	}
+}
+
+// forwarded applies the progress a worker forwarded with the heartbeat of a remote run.
+// A heartbeat of the job on the worker counts as one here.
+func (p *ProgressReporter) forwarded(pr Progress) {
+	p.mu.Lock()
+	beat := pr.LastHeartbeat.After(p.workerBeat)
+	if beat {
+		p.workerBeat = pr.LastHeartbeat
+	}
+	p.mu.Unlock()
+	if !beat {
+		return
+	}
+
+	p.update(func(cur *Progress) {
+		cur.Percent, cur.Step, cur.Message = pr.Percent, pr.Step, pr.Message
+	})
+}
+
+// check pushes progress not pushed yet, and flags the run as stuck once it misses its heartbeat deadline.
+// It reports whether the run just got stuck.
+func (p *ProgressReporter) check(now time.Time) (stuck bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	timeout, since := p.timeout, p.progress.LastHeartbeat
+	if since.IsZero() {
+		since = p.started
+	}
+	if timeout == 0 && !p.progress.LastHeartbeat.IsZero() {
+		timeout = defaultHeartbeatTimeout
+	}
+	if timeout > 0 && !p.progress.Stuck && now.Sub(since) > timeout {
+		p.progress.Stuck, p.dirty, stuck = true, true, true
+	}
+	if p.dirty {
+		p.flushLocked()
+	}
+	return stuck
+}
+
+// begin restarts the heartbeat deadline of a run that waited before it started
+func (p *ProgressReporter) begin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = time.Now()
+}
+
+// finish pushes that the run is over
+func (p *ProgressReporter) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress.Done = true
+	p.flushLocked()
+}
+
+// flushLocked pushes the progress
+// The caller must hold p.mu
+func (p *ProgressReporter) flushLocked() {
+	p.dirty = false
+	p.published = time.Now()
+	if p.publish != nil {
+		p.publish(p.progress)
+	}
+}
+
+// publishProgress sends a run's progress to the progress channel, dropping it if the channel is full
+func (m *DefaultJobManager) publishProgress(pr Progress) {
+	msg, err := json.Marshal(pr)
+	if err != nil {
+		return
+	}
+	select {
+	case m.progressUpdates <- ProgressUpdatePrefix + string(msg):
+	default: // the next update of the run catches up
+	}
+}
+
+// UpdateVisible reports whether a message of the job update or progress channels may be pushed to the
+// subscribers of the manager. A namespace view only sees the progress of runs in its namespace.
+func (m *DefaultJobManager) UpdateVisible(msg any) bool {
+	str, ok := msg.(string)
+	if m.namespace == "" || !ok || !strings.HasPrefix(str, ProgressUpdatePrefix) {
+		return true
+	}
+	var pr Progress
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(str, ProgressUpdatePrefix)), &pr); err != nil {
+		return false
+	}
+	return pr.Namespace == m.namespace
+}
+
+// GetProgressChan returns the channel of the progress of runs, as messages starting with ProgressUpdatePrefix
+func (m *DefaultJobManager) GetProgressChan() <-chan any {
+	return m.progressUpdates
+}
+
+// watchProgress pushes progress reported since the last push and flags runs that stopped sending heartbeats
+// as stuck, until the manager shuts down
+func (m *DefaultJobManager) watchProgress() {
+	ticker := time.NewTicker(progressCheckInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.mu.RLock()
+		if m.shutdown {
+			m.mu.RUnlock()
+			return
+		}
+		running := make(map[string]*ProgressReporter, len(m.runningJobs))
+		for id, run := range m.runningJobs {
+			running[id] = run.progress
+		}
+		m.mu.RUnlock()
+
+		for id, progress := range running {
+			if progress.check(now) {
+				last := progress.Progress().LastHeartbeat
+				log.Printf("Job %s: the run looks stuck, no heartbeat since %s", id,
+					util.If(last.IsZero(), "it started", last.Format(time.RFC3339)))
+			}
+		}
+	}
+}
+
+// RunProgress returns the progress of the job's run in progress, if it is running
+func (m *DefaultJobManager) RunProgress(id string) (Progress, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.job(id); !exists {
+		return Progress{}, false
+	}
+	run, running := m.runningJobs[id]
+	if !running {
+		return Progress{}, false
+	}
+	return run.progress.Progress(), true
+}
+
+// heartbeatTimeout returns the HeartbeatTimeout of a job built from a config, 0 for other jobs
+func heartbeatTimeout(job Job) time.Duration {
+	if cj, ok := job.(ConfigurableJob); ok {
+		return time.Duration(cj.Config().HeartbeatTimeout) * time.Second
+	}
+	return 0
+}
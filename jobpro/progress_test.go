@@ -0,0 +1,162 @@
+package jobpro
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nextProgress waits for the next progress pushed on the manager's progress channel
+func nextProgress(t *testing.T, mgr *DefaultJobManager, wait time.Duration) Progress {
+	t.Helper()
+	select {
+	case msg := <-mgr.GetProgressChan():
+		s, _ := msg.(string)
+		var p Progress
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(s, ProgressUpdatePrefix)), &p); err != nil ||
+			!strings.HasPrefix(s, ProgressUpdatePrefix) {
+			t.Fatalf("Expected a progress message, got %q (%v)", s, err)
+		}
+		return p
+	case <-time.After(wait):
+		t.Fatalf("Expected progress to be pushed within %s", wait)
+		return Progress{}
+	}
+}
+
+func TestRunProgress(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	reported, finish := make(chan struct{}), make(chan struct{})
+	jc := JobConfig{Id: "etl", Name: "ETL", AutoStart: true,
+		RunFunction: func(ctx context.Context) error {
+			ProgressFromContext(ctx).Report(40, "transform", "batch 4 of 10")
+			close(reported)
+			<-finish
+			return nil
+		}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	<-reported
+
+	p := nextProgress(t, mgr, 2*time.Second)
+	if p.JobID != "etl" || p.Percent != 40 || p.Step != "transform" || p.Message != "batch 4 of 10" || p.Done {
+		t.Errorf("Expected the reported progress to be pushed, got %+v", p)
+	}
+	if p, running := mgr.RunProgress("etl"); !running || p.Percent != 40 || p.LastHeartbeat.IsZero() {
+		t.Errorf("Expected the progress of the running job, got %+v (running: %v)", p, running)
+	}
+	jobs, _, err := mgr.ListJobsWithPagination(10, nil)
+	if err != nil {
+		t.Fatalf("Failed to list jobs: %v", err)
+	}
+	if len(jobs) == 0 || jobs[0].Progress == nil || jobs[0].Progress.Step != "transform" {
+		t.Errorf("Expected the job's row to carry its progress, got %+v", jobs)
+	}
+
+	// The end of the run is pushed, to clear the progress bar
+	close(finish)
+	if p := nextProgress(t, mgr, 2*time.Second); !p.Done {
+		t.Errorf("Expected the end of the run to be pushed, got %+v", p)
+	}
+	waitForResult(t, store, "etl", 5*time.Second)
+	if _, running := mgr.RunProgress("etl"); running {
+		t.Error("Expected no progress once the run is over")
+	}
+
+	// Outside of a run, reporting progress does nothing
+	ProgressFromContext(context.Background()).SetPercent(50)
+}
+
+func TestStuckRun(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	beat, finish := make(chan struct{}), make(chan struct{})
+	jc := JobConfig{Id: "hung", Name: "Hung", HeartbeatTimeout: 1, AutoStart: true,
+		RunFunction: func(ctx context.Context) error {
+			<-beat
+			ProgressFromContext(ctx).Heartbeat()
+			<-finish
+			return nil
+		}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	defer close(finish)
+
+	// No heartbeat within the timeout of the start flags the run
+	waitStuck := func(want bool) {
+		t.Helper()
+		for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+			if p, _ := mgr.RunProgress("hung"); p.Stuck == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the run's stuck flag to be %v", want)
+			}
+		}
+	}
+	waitStuck(true)
+
+	// A heartbeat clears it, until the run misses the next
+	close(beat)
+	waitStuck(false)
+	waitStuck(true)
+}
+
+func TestProgressNamespaces(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	reported, finish := make(chan struct{}), make(chan struct{})
+	jc := JobConfig{Id: "etl", Name: "ETL", AutoStart: true,
+		RunFunction: func(ctx context.Context) error {
+			ProgressFromContext(ctx).Report(40, "transform", "")
+			close(reported)
+			<-finish
+			return nil
+		}}
+	if err := setupJob(mgr.ForNamespace("team-a"), jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	defer close(finish)
+	<-reported
+
+	var msg any
+	select {
+	case msg = <-mgr.GetProgressChan():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected progress to be pushed")
+	}
+	if !strings.Contains(msg.(string), `"Namespace":"team-a"`) {
+		t.Errorf("Expected the progress to carry the job's namespace, got %q", msg)
+	}
+
+	// Only the job's namespace and the whole manager see the progress, job updates are seen by all
+	if !mgr.UpdateVisible(msg) || !mgr.ForNamespace("team-a").UpdateVisible(msg) {
+		t.Error("Expected the progress to be visible to the manager and the job's namespace")
+	}
+	if mgr.ForNamespace("team-b").UpdateVisible(msg) {
+		t.Error("Expected the progress not to be visible to another namespace")
+	}
+	if !mgr.ForNamespace("team-b").UpdateVisible("updated") {
+		t.Error("Expected job updates to be visible to every namespace")
+	}
+}
@@ -1,6 +1,8 @@
 package jobpro
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,11 +31,48 @@ type JobConfig struct {
 	Schedule   string
 	Priority   int // Priority is not yet supported
 	MaxRunTime int
-	RetryCount int  // RetryCount is not yet supported
+	RetryCount int  // RetryCount is how many more times a failed run is tried, waiting a little longer before each retry
 	AutoStart  bool // Whether to automatically start the job after creation (default: true)
-	// We can use either the TriggerEndpoint or the JobFunction.
+	// HeartbeatTimeout is how long, in seconds, a run can go without reporting progress or a heartbeat
+	// (see ProgressFromContext) before it is flagged as stuck. Without it, runs are watched once they send one,
+	// with a timeout of 5 minutes.
+	HeartbeatTimeout int
+	// ResumeInterrupted runs the job again when it is started after a shutdown or crash interrupted its run.
+	// The run gets back the state the interrupted one saved with CheckpointFromContext(ctx).Save.
+	ResumeInterrupted bool
+	// Tags are labels used to filter jobs and operate on them in bulk, e.g. {"team": "etl", "env": "prod"}
+	Tags map[string]string
+	// Namespace isolates the job (and its results) for a tenant. Defaults to DefaultNamespace.
+	Namespace string
+	// Timezone is the IANA timezone (e.g. "America/New_York") the schedule is in.
+	// Cron schedules can also be prefixed with "CRON_TZ=America/New_York ". Defaults to server local time.
+	Timezone string
+	// Calendars name blackout calendars: scheduled runs falling in one of their windows
+	// are skipped, or with CalendarPolicy "defer", run once when the window ends
+	Calendars      []string
+	CalendarPolicy CalendarPolicy
+	// Jitter is the maximum random delay, in seconds, of scheduled runs.
+	// Spread is a window, in seconds, scheduled runs are delayed within by an offset hashed from the Id,
+	// so jobs on the same schedule fire at different but steady times. Without either, the manager's
+	// JitterConfig applies.
+	Jitter int
+	Spread int
+	// Remote runs are queued for workers (job_processor worker) to claim and run, rather than run by the scheduler.
+	// Requires is a selector of the worker labels a worker needs to claim them, e.g. "gpu,region=eu".
+	Remote   bool
+	Requires string
+	// Pools name resource pools shared with other jobs (see PoolConfig), limiting how many of their runs go at once
+	// and how often they start. PoolPolicy is what a run does when one is exhausted: "wait" (the default), "skip" or "fail".
+	Pools      []string
+	PoolPolicy PoolPolicy
+	// We can use either the TriggerEndpoint, the Command or the JobFunction.
 	TriggerEndpoint string
-	JobFunction     func() error // no longer used
+	// Command is a shell command, run with sh -c. Its output is the run's message.
+	Command     string
+	JobFunction func() error `json:"-"` // no longer used
+	// RunFunction is a context aware JobFunction. Structured output can be recorded with
+	// OutputFromContext(ctx).SetRecords(n) etc. and is stored with the job result.
+	RunFunction func(ctx context.Context) error `json:"-"`
 }
 
 var jobCfgs = &jobConfigs{}
@@ -147,15 +186,34 @@ func setupJob(mgr JobMgr, jc JobConfig) error {
 	return nil
 }
 
+// maxTriggerResponseSize limits how much of a remote job's response is read for output
+const maxTriggerResponseSize = 1 << 20
+
 // TriggerRemoteJob will trigger the job endpoint given by the JobConfig
-func TriggerRemoteJob(jc JobConfig) error {
+// If the endpoint responds with a JSON object, its "output" member (or the whole object
+// if there is none) is recorded as the structured output of the run.
+func TriggerRemoteJob(ctx context.Context, jc JobConfig) error {
 	if jc.TriggerEndpoint == "" {
 		return serr.New("Trigger endpoint is empty")
 	}
 
 	endpoint := BackendURLWoPath() + jc.TriggerEndpoint
 
-	resp, err := http.Get(endpoint)
+	// Runs with parameters, such as those of a webhook, post them to the endpoint as JSON
+	method, payload := http.MethodGet, io.Reader(nil)
+	if params := encodeParams(ctx); params != nil {
+		method, payload = http.MethodPost, bytes.NewReader(params)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return serr.Wrap(err, "Failed to create remote job request")
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return serr.Wrap(err, "Failed to trigger remote job")
 	}
@@ -164,11 +222,30 @@ func TriggerRemoteJob(jc JobConfig) error {
 	if resp.StatusCode >= 400 {
 		return serr.F("Failed to trigger remote job. Bad status %s", resp.Status)
 	}
-	// We don't care about the response body
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTriggerResponseSize))
+	if err != nil {
+		logger.LogErr(serr.Wrap(err, "Failed to read remote job response"), "endpoint", endpoint)
+		return nil // the job itself was triggered
+	}
+	OutputFromContext(ctx).Merge(parseTriggerOutput(body))
 
 	return nil
 }
 
+// parseTriggerOutput extracts structured output from a remote job response body
+// Bodies that are not JSON objects carry no output
+func parseTriggerOutput(body []byte) map[string]any {
+	var obj map[string]any
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil
+	}
+	if out, ok := obj["output"].(map[string]any); ok {
+		return out
+	}
+	return obj
+}
+
 func BackendURLWoPath() (urlWoPath string) {
 	return fmt.Sprintf("http://localhost:%s", backendPort)
 }
@@ -0,0 +1,531 @@
+package jobpro
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// ErrRunLost is returned to a worker reporting on a run it no longer holds the lease on,
+// such as one queued again after its lease expired
+var ErrRunLost = errors.New("run is not leased to the worker")
+
+const (
+	// defaultRunLeaseTTL is how long a worker holds a run without sending a heartbeat
+	defaultRunLeaseTTL = 30 * time.Second
+	// runLeaseCheckInterval is how often expired leases are looked for
+	runLeaseCheckInterval = time.Second
+	// maxRunAttempts is how many times a run is claimed before a lost lease fails it, rather than queue it again
+	maxRunAttempts = 3
+	// workerForgetAfter is how long a worker not heard from is still listed
+	workerForgetAfter = time.Hour
+)
+
+// WorkerInfo describes a worker claiming remote runs
+type WorkerInfo struct {
+	ID       string
+	Labels   map[string]string // capabilities, matched against the Requires selector of jobs
+	LastSeen time.Time
+	Runs     int // runs the worker holds leases on
+}
+
+// WorkerClaim is the run a worker claimed, nil if none was queued, and how long the worker holds it between heartbeats
+type WorkerClaim struct {
+	Run      *QueuedRun
+	LeaseTTL time.Duration
+}
+
+// RunReport is a worker's report of a run it finished
+type RunReport struct {
+	Worker    string
+	Stats     Stats
+	Error     string // the run's error, empty on success
+	TimedOut  bool   // the run exceeded the job's MaxRunTime
+	Cancelled bool   // the run was stopped as the scheduler asked
+}
+
+// reportedError is the error of a run on a worker, wrapping ErrTimedOut if the run timed out
+type reportedError struct {
+	msg  string
+	kind error
+}
+
+func (e reportedError) Error() string { return e.msg }
+func (e reportedError) Unwrap() error { return e.kind }
+
+// err returns the error of the run
+func (r RunReport) err() error {
+	switch {
+	case r.Error == "":
+		return nil
+	case r.TimedOut:
+		return reportedError{r.Error, ErrTimedOut}
+	default:
+		return reportedError{r.Error, nil}
+	}
+}
+
+// runQueue dispatches the runs of remote jobs to workers. The queue is persisted in the store
+// and mirrored here, so runs queued or claimed before a restart are not lost.
+type runQueue struct {
+	mu       sync.Mutex
+	runs     map[string]*QueuedRun        // by run Id
+	waiting  map[string]chan RunReport    // the runs executeJob waits on, by run Id
+	progress map[string]*ProgressReporter // the progress reporters of the runs waited on, by run Id
+	workers  map[string]*WorkerInfo       // by worker Id
+	wake     chan struct{}                // closed when runs are queued, waking workers waiting for one
+	leaseTTL time.Duration
+
+	// The claim each worker is waiting on, closed when the worker claims again: a worker claims one run
+	// at a time, so its earlier claim was left by a request it gave up on, and must not lease it a run
+	claims map[string]chan struct{}
+}
+
+func newRunQueue() *runQueue {
+	return &runQueue{
+		runs:     make(map[string]*QueuedRun),
+		waiting:  make(map[string]chan RunReport),
+		progress: make(map[string]*ProgressReporter),
+		workers:  make(map[string]*WorkerInfo),
+		wake:     make(chan struct{}),
+		leaseTTL: defaultRunLeaseTTL,
+		claims:   make(map[string]chan struct{}),
+	}
+}
+
+// endClaims ends the claims workers are waiting on, without a run
+func (q *runQueue) endClaims() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, claim := range q.claims {
+		close(claim)
+		delete(q.claims, id)
+	}
+}
+
+// wakeWorkers wakes the workers waiting for a run
+// The caller must hold q.mu
+func (q *runQueue) wakeWorkers() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// seen records that a worker was heard from. Labels are updated when given.
+// The caller must hold q.mu
+func (q *runQueue) seen(id string, labels map[string]string) {
+	w, ok := q.workers[id]
+	if !ok {
+		w = &WorkerInfo{ID: id}
+		q.workers[id] = w
+	}
+	if labels != nil {
+		w.Labels = labels
+	}
+	w.LastSeen = time.Now().UTC()
+}
+
+// loadRunQueue reads the queue persisted by a previous run of the scheduler.
+// Runs claimed then stay with their workers; those whose lease expires are queued again.
+func (m *DefaultJobManager) loadRunQueue() error {
+	runs, err := m.rootStore.ListQueuedRuns()
+	if err != nil {
+		return err
+	}
+
+	m.queue.mu.Lock()
+	defer m.queue.mu.Unlock()
+	for i := range runs {
+		m.queue.runs[runs[i].RunID] = &runs[i]
+	}
+	if len(runs) > 0 {
+		log.Printf("Loaded %d remote runs from the queue", len(runs))
+	}
+	return nil
+}
+
+// SetRunLease sets how long a worker holds a run without sending a heartbeat (30s by default).
+// Workers send heartbeats every third of it.
+func (m *DefaultJobManager) SetRunLease(ttl time.Duration) {
+	m.queue.mu.Lock()
+	defer m.queue.mu.Unlock()
+	m.queue.leaseTTL = ttl
+}
+
+// remoteConfig returns the config of a job run by workers
+func remoteConfig(job Job) (JobConfig, bool) {
+	cj, ok := job.(ConfigurableJob)
+	if !ok || !cj.Config().Remote {
+		return JobConfig{}, false
+	}
+	return cj.Config(), true
+}
+
+// runRemote queues a run of a remote job and waits for the worker claiming it to report.
+// When the run is cancelled, the worker is told to stop it - unless the scheduler is shutting down:
+// the run is then left to the workers, and recorded when reported after a restart.
+func (m *DefaultJobManager) runRemote(ctx context.Context, runID, id, namespace string, cfg JobConfig, trigger runTrigger) (Stats, error) {
+	q := m.queue
+	run := &QueuedRun{
+		RunID:         runID,
+		JobID:         id,
+		Namespace:     namespace,
+		Config:        cfg,
+		Requires:      cfg.Requires,
+		Params:        trigger.params,
+		Trigger:       trigger.source,
+		ScheduledTime: trigger.scheduled.UTC(),
+		EnqueuedAt:    time.Now().UTC(),
+		Status:        RunQueued,
+	}
+	report := make(chan RunReport, 1)
+
+	q.mu.Lock()
+	if err := m.rootStore.EnqueueRun(*run); err != nil {
+		q.mu.Unlock()
+		return Stats{}, serr.Wrap(err, "failed to queue the run for workers")
+	}
+	q.runs[run.RunID] = run
+	q.waiting[run.RunID] = report
+	q.progress[run.RunID] = ProgressFromContext(ctx)
+	q.wakeWorkers()
+	q.mu.Unlock()
+
+	select {
+	case r := <-report:
+		return r.Stats, r.err()
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	delete(q.waiting, run.RunID)
+	delete(q.progress, run.RunID)
+	if _, queued := q.runs[run.RunID]; queued && !errors.Is(context.Cause(ctx), ErrShutdown) {
+		m.abandonRun(run)
+	}
+	q.mu.Unlock()
+	return Stats{SuccessMsg: "Job was canceled"}, ctx.Err()
+}
+
+// abandonRun takes a run not claimed yet off the queue, and has the worker of a claimed run stop it
+// The caller must hold m.queue.mu
+func (m *DefaultJobManager) abandonRun(run *QueuedRun) {
+	if run.Status == RunQueued {
+		if err := m.rootStore.DeleteQueuedRun(run.RunID); err != nil {
+			log.Printf("Error removing run %s from the queue: %v", run.RunID, err)
+			return
+		}
+		delete(m.queue.runs, run.RunID)
+		return
+	}
+
+	run.Status = RunAbandoned
+	if err := m.rootStore.SaveQueuedRun(*run); err != nil {
+		log.Printf("Error abandoning queued run %s: %v", run.RunID, err)
+	}
+}
+
+// ClaimRun leases to the worker the oldest queued run whose requirements its labels meet,
+// waiting up to wait for one to be queued. The claim has no run if none was, or if the worker
+// claimed again meanwhile, having given up on this claim. Only the leader hands out runs.
+func (m *DefaultJobManager) ClaimRun(ctx context.Context, worker WorkerInfo, wait time.Duration) (WorkerClaim, error) {
+	if worker.ID == "" {
+		return WorkerClaim{}, errors.New("worker Id is required")
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	q := m.queue
+	superseded := make(chan struct{})
+	q.mu.Lock()
+	if earlier, ok := q.claims[worker.ID]; ok {
+		close(earlier)
+	}
+	q.claims[worker.ID] = superseded
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		if q.claims[worker.ID] == superseded {
+			delete(q.claims, worker.ID)
+		}
+		q.mu.Unlock()
+	}()
+
+	for {
+		m.mu.RLock()
+		notLeader, shutdown := m.checkLeader(), m.shutdown
+		m.mu.RUnlock()
+		if notLeader != nil {
+			return WorkerClaim{}, notLeader
+		}
+		if shutdown {
+			return WorkerClaim{}, nil
+		}
+
+		q.mu.Lock()
+		select {
+		case <-superseded:
+			q.mu.Unlock()
+			return WorkerClaim{}, nil
+		default:
+		}
+		q.seen(worker.ID, worker.Labels)
+		run, err := m.claimNext(worker)
+		wake, claim := q.wake, WorkerClaim{Run: run, LeaseTTL: q.leaseTTL}
+		q.mu.Unlock()
+		if err != nil || run != nil {
+			return claim, err
+		}
+
+		select {
+		case <-wake:
+		case <-timeout.C:
+			return claim, nil
+		case <-superseded:
+			return claim, nil
+		case <-ctx.Done():
+			return claim, ctx.Err()
+		}
+	}
+}
+
+// claimNext leases the oldest run the worker can claim, returning a copy of it, or nil if there is none
+// The caller must hold m.queue.mu
+func (m *DefaultJobManager) claimNext(worker WorkerInfo) (*QueuedRun, error) {
+	q := m.queue
+	var candidates []*QueuedRun
+	for _, run := range q.runs {
+		if run.Status == RunQueued {
+			candidates = append(candidates, run)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b *QueuedRun) int {
+		return cmp.Or(a.EnqueuedAt.Compare(b.EnqueuedAt), cmp.Compare(a.RunID, b.RunID))
+	})
+
+	for _, run := range candidates {
+		sel, err := ParseSelector(run.Requires)
+		if err != nil || !sel.Matches(worker.Labels) {
+			continue
+		}
+
+		claimed := *run
+		claimed.Status = RunClaimed
+		claimed.Worker = worker.ID
+		claimed.Attempts++
+		claimed.LeaseExpires = time.Now().UTC().Add(q.leaseTTL)
+		if err := m.rootStore.SaveQueuedRun(claimed); err != nil {
+			return nil, serr.Wrap(err, "failed to claim run")
+		}
+		*run = claimed
+		return &claimed, nil
+	}
+	return nil, nil
+}
+
+// HeartbeatRun renews the worker's lease on a run, updating its progress if the worker forwards it.
+// It reports whether the worker should stop the run, which has been cancelled.
+// ErrRunLost tells the worker the run is no longer its own.
+func (m *DefaultJobManager) HeartbeatRun(runID, workerID string, progress *Progress) (stop bool, err error) {
+	q := m.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seen(workerID, nil)
+	run, ok := q.runs[runID]
+	if !ok || run.Worker != workerID || run.Status == RunQueued {
+		return false, ErrRunLost
+	}
+	if run.Status == RunAbandoned {
+		return true, nil
+	}
+
+	renewed := *run
+	renewed.LeaseExpires = time.Now().UTC().Add(q.leaseTTL)
+	if err := m.rootStore.SaveQueuedRun(renewed); err != nil {
+		return false, serr.Wrap(err, "failed to renew run lease")
+	}
+	*run = renewed
+	if reporter := q.progress[runID]; reporter != nil && progress != nil {
+		reporter.forwarded(*progress)
+	}
+	return false, nil
+}
+
+// CompleteRun takes the report of a worker on a run it finished, and records the run's result
+func (m *DefaultJobManager) CompleteRun(runID string, report RunReport) error {
+	q := m.queue
+	q.mu.Lock()
+	q.seen(report.Worker, nil)
+	run, ok := q.runs[runID]
+	if !ok || run.Worker != report.Worker || run.Status == RunQueued {
+		q.mu.Unlock()
+		return ErrRunLost
+	}
+	if err := m.rootStore.DeleteQueuedRun(runID); err != nil {
+		q.mu.Unlock()
+		return serr.Wrap(err, "failed to remove run from the queue")
+	}
+	delete(q.runs, runID)
+	waiter, waiting := q.waiting[runID]
+	delete(q.waiting, runID)
+	delete(q.progress, runID)
+	q.mu.Unlock()
+
+	switch {
+	case waiting:
+		waiter <- report
+	case run.Status == RunAbandoned: // its cancellation is recorded already
+	default: // queued before the scheduler restarted
+		m.recordReport(*run, report)
+	}
+	return nil
+}
+
+// recordReport records the result of a remote run that no run of executeJob waits on
+func (m *DefaultJobManager) recordReport(run QueuedRun, report RunReport) {
+	var cause error
+	if report.Cancelled {
+		cause = ErrCancelled
+	}
+	status, errMsg := resultStatus(report.err(), cause)
+
+	start := report.Stats.StartTimeUTC
+	if start.IsZero() {
+		start = time.Now().UTC()
+	}
+	m.queueResult(JobResult{
+		JobID:         run.JobID,
+		StartTime:     start,
+		EndTime:       start.Add(report.Stats.Duration),
+		Duration:      report.Stats.Duration,
+		Status:        status,
+		SuccessMsg:    report.Stats.SuccessMsg,
+		ErrorMsg:      errMsg,
+		Output:        report.Stats.Output,
+		Namespace:     run.Namespace,
+		ScheduledTime: run.ScheduledTime,
+		Trigger:       run.Trigger,
+	})
+}
+
+// reapRunLeases queues again the runs of workers that stopped sending heartbeats, until the manager shuts down.
+// A run lost maxRunAttempts times fails.
+func (m *DefaultJobManager) reapRunLeases() {
+	ticker := time.NewTicker(runLeaseCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.mu.RLock()
+		shutdown, leading := m.shutdown, m.leading
+		m.mu.RUnlock()
+		if shutdown {
+			return
+		}
+		if leading {
+			m.reapExpiredLeases(time.Now())
+		}
+	}
+}
+
+// reapExpiredLeases handles the runs whose lease expired before now
+func (m *DefaultJobManager) reapExpiredLeases(now time.Time) {
+	type failedRun struct {
+		run    QueuedRun
+		waiter chan RunReport
+	}
+	var failed []failedRun
+
+	q := m.queue
+	q.mu.Lock()
+	for id, run := range q.runs {
+		if run.Status == RunQueued || run.LeaseExpires.After(now) {
+			continue
+		}
+
+		// A worker told to stop a run may never report it
+		if run.Status == RunAbandoned || run.Attempts >= maxRunAttempts {
+			if err := m.rootStore.DeleteQueuedRun(id); err != nil {
+				log.Printf("Error removing run %s from the queue: %v", id, err)
+				continue
+			}
+			delete(q.runs, id)
+			if run.Status == RunClaimed {
+				failed = append(failed, failedRun{*run, q.waiting[id]})
+				delete(q.waiting, id)
+				delete(q.progress, id)
+			}
+			continue
+		}
+
+		log.Printf("Worker %s lost the lease on run %s of job %s, queueing it again", run.Worker, id, run.JobID)
+		requeued := *run
+		requeued.Status, requeued.Worker, requeued.LeaseExpires = RunQueued, "", time.Time{}
+		if err := m.rootStore.SaveQueuedRun(requeued); err != nil {
+			log.Printf("Error queueing run %s again: %v", id, err)
+			continue
+		}
+		*run = requeued
+		q.wakeWorkers()
+	}
+	q.mu.Unlock()
+
+	for _, f := range failed {
+		report := RunReport{Worker: f.run.Worker,
+			Error: fmt.Sprintf("run lost by workers %d times, last by %s", f.run.Attempts, f.run.Worker)}
+		log.Printf("Job %s: %s", f.run.JobID, report.Error)
+		if f.waiter != nil {
+			f.waiter <- report
+		} else {
+			m.recordReport(f.run, report)
+		}
+	}
+}
+
+// Workers returns the workers heard from in the last hour, by Id
+func (m *DefaultJobManager) Workers() []WorkerInfo {
+	q := m.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	workers := make([]WorkerInfo, 0, len(q.workers))
+	for id, w := range q.workers {
+		if time.Since(w.LastSeen) > workerForgetAfter {
+			delete(q.workers, id)
+			continue
+		}
+		info := *w
+		for _, run := range q.runs {
+			if run.Worker == id && run.Status != RunQueued {
+				info.Runs++
+			}
+		}
+		workers = append(workers, info)
+	}
+	slices.SortFunc(workers, func(a, b WorkerInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return workers
+}
+
+// QueuedRuns returns the runs queued for workers or claimed by them, oldest first
+func (m *DefaultJobManager) QueuedRuns() []QueuedRun {
+	q := m.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	runs := make([]QueuedRun, 0, len(q.runs))
+	for _, run := range q.runs {
+		if m.namespace == "" || run.Namespace == m.namespace {
+			runs = append(runs, *run)
+		}
+	}
+	slices.SortFunc(runs, func(a, b QueuedRun) int {
+		return cmp.Or(a.EnqueuedAt.Compare(b.EnqueuedAt), cmp.Compare(a.RunID, b.RunID))
+	})
+	return runs
+}
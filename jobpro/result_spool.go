@@ -0,0 +1,212 @@
+package jobpro
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// spoolReplayBatch is how many spooled results are recorded per transaction when the spool is replayed
+const spoolReplayBatch = 500
+
+// resultSpool holds the results that could not be recorded in the store, until they are replayed into it.
+// With a path, results are appended to that file as JSON lines (a write-ahead log outliving restarts),
+// otherwise, or if the file can't be written, they are kept in memory.
+type resultSpool struct {
+	mu   sync.Mutex
+	path string
+	mem  []JobResult
+	// spooled is set while the file may hold results, so replays don't have to check it
+	spooled bool
+}
+
+// add spools results
+func (s *resultSpool) add(results []JobResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != "" {
+		err := s.appendFile(results)
+		if err == nil {
+			s.spooled = true
+			return
+		}
+		log.Printf("Failed to spool %d job results to %s, keeping them in memory: %v", len(results), s.path, err)
+	}
+	s.mem = append(s.mem, results...)
+}
+
+// pending reports whether there are spooled results
+func (s *resultSpool) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spooled || len(s.mem) > 0
+}
+
+// reject sets aside results the store can never record, such as those of a deleted job, so they don't hold up
+// the others. With a path, they are appended to the dead-letter file next to the spool, otherwise they are logged.
+func (s *resultSpool) reject(results []JobResult, reason error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setAside(results, reason)
+}
+
+// setAside is reject for callers holding s.mu
+func (s *resultSpool) setAside(results []JobResult, reason error) {
+	if len(results) == 0 {
+		return
+	}
+	if s.path != "" {
+		err := appendResultsFile(s.path+".rejected", results)
+		if err == nil {
+			log.Printf("Set aside %d job results the store rejected in %s.rejected: %v", len(results), s.path, reason)
+			return
+		}
+		log.Printf("Failed to set aside %d rejected job results: %v", len(results), err)
+	}
+	for _, result := range results {
+		log.Printf("Dropping the result of job %s started at %s, rejected by the store: %v",
+			result.JobID, result.StartTime.Format(time.RFC3339), reason)
+	}
+}
+
+// recordBatch records results with record. A bad result fails the whole batch, so then the results
+// are recorded one by one. Those the store rejects are returned in rejected, with the reason. If the store
+// fails otherwise, the results left unrecorded are returned in rest, with the error.
+func recordBatch(record func([]JobResult) error, results []JobResult) (rejected, rest []JobResult, reason, err error) {
+	if err = record(results); err == nil {
+		return nil, nil, nil, nil
+	}
+	if len(results) == 1 {
+		if errors.Is(err, ErrResultRejected) {
+			return results, nil, err, nil
+		}
+		return nil, results, nil, err
+	}
+
+	for i, result := range results {
+		switch err := record([]JobResult{result}); {
+		case err == nil:
+		case errors.Is(err, ErrResultRejected):
+			rejected, reason = append(rejected, result), err
+		default:
+			return rejected, results[i:], reason, err
+		}
+	}
+	return rejected, nil, reason, nil
+}
+
+// appendFile appends results to the spool file, syncing it before returning. The caller must hold s.mu
+func (s *resultSpool) appendFile(results []JobResult) error {
+	return appendResultsFile(s.path, results)
+}
+
+// appendResultsFile appends results to a file as JSON lines, syncing it before returning
+func appendResultsFile(path string, results []JobResult) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w) // one result per line
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readFile returns the results in the spool file. Lines that can't be decoded, such as one cut short
+// by a crash while it was written, are skipped. The caller must hold s.mu
+func (s *resultSpool) readFile() ([]JobResult, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var results []JobResult
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var result JobResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			log.Printf("Skipping unreadable line %d of result spool %s: %v", line, s.path, err)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+// replay records the spooled results with record, in batches, removing them from the spool as they are recorded.
+// Results the store rejects are set aside, so they don't hold up the others. It stops when the store fails,
+// leaving the results not recorded yet spooled. It returns how many results were recorded.
+func (s *resultSpool) replay(record func([]JobResult) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := s.mem
+	if s.path != "" {
+		fromFile, err := s.readFile()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read result spool %s: %w", s.path, err)
+		}
+		results = append(fromFile, results...)
+	}
+
+	var replayed, recorded int
+	var recordErr error
+	for replayed < len(results) {
+		batch := results[replayed:min(replayed+spoolReplayBatch, len(results))]
+		rejected, rest, reason, err := recordBatch(record, batch)
+		s.setAside(rejected, reason)
+		replayed += len(batch) - len(rest)
+		recorded += len(batch) - len(rest) - len(rejected)
+		if recordErr = err; err != nil {
+			break
+		}
+	}
+	if replayed == 0 && recordErr != nil {
+		return 0, recordErr
+	}
+
+	// Keep what is left, in the file if there is one
+	rest := results[replayed:]
+	s.mem = nil
+	if s.path != "" {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return recorded, fmt.Errorf("failed to clear result spool %s: %w", s.path, err)
+		}
+		s.spooled = false
+		if len(rest) > 0 {
+			if err := s.appendFile(rest); err != nil {
+				s.mem = rest
+			} else {
+				s.spooled = true
+			}
+		}
+	} else {
+		s.mem = rest
+	}
+	return recorded, recordErr
+}
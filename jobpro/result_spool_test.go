@@ -0,0 +1,330 @@
+package jobpro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyStore is a store whose result writes fail while fail is set
+type flakyStore struct {
+	*DuckDBStore
+	fail atomic.Bool
+}
+
+func (s *flakyStore) RecordJobResults(results []JobResult) error {
+	if s.fail.Load() {
+		return errors.New("store unavailable")
+	}
+	return s.DuckDBStore.RecordJobResults(results)
+}
+
+// runOnce triggers a job and waits for the run's result to be processed
+func runOnce(t *testing.T, mgr *DefaultJobManager, jobID string, ran <-chan struct{}) {
+	t.Helper()
+	if err := mgr.TriggerJobNow(jobID); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the job to run")
+	}
+	waitNotRunning(t, mgr, 10*time.Second)
+}
+
+// waitNotRunning waits until no job is running
+func waitNotRunning(t *testing.T, mgr *DefaultJobManager, wait time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(wait); ; time.Sleep(20 * time.Millisecond) {
+		mgr.mu.RLock()
+		running := len(mgr.runningJobs)
+		mgr.mu.RUnlock()
+		if running == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected no running jobs, got %d", running)
+		}
+	}
+}
+
+func TestResultSpool(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, spoolPath := filepath.Join(dir, "spool.db"), filepath.Join(dir, "spool.db.results-spool")
+
+	store1, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	flaky := &flakyStore{DuckDBStore: store1}
+	mgr1 := NewJobManager(flaky)
+	mgr1.SetResultSpool(spoolPath)
+
+	ran := make(chan struct{}, 10)
+	jc := JobConfig{Id: "spooled", Name: "Spooled", RunFunction: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}}
+	if err := setupJob(mgr1, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	// While the store fails, the result is spooled to the file
+	flaky.fail.Store(true)
+	runOnce(t, mgr1, jc.Id, ran)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if _, err := os.Stat(spoolPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the result to be spooled")
+		}
+	}
+
+	// Once the store works again, the spooled result is recorded along with the next
+	flaky.fail.Store(false)
+	runOnce(t, mgr1, jc.Id, ran)
+	waitForResults(t, store1, jc.Id, 2)
+	if _, err := os.Stat(spoolPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the spool to be cleared, got %v", err)
+	}
+
+	// A result spooled before a shutdown is recorded on the next start
+	flaky.fail.Store(true)
+	runOnce(t, mgr1, jc.Id, ran)
+	if err := mgr1.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+
+	// A line cut short by a crash is skipped
+	f, err := os.OpenFile(spoolPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Expected a spool file: %v", err)
+	}
+	f.WriteString(`{"JobID":"spooled","Sta`)
+	f.Close()
+
+	store2, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	mgr2 := NewJobManager(store2)
+	defer mgr2.Shutdown(5 * time.Second)
+	mgr2.SetResultSpool(spoolPath)
+
+	results, err := store2.GetJobResults(jc.Id, 10)
+	if err != nil || len(results) != 3 {
+		t.Fatalf("Expected 3 results after the replay, got %d (%v)", len(results), err)
+	}
+	if _, err := os.Stat(spoolPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the spool to be cleared after the replay, got %v", err)
+	}
+}
+
+func TestResultsUnderLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs thousands of jobs")
+	}
+
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(10 * time.Second)
+
+	// Many more concurrent runs than the results channel holds
+	const jobs = 2000
+	ids := make([]string, jobs)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("load-%d", i)
+		jc := JobConfig{Id: ids[i], Name: ids[i], RunFunction: func(ctx context.Context) error { return nil }}
+		if err := setupJob(mgr, jc); err != nil {
+			t.Fatalf("Failed to setup job: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := mgr.TriggerJobNow(id); err != nil {
+				t.Errorf("Failed to trigger job %s: %v", id, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every run is recorded, none dropped
+	var runs int
+	for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		summary, err := store.GetSuccessRateSeries("", BucketDay, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to count results: %v", err)
+		}
+		runs = 0
+		for _, p := range summary {
+			runs += p.Runs
+		}
+		if runs >= jobs {
+			break
+		}
+	}
+	if runs != jobs {
+		t.Fatalf("Expected %d results, got %d", jobs, runs)
+	}
+
+	// and every run is cleaned up after
+	waitNotRunning(t, mgr, 30*time.Second)
+}
+
+func TestRejectedResults(t *testing.T) {
+	dir := t.TempDir()
+	spoolPath := filepath.Join(dir, "rejected.db.results-spool")
+
+	store, err := NewDuckDBStore(filepath.Join(dir, "rejected.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+	mgr.SetResultSpool(spoolPath)
+
+	started, ran := make(chan struct{}, 1), make(chan struct{}, 1)
+	for _, jc := range []JobConfig{
+		{Id: "deleted", Name: "Deleted", RunFunction: func(ctx context.Context) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		{Id: "kept", Name: "Kept", RunFunction: func(ctx context.Context) error {
+			ran <- struct{}{}
+			return nil
+		}},
+	} {
+		if err := setupJob(mgr, jc); err != nil {
+			t.Fatalf("Failed to setup job: %v", err)
+		}
+	}
+
+	// Deleting a running job drops the result of its run, which could no longer be recorded
+	if err := mgr.TriggerJobNow("deleted"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	<-started
+	if err := mgr.DeleteJob("deleted"); err != nil {
+		t.Fatalf("Failed to delete job: %v", err)
+	}
+	runOnce(t, mgr, "kept", ran)
+	waitForResults(t, store, "kept", 1)
+	if mgr.spool.pending() {
+		t.Error("Expected nothing spooled")
+	}
+
+	// A result the store rejects doesn't fail the others of its batch, and is set aside
+	now := time.Now().UTC()
+	mgr.recordResults([]JobResult{
+		{JobID: "ghost", StartTime: now, EndTime: now, Status: StatusComplete},
+		{JobID: "kept", StartTime: now, EndTime: now, Status: StatusComplete},
+	})
+	if results, err := store.GetJobResults("kept", 10); err != nil || len(results) != 2 {
+		t.Errorf("Expected the valid result of the batch to be recorded, got %d (%v)", len(results), err)
+	}
+	if mgr.spool.pending() {
+		t.Error("Expected the rejected result not to be spooled")
+	}
+	if _, err := os.Stat(spoolPath + ".rejected"); err != nil {
+		t.Errorf("Expected the rejected result to be set aside: %v", err)
+	}
+}
+
+func TestSpoolReplaySetsAsideRejectedResults(t *testing.T) {
+	dir := t.TempDir()
+	spoolPath := filepath.Join(dir, "replay.db.results-spool")
+
+	store, err := NewDuckDBStore(filepath.Join(dir, "replay.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+	mgr.SetResultSpool(spoolPath)
+
+	ran := make(chan struct{}, 4)
+	jc := JobConfig{Id: "kept", Name: "Kept", RunFunction: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	// A spooled result of a deleted job, ahead of a valid one
+	now := time.Now().UTC()
+	mgr.spool.add([]JobResult{
+		{JobID: "deleted", StartTime: now, EndTime: now, Status: StatusComplete},
+		{JobID: jc.Id, StartTime: now, EndTime: now, Status: StatusComplete},
+	})
+
+	// The next recorded result replays the spool: the valid result is recorded, the other set aside
+	runOnce(t, mgr, jc.Id, ran)
+	waitForResults(t, store, jc.Id, 2)
+	if mgr.spool.pending() {
+		t.Error("Expected the spool to be cleared")
+	}
+	if _, err := os.Stat(spoolPath + ".rejected"); err != nil {
+		t.Errorf("Expected the rejected result to be set aside: %v", err)
+	}
+
+	// and later results are still recorded
+	runOnce(t, mgr, jc.Id, ran)
+	waitForResults(t, store, jc.Id, 3)
+	if _, err := os.Stat(spoolPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected nothing spooled, got %v", err)
+	}
+}
+
+func TestResultsAfterShutdownAreSpooled(t *testing.T) {
+	dir := t.TempDir()
+	dbPath, spoolPath := filepath.Join(dir, "late.db"), filepath.Join(dir, "late.db.results-spool")
+
+	store1, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr1 := NewJobManager(store1)
+	mgr1.SetResultSpool(spoolPath)
+	jc := JobConfig{Id: "late", Name: "Late", RunFunction: func(ctx context.Context) error { return nil }}
+	if err := setupJob(mgr1, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr1.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+
+	// A result coming in after the shutdown, such as a remote run's report, is spooled rather than dropped
+	now := time.Now().UTC()
+	mgr1.queueResult(JobResult{JobID: jc.Id, StartTime: now, EndTime: now, Status: StatusComplete})
+	if _, err := os.Stat(spoolPath); err != nil {
+		t.Fatalf("Expected the result to be spooled: %v", err)
+	}
+
+	store2, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	mgr2 := NewJobManager(store2)
+	defer mgr2.Shutdown(5 * time.Second)
+	mgr2.SetResultSpool(spoolPath)
+	if results, err := store2.GetJobResults(jc.Id, 10); err != nil || len(results) != 1 {
+		t.Errorf("Expected the spooled result to be recorded on the next start, got %d (%v)", len(results), err)
+	}
+}
@@ -0,0 +1,120 @@
+package jobpro
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchResults returns n results spread over the jobs
+func benchResults(n int, jobIDs []string) []JobResult {
+	results := make([]JobResult, n)
+	now := time.Now().UTC()
+	for i := range results {
+		results[i] = JobResult{JobID: jobIDs[i%len(jobIDs)], StartTime: now, EndTime: now,
+			Duration: time.Millisecond, Status: StatusComplete, Output: map[string]any{"records": i}}
+	}
+	return results
+}
+
+// benchStore returns an in-memory store with the given number of one-time jobs
+func benchStore(b *testing.B, jobs int) (*DuckDBStore, []string) {
+	b.Helper()
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		b.Fatalf("Failed to create store: %v", err)
+	}
+	ids := make([]string, jobs)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("bench-%d", i)
+		now := time.Now().UTC()
+		if err := store.SaveJob(JobDef{JobID: ids[i], JobName: ids[i], SchedType: OneTime,
+			Status: StatusCreated, CreatedAt: now, UpdatedAt: now, NextRunTime: now}); err != nil {
+			b.Fatalf("Failed to save job: %v", err)
+		}
+	}
+	return store, ids
+}
+
+// BenchmarkRecordJobResults compares inserting results one at a time with inserting them in batches
+func BenchmarkRecordJobResults(b *testing.B) {
+	b.Run("one at a time", func(b *testing.B) {
+		store, ids := benchStore(b, 10)
+		defer store.Close()
+		results := benchResults(b.N, ids)
+
+		b.ResetTimer()
+		for _, result := range results {
+			if err := store.RecordJobResult(result); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "results/s")
+	})
+
+	b.Run("batches of 500", func(b *testing.B) {
+		store, ids := benchStore(b, 10)
+		defer store.Close()
+		results := benchResults(b.N, ids)
+
+		b.ResetTimer()
+		for start := 0; start < len(results); start += 500 {
+			if err := store.RecordJobResults(results[start:min(start+500, len(results))]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "results/s")
+	})
+}
+
+// BenchmarkResultPipeline measures how many results per second the manager records,
+// updating their jobs, when they are processed one by one and in batches
+func BenchmarkResultPipeline(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		size  int
+		flush time.Duration
+	}{
+		{"unbatched", 1, 0},
+		{"batched", defaultResultBatchSize, defaultResultFlushInterval},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			store, ids := benchStore(b, 50)
+			mgr := NewJobManager(store)
+			defer mgr.Shutdown(time.Minute)
+			mgr.SetResultBatching(bc.size, bc.flush)
+			results := benchResults(b.N, ids)
+
+			b.ResetTimer()
+			for _, result := range results {
+				mgr.wg.Add(1) // processResults marks each result done
+				mgr.sendResult(result)
+			}
+			mgr.wg.Wait()
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "results/s")
+		})
+	}
+}
+
+// BenchmarkGetJob compares reading a job definition from the cache with querying it
+func BenchmarkGetJob(b *testing.B) {
+	store, ids := benchStore(b, 1)
+	defer store.Close()
+
+	b.Run("cached", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := store.GetJob(ids[0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			store.cache.drop(ids[0])
+			if _, err := store.GetJob(ids[0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
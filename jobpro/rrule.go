@@ -0,0 +1,527 @@
+package jobpro
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rruleFreq is the FREQ of an RRULE
+type rruleFreq int
+
+const (
+	freqSecondly rruleFreq = iota
+	freqMinutely
+	freqHourly
+	freqDaily
+	freqWeekly
+	freqMonthly
+	freqYearly
+)
+
+var rruleFreqs = map[string]rruleFreq{
+	"SECONDLY": freqSecondly, "MINUTELY": freqMinutely, "HOURLY": freqHourly, "DAILY": freqDaily,
+	"WEEKLY": freqWeekly, "MONTHLY": freqMonthly, "YEARLY": freqYearly,
+}
+
+var rruleDays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// byDay is a BYDAY entry, e.g. "MO", or "-1FR" (the last Friday)
+type byDay struct {
+	weekday time.Weekday
+	nth     int // 0 for every such weekday
+}
+
+// rrule is a subset of RFC 5545 recurrence rules:
+// FREQ (SECONDLY to YEARLY), INTERVAL, BYMONTH, BYMONTHDAY, BYDAY (with ordinals in
+// MONTHLY and YEARLY rules), BYHOUR, BYMINUTE, BYSECOND, UNTIL and COUNT (which needs a DTSTART)
+type rrule struct {
+	freq       rruleFreq
+	interval   int
+	byMonth    []int
+	byMonthDay []int
+	byDay      []byDay
+	byHour     []int
+	byMinute   []int
+	bySecond   []int
+	until      time.Time
+	count      int
+	dtStart    time.Time
+	hasStart   bool
+	loc        *time.Location
+}
+
+// rruleAnchor is where intervals are counted from when a rule has no DTSTART - a Monday
+var rruleAnchor = [6]int{2024, 1, 1, 0, 0, 0}
+
+// parseRRule parses an RRULE such as "FREQ=MONTHLY;BYDAY=-1FR", optionally prefixed with "RRULE:"
+// and preceded by a "DTSTART:20260101T090000Z" (or "DTSTART;TZID=Europe/Paris:20260101T090000") line.
+// Times are in loc, unless DTSTART gives a zone.
+func parseRRule(spec string, loc *time.Location) (*rrule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	r := &rrule{interval: 1, loc: loc, freq: -1}
+
+	var rule string
+	for _, line := range strings.FieldsFunc(spec, func(c rune) bool { return c == '\n' || c == ' ' }) {
+		line = strings.TrimSpace(line)
+		upper := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(upper, "DTSTART"):
+			if err := r.parseDTStart(line); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(upper, "RRULE:"):
+			rule = line[len("RRULE:"):]
+		default:
+			rule = line
+		}
+	}
+
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		if err := r.set(strings.ToUpper(key), strings.ToUpper(val)); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.freq < 0 {
+		return nil, fmt.Errorf("RRULE has no FREQ")
+	}
+	if r.count > 0 && !r.hasStart {
+		return nil, fmt.Errorf("RRULE COUNT needs a DTSTART")
+	}
+	for _, d := range r.byDay {
+		if d.nth != 0 && r.freq != freqMonthly && r.freq != freqYearly {
+			return nil, fmt.Errorf("RRULE BYDAY ordinals are only supported in MONTHLY and YEARLY rules")
+		}
+	}
+
+	// COUNT is applied as the time of the last occurrence
+	if r.count > 0 {
+		t := r.dtStart.Add(-time.Second)
+		for i := 0; i < r.count; i++ {
+			if t = r.Next(t); t.IsZero() {
+				break
+			}
+		}
+		if !t.IsZero() && (r.until.IsZero() || t.Before(r.until)) {
+			r.until = t
+		}
+	}
+	return r, nil
+}
+
+func (r *rrule) parseDTStart(line string) error {
+	head, val, ok := strings.Cut(line, ":")
+	if !ok {
+		return fmt.Errorf("invalid DTSTART %q", line)
+	}
+	if _, tzid, ok := strings.Cut(head, "TZID="); ok {
+		loc, err := loadLocation(tzid)
+		if err != nil {
+			return err
+		}
+		r.loc = loc
+	}
+
+	t, err := parseRRuleTime(val, r.loc)
+	if err != nil {
+		return fmt.Errorf("invalid DTSTART %q", val)
+	}
+	r.dtStart, r.hasStart = t.In(r.loc), true
+	return nil
+}
+
+// parseRRuleTime parses "20060102T150405Z", "20060102T150405" (in loc) or "20060102"
+func parseRRuleTime(s string, loc *time.Location) (time.Time, error) {
+	if strings.HasSuffix(s, "Z") {
+		return time.Parse("20060102T150405Z", s)
+	}
+	if t, err := time.ParseInLocation("20060102T150405", s, loc); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("20060102", s, loc)
+}
+
+func (r *rrule) set(key, val string) error {
+	ints := func(min, max int, allowNeg bool) ([]int, error) {
+		var out []int
+		for _, s := range strings.Split(val, ",") {
+			n, err := strconv.Atoi(s)
+			if err != nil || n > max || (n < min && !(allowNeg && n < 0 && -n <= max)) || n == 0 && min > 0 {
+				return nil, fmt.Errorf("invalid RRULE %s value %q", key, s)
+			}
+			out = append(out, n)
+		}
+		return out, nil
+	}
+
+	var err error
+	switch key {
+	case "FREQ":
+		f, ok := rruleFreqs[val]
+		if !ok {
+			return fmt.Errorf("unsupported RRULE FREQ %q", val)
+		}
+		r.freq = f
+	case "INTERVAL":
+		if r.interval, err = strconv.Atoi(val); err != nil || r.interval < 1 {
+			return fmt.Errorf("invalid RRULE INTERVAL %q", val)
+		}
+	case "COUNT":
+		if r.count, err = strconv.Atoi(val); err != nil || r.count < 1 {
+			return fmt.Errorf("invalid RRULE COUNT %q", val)
+		}
+	case "UNTIL":
+		if r.until, err = parseRRuleTime(val, r.loc); err != nil {
+			return fmt.Errorf("invalid RRULE UNTIL %q", val)
+		}
+	case "BYMONTH":
+		r.byMonth, err = ints(1, 12, false)
+	case "BYMONTHDAY":
+		r.byMonthDay, err = ints(1, 31, true)
+	case "BYHOUR":
+		r.byHour, err = ints(0, 23, false)
+	case "BYMINUTE":
+		r.byMinute, err = ints(0, 59, false)
+	case "BYSECOND":
+		r.bySecond, err = ints(0, 59, false)
+	case "BYDAY":
+		for _, s := range strings.Split(val, ",") {
+			if len(s) < 2 {
+				return fmt.Errorf("invalid RRULE BYDAY %q", s)
+			}
+			wd, ok := rruleDays[s[len(s)-2:]]
+			if !ok {
+				return fmt.Errorf("invalid RRULE BYDAY %q", s)
+			}
+			d := byDay{weekday: wd}
+			if ord := s[:len(s)-2]; ord != "" {
+				if d.nth, err = strconv.Atoi(strings.TrimPrefix(ord, "+")); err != nil || d.nth == 0 || d.nth < -53 || d.nth > 53 {
+					return fmt.Errorf("invalid RRULE BYDAY %q", s)
+				}
+			}
+			r.byDay = append(r.byDay, d)
+		}
+	case "WKST":
+		if val != "MO" {
+			return fmt.Errorf("only WKST=MO is supported")
+		}
+	default:
+		return fmt.Errorf("unsupported RRULE part %s", key)
+	}
+	return err
+}
+
+// anchor returns where intervals and default BY values are taken from
+func (r *rrule) anchor() time.Time {
+	if r.hasStart {
+		return r.dtStart
+	}
+	a := rruleAnchor
+	return time.Date(a[0], time.Month(a[1]), a[2], a[3], a[4], a[5], 0, r.loc)
+}
+
+// Next returns the first occurrence after t, or the zero time if there is none
+func (r *rrule) Next(t time.Time) time.Time {
+	if r.hasStart && t.Before(r.dtStart) {
+		t = r.dtStart.Add(-time.Second)
+	}
+	t = t.In(r.loc)
+
+	hours, minutes, seconds := r.timesOfDay()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, r.loc)
+
+	// Look up to five years ahead, like the cron scheduler
+	for i := 0; i < 5*366; i++ {
+		if !r.until.IsZero() && day.After(r.until) {
+			return time.Time{}
+		}
+
+		if r.matchesDay(day) {
+			for _, h := range hours {
+				for _, m := range minutes {
+					for _, s := range seconds {
+						occ := time.Date(day.Year(), day.Month(), day.Day(), h, m, s, 0, r.loc)
+						if !occ.After(t) || !r.inInterval(occ) {
+							continue
+						}
+						if !r.until.IsZero() && occ.After(r.until) {
+							return time.Time{}
+						}
+						return occ
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// timesOfDay returns the hours, minutes and seconds occurrences can fall on
+func (r *rrule) timesOfDay() (hours, minutes, seconds []int) {
+	a := r.anchor()
+	all := func(n int) []int {
+		out := make([]int, n)
+		for i := range out {
+			out[i] = i
+		}
+		return out
+	}
+	pick := func(by []int, freqAll rruleFreq, n, def int) []int {
+		if len(by) > 0 {
+			return slices.Sorted(slices.Values(by))
+		}
+		if r.freq <= freqAll {
+			return all(n)
+		}
+		return []int{def}
+	}
+	return pick(r.byHour, freqHourly, 24, a.Hour()),
+		pick(r.byMinute, freqMinutely, 60, a.Minute()),
+		pick(r.bySecond, freqSecondly, 60, a.Second())
+}
+
+// matchesDay reports whether occurrences can fall on the day
+func (r *rrule) matchesDay(day time.Time) bool {
+	a := r.anchor()
+
+	if len(r.byMonth) > 0 {
+		if !slices.Contains(r.byMonth, int(day.Month())) {
+			return false
+		}
+	} else if r.freq == freqYearly && len(r.byMonthDay) == 0 && len(r.byDay) == 0 && day.Month() != a.Month() {
+		return false
+	}
+
+	lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if len(r.byMonthDay) > 0 {
+		if !slices.ContainsFunc(r.byMonthDay, func(d int) bool {
+			return d == day.Day() || (d < 0 && lastDay+d+1 == day.Day())
+		}) {
+			return false
+		}
+	} else if (r.freq == freqMonthly || r.freq == freqYearly) && len(r.byDay) == 0 && day.Day() != a.Day() {
+		return false
+	}
+
+	if len(r.byDay) > 0 {
+		if !slices.ContainsFunc(r.byDay, func(d byDay) bool { return r.matchesByDay(d, day, lastDay) }) {
+			return false
+		}
+	} else if r.freq == freqWeekly && day.Weekday() != a.Weekday() {
+		return false
+	}
+
+	return true
+}
+
+// matchesByDay reports whether the day matches a BYDAY entry. Ordinals count weekdays
+// within the month, or within the year for YEARLY rules without BYMONTH.
+func (r *rrule) matchesByDay(d byDay, day time.Time, lastDay int) bool {
+	if day.Weekday() != d.weekday {
+		return false
+	}
+	if d.nth == 0 {
+		return true
+	}
+
+	pos, last := day.Day(), lastDay
+	if r.freq == freqYearly && len(r.byMonth) == 0 {
+		pos, last = day.YearDay(), time.Date(day.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	if d.nth > 0 {
+		return (pos-1)/7+1 == d.nth
+	}
+	return (last-pos)/7+1 == -d.nth
+}
+
+// inInterval reports whether the occurrence is in a period counted by INTERVAL from the anchor
+func (r *rrule) inInterval(occ time.Time) bool {
+	if r.interval == 1 {
+		return true
+	}
+	a := r.anchor()
+
+	// Count days by the calendar, so DST changes don't shift the periods
+	days := func() int {
+		d1 := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+		d2 := time.Date(occ.Year(), occ.Month(), occ.Day(), 0, 0, 0, 0, time.UTC)
+		return int(d2.Sub(d1).Hours() / 24)
+	}
+
+	var n int
+	switch r.freq {
+	case freqSecondly:
+		n = int(occ.Sub(a) / time.Second)
+	case freqMinutely:
+		n = int(occ.Sub(a) / time.Minute)
+	case freqHourly:
+		n = int(occ.Sub(a) / time.Hour)
+	case freqDaily:
+		n = days()
+	case freqWeekly:
+		// Weeks start on Monday
+		offset := (int(a.Weekday()) + 6) % 7
+		n = (days() + offset) / 7
+		if days()+offset < 0 {
+			n = (days() + offset - 6) / 7
+		}
+	case freqMonthly:
+		n = (occ.Year()-a.Year())*12 + int(occ.Month()) - int(a.Month())
+	case freqYearly:
+		n = occ.Year() - a.Year()
+	}
+
+	return ((n%r.interval)+r.interval)%r.interval == 0
+}
+
+// describe renders the rule in English, e.g. "Monthly on the last Friday at 09:00"
+func (r *rrule) describe() string {
+	units := map[rruleFreq][2]string{
+		freqSecondly: {"Every second", "seconds"}, freqMinutely: {"Every minute", "minutes"},
+		freqHourly: {"Every hour", "hours"}, freqDaily: {"Daily", "days"},
+		freqWeekly: {"Weekly", "weeks"}, freqMonthly: {"Monthly", "months"}, freqYearly: {"Yearly", "years"},
+	}
+	u := units[r.freq]
+	desc := u[0]
+	if r.interval > 1 {
+		desc = fmt.Sprintf("Every %d %s", r.interval, u[1])
+	}
+
+	if len(r.byDay) > 0 {
+		days := describeByDay(r.byDay)
+		weekly := r.freq == freqWeekly && r.interval == 1
+		switch {
+		case weekly && days == "weekdays":
+			desc = "Every weekday"
+		case weekly && days == "weekends":
+			desc = "Every weekend day"
+		case weekly && !strings.HasPrefix(days, "the "):
+			desc = "Every " + days // "Every Monday and Friday"
+		default:
+			desc += " on " + days
+		}
+	}
+	if len(r.byMonthDay) > 0 {
+		days := make([]string, len(r.byMonthDay))
+		for i, d := range r.byMonthDay {
+			days[i] = describeMonthDay(d)
+		}
+		desc += " on the " + joinAnd(days)
+	}
+	if len(r.byMonth) > 0 {
+		months := make([]string, len(r.byMonth))
+		for i, m := range r.byMonth {
+			months[i] = time.Month(m).String()
+		}
+		desc += " in " + joinAnd(months)
+	}
+
+	// Times of day, when they are fixed
+	if r.freq >= freqDaily || len(r.byHour) > 0 {
+		hours, minutes, seconds := r.timesOfDay()
+		if len(hours) < 24 && len(minutes) == 1 && len(seconds) == 1 {
+			times := make([]string, len(hours))
+			for i, h := range hours {
+				times[i] = fmt.Sprintf("%02d:%02d", h, minutes[0])
+				if seconds[0] != 0 {
+					times[i] += fmt.Sprintf(":%02d", seconds[0])
+				}
+			}
+			desc += " at " + joinAnd(times)
+		}
+	} else if r.freq == freqHourly && len(r.byMinute) == 1 && r.byMinute[0] != 0 {
+		desc += fmt.Sprintf(" at minute %d", r.byMinute[0])
+	}
+
+	if r.count > 0 {
+		desc += fmt.Sprintf(", %d times", r.count)
+	} else if !r.until.IsZero() {
+		desc += ", until " + r.until.In(r.loc).Format("2006-01-02 15:04")
+	}
+	return desc
+}
+
+// describeByDay renders BYDAY entries, e.g. "Monday and Friday", "weekdays" or "the last Friday"
+func describeByDay(days []byDay) string {
+	plain := true
+	names := make([]string, len(days))
+	for i, d := range days {
+		names[i] = d.weekday.String()
+		if d.nth != 0 {
+			plain = false
+			names[i] = "the " + describeOrdinal(d.nth) + " " + names[i]
+		}
+	}
+
+	if plain {
+		set := make([]time.Weekday, len(days))
+		for i, d := range days {
+			set[i] = d.weekday
+		}
+		slices.Sort(set)
+		switch {
+		case slices.Equal(set, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}):
+			return "weekdays"
+		case slices.Equal(set, []time.Weekday{time.Sunday, time.Saturday}):
+			return "weekends"
+		}
+	}
+	return joinAnd(names)
+}
+
+// describeOrdinal renders 1 as "1st", -1 as "last", -2 as "2nd to last"
+func describeOrdinal(n int) string {
+	switch {
+	case n == -1:
+		return "last"
+	case n < 0:
+		return ordinal(-n) + " to last"
+	}
+	return ordinal(n)
+}
+
+func describeMonthDay(d int) string {
+	if d < 0 {
+		return describeOrdinal(d) + " day"
+	}
+	return ordinal(d)
+}
+
+// ordinal renders 1 as "1st", 2 as "2nd", 11 as "11th" etc.
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// joinAnd joins items as "a, b and c"
+func joinAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
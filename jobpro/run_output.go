@@ -0,0 +1,93 @@
+package jobpro
+
+import (
+	"context"
+	"sync"
+)
+
+// Well-known output keys, charted alongside duration in the UI
+const (
+	OutputRecords = "records" // Number of records / rows processed
+	OutputBytes   = "bytes"   // Number of bytes processed
+)
+
+// RunOutput collects the structured output of a single run.
+// A work function gets it from its context with OutputFromContext and
+// the collected values are stored as JSON on the job result.
+type RunOutput struct {
+	mu     sync.Mutex
+	values map[string]any
+}
+
+type runOutputKey struct{}
+
+// withRunOutput returns a context carrying a new RunOutput
+func withRunOutput(ctx context.Context) (context.Context, *RunOutput) {
+	out := &RunOutput{values: make(map[string]any)}
+	return context.WithValue(ctx, runOutputKey{}, out), out
+}
+
+// OutputFromContext returns the RunOutput of the run owning ctx.
+// Outside of a run a detached RunOutput is returned, so callers never need to nil check.
+func OutputFromContext(ctx context.Context) *RunOutput {
+	if out, ok := ctx.Value(runOutputKey{}).(*RunOutput); ok {
+		return out
+	}
+	return &RunOutput{values: make(map[string]any)}
+}
+
+// Set sets an output value. Values must be JSON encodable.
+func (o *RunOutput) Set(key string, val any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values[key] = val
+}
+
+// Add increments a numeric output value, e.g. a running count of records
+func (o *RunOutput) Add(key string, delta float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var cur float64
+	switch v := o.values[key].(type) {
+	case float64:
+		cur = v
+	case int:
+		cur = float64(v)
+	case int64:
+		cur = float64(v)
+	}
+	o.values[key] = cur + delta
+}
+
+// SetRecords sets the number of records processed by the run
+func (o *RunOutput) SetRecords(n int64) {
+	o.Set(OutputRecords, n)
+}
+
+// SetBytes sets the number of bytes processed by the run
+func (o *RunOutput) SetBytes(n int64) {
+	o.Set(OutputBytes, n)
+}
+
+// Merge copies all values from m into the output
+func (o *RunOutput) Merge(m map[string]any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for k, v := range m {
+		o.values[k] = v
+	}
+}
+
+// Values returns a copy of the collected values, or nil if there are none
+func (o *RunOutput) Values() map[string]any {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.values) == 0 {
+		return nil
+	}
+	vals := make(map[string]any, len(o.values))
+	for k, v := range o.values {
+		vals[k] = v
+	}
+	return vals
+}
@@ -0,0 +1,106 @@
+package jobpro
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunOutput_RecordedOnResult(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	job := NewScheduledJob(JobConfig{
+		Id:   "output-job",
+		Name: "Output Job",
+		RunFunction: func(ctx context.Context) error {
+			out := OutputFromContext(ctx)
+			out.SetRecords(120)
+			out.Add("batches", 1)
+			out.Add("batches", 2)
+			out.Set("source", "orders")
+			return nil
+		},
+	})
+
+	stats, err := job.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stats.Output[OutputRecords] != int64(120) || stats.Output["batches"] != float64(3) {
+		t.Fatalf("Unexpected output: %+v", stats.Output)
+	}
+
+	if err := store.SaveJob(JobDef{JobID: "output-job", JobName: "Output Job", SchedType: Periodic,
+		Status: StatusCreated, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	base := time.Now().UTC().Add(-time.Hour)
+	for i, records := range []int64{10, 20, 30} {
+		start := base.Add(time.Duration(i) * time.Minute)
+		if err := store.RecordJobResult(JobResult{JobID: "output-job", StartTime: start, EndTime: start,
+			Status: StatusComplete, Output: map[string]any{OutputRecords: records, "source": "orders"}}); err != nil {
+			t.Fatalf("Failed to record job result: %v", err)
+		}
+	}
+	// A run without output is stored with a NULL output
+	if err := store.RecordJobResult(JobResult{JobID: "output-job", StartTime: base.Add(time.Hour - time.Second),
+		EndTime: base, Status: StatusFailed, ErrorMsg: "boom"}); err != nil {
+		t.Fatalf("Failed to record job result: %v", err)
+	}
+
+	results, err := store.GetJobResults("output-job", 10)
+	if err != nil {
+		t.Fatalf("Failed to get job results: %v", err)
+	}
+	if len(results) != 4 || results[0].Output != nil {
+		t.Fatalf("Expected 4 results, the latest without output, got %+v", results)
+	}
+	if results[1].Output[OutputRecords] != float64(30) || results[1].Output["source"] != "orders" {
+		t.Errorf("Output did not round trip: %+v", results[1].Output)
+	}
+
+	points, err := store.GetOutputSeries("output-job", OutputRecords, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get output series: %v", err)
+	}
+	if len(points) != 3 || points[0].Value != 10 || points[2].Value != 30 {
+		t.Errorf("Unexpected output series: %+v", points)
+	}
+
+	// Non-numeric values are left out of the series
+	if points, err = store.GetOutputSeries("output-job", "source", 24*time.Hour); err != nil || len(points) != 0 {
+		t.Errorf("Expected no points for a string key, got %+v (err %v)", points, err)
+	}
+
+	keys, err := store.GetOutputKeys("output-job", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get output keys: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != OutputRecords || keys[1] != "source" {
+		t.Errorf("Unexpected output keys: %v", keys)
+	}
+}
+
+func TestParseTriggerOutput(t *testing.T) {
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"output": {"records": 5, "bytes": 100}, "status": "ok"}`, 2},
+		{`{"records": 5}`, 1},
+		{`[1, 2, 3]`, 0},
+		{`OK`, 0},
+		{``, 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseTriggerOutput([]byte(tt.body)); len(got) != tt.want {
+			t.Errorf("parseTriggerOutput(%q) = %v, want %d keys", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package jobpro
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// QueuedRunStatus is the state of a run in the queue of remote runs
+type QueuedRunStatus string
+
+const (
+	RunQueued  QueuedRunStatus = "queued"  // waiting for a worker
+	RunClaimed QueuedRunStatus = "claimed" // leased to a worker, which runs it
+	// RunAbandoned is a claimed run the scheduler stopped waiting for, as it was cancelled.
+	// The worker is told to stop it, and the run leaves the queue once the worker reports.
+	RunAbandoned QueuedRunStatus = "abandoned"
+)
+
+// QueuedRun is a run of a remote job, queued for a worker to claim
+type QueuedRun struct {
+	RunID         string
+	JobID         string
+	Namespace     string
+	Config        JobConfig // the config the worker builds the job from
+	Requires      string    // selector of the worker labels the job requires
+	ScheduledTime time.Time // when the run was due, zero for runs on demand
+	EnqueuedAt    time.Time
+	Attempts      int // how many times the run was claimed
+	Status        QueuedRunStatus
+	Worker        string    // the worker holding the lease on the run
+	LeaseExpires  time.Time // when the run goes back to the queue unless the worker sends a heartbeat
+
+	// Params are the parameters of the run, such as the payload of a webhook, and Trigger what started it
+	Params  map[string]any
+	Trigger TriggerSource
+}
+
+// EnqueueRun adds a run to the queue of remote runs
+// The queue is shared by all namespaces
+func (s *DuckDBStore) EnqueueRun(run QueuedRun) error {
+	cfg, err := json.Marshal(run.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode job config: %w", err)
+	}
+
+	var params any
+	if len(run.Params) > 0 {
+		byts, err := json.Marshal(run.Params)
+		if err != nil {
+			return fmt.Errorf("failed to encode run params: %w", err)
+		}
+		params = string(byts)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO run_queue (run_id, job_id, namespace, config, requires, scheduled_time, enqueued_at,
+			attempts, status, worker, lease_expires, params, trigger_source)
+		VALUES (?, ?, ?, CAST(?::VARCHAR AS JSON), ?, ?, ?, ?, ?, ?, ?, CAST(?::VARCHAR AS JSON), ?)
+	`, run.RunID, run.JobID, run.Namespace, string(cfg), run.Requires, nullTime(run.ScheduledTime), run.EnqueuedAt.UTC(),
+		run.Attempts, run.Status, run.Worker, nullTime(run.LeaseExpires), params, run.Trigger)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue run: %w", err)
+	}
+	return nil
+}
+
+// SaveQueuedRun updates the status, lease and attempts of a queued run
+func (s *DuckDBStore) SaveQueuedRun(run QueuedRun) error {
+	res, err := s.db.Exec(`
+		UPDATE run_queue SET attempts = ?, status = ?, worker = ?, lease_expires = ?
+		WHERE run_id = ?
+	`, run.Attempts, run.Status, run.Worker, nullTime(run.LeaseExpires), run.RunID)
+	if err != nil {
+		return fmt.Errorf("failed to update queued run: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("queued run %s not found", run.RunID)
+	}
+	return nil
+}
+
+// DeleteQueuedRun removes a run from the queue
+func (s *DuckDBStore) DeleteQueuedRun(runID string) error {
+	if _, err := s.db.Exec(`DELETE FROM run_queue WHERE run_id = ?`, runID); err != nil {
+		return fmt.Errorf("failed to delete queued run: %w", err)
+	}
+	return nil
+}
+
+// ListQueuedRuns returns the runs in the queue, oldest first
+func (s *DuckDBStore) ListQueuedRuns() ([]QueuedRun, error) {
+	rows, err := s.db.Query(`
+		SELECT run_id, job_id, namespace, config::VARCHAR, requires, scheduled_time, enqueued_at,
+			attempts, status, worker, lease_expires, params::VARCHAR, trigger_source
+		FROM run_queue
+		ORDER BY enqueued_at, run_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []QueuedRun{}
+	for rows.Next() {
+		var run QueuedRun
+		var cfg string
+		var requires, worker, params, trigger sql.NullString
+		var scheduled, lease sql.NullTime
+		if err := rows.Scan(&run.RunID, &run.JobID, &run.Namespace, &cfg, &requires, &scheduled, &run.EnqueuedAt,
+			&run.Attempts, &run.Status, &worker, &lease, &params, &trigger); err != nil {
+			return nil, fmt.Errorf("failed to scan queued run: %w", err)
+		}
+		if err := json.Unmarshal([]byte(cfg), &run.Config); err != nil {
+			return nil, fmt.Errorf("failed to decode job config of run %s: %w", run.RunID, err)
+		}
+		if params.Valid {
+			if err := json.Unmarshal([]byte(params.String), &run.Params); err != nil {
+				return nil, fmt.Errorf("failed to decode params of run %s: %w", run.RunID, err)
+			}
+		}
+		run.Requires, run.Worker, run.Trigger = requires.String, worker.String, TriggerSource(trigger.String)
+		run.ScheduledTime, run.LeaseExpires = scheduled.Time, lease.Time
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// nullTime returns nil for the zero time, so it is stored as NULL
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,349 @@
+package jobpro
+
+import (
+	"fmt"
+	"job_processor/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule is a parsed recurring schedule. Periodic job schedules can be written as
+//   - cron expressions: "0 30 9 * * 1-5"
+//   - natural language: "every weekday at 09:30", "every 2 hours", "every month on the last friday"
+//   - fixed delays: "@every 90s"
+//   - RFC 5545 recurrence rules: "FREQ=MONTHLY;BYDAY=-1FR"
+//
+// Any of them can take a "CRON_TZ=Zone " prefix.
+type Schedule interface {
+	cron.Schedule
+	// Describe renders the schedule in English
+	Describe() string
+}
+
+// ParseSchedule parses a recurring schedule, in the timezone unless the schedule has a CRON_TZ prefix
+func ParseSchedule(schedule, timezone string) (Schedule, error) {
+	return cronParser.parse(cronSpec(schedule, timezone))
+}
+
+// DescribeSchedule renders a recurring schedule in English, e.g. "Every weekday at 09:30 (Europe/Paris)"
+func DescribeSchedule(schedule, timezone string) string {
+	sched, err := ParseSchedule(schedule, timezone)
+	if err != nil {
+		return fmt.Sprintf("Invalid schedule: %v", err)
+	}
+	return sched.Describe()
+}
+
+// parse parses any supported schedule
+func (p zonedParser) parse(spec string) (Schedule, error) {
+	tz, body := util.SplitCronTZ(spec)
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	withZone := func(desc string) string {
+		if tz != "" {
+			desc += " (" + tz + ")"
+		}
+		return desc
+	}
+
+	lower := strings.ToLower(body)
+	switch {
+	case strings.HasPrefix(lower, "@every "):
+		d, err := time.ParseDuration(strings.TrimSpace(body[len("@every "):]))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("invalid @every duration %q", body)
+		}
+		return everySchedule{cron.Every(d), describeEvery(d)}, nil
+
+	case strings.Contains(strings.ToUpper(body), "FREQ="):
+		r, err := parseRRule(body, loc)
+		if err != nil {
+			return nil, err
+		}
+		return rruleSchedule{r, withZone(r.describe())}, nil
+
+	case isNaturalSchedule(lower):
+		rule, err := naturalToRRule(lower)
+		if err != nil {
+			return nil, err
+		}
+		r, err := parseRRule(rule, loc)
+		if err != nil {
+			return nil, err
+		}
+		return rruleSchedule{r, withZone(r.describe())}, nil
+	}
+
+	sched, err := p.Parser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return wallClockSchedule{sched.(*cron.SpecSchedule), util.ParseCronToEnglish(spec)}, nil
+}
+
+// everySchedule runs at a fixed delay after the previous run
+type everySchedule struct {
+	cron.ConstantDelaySchedule
+	desc string
+}
+
+// Describe renders the schedule in English
+func (s everySchedule) Describe() string { return s.desc }
+
+// rruleSchedule runs on the occurrences of a recurrence rule
+type rruleSchedule struct {
+	*rrule
+	desc string
+}
+
+// Describe renders the schedule in English
+func (s rruleSchedule) Describe() string { return s.desc }
+
+// describeEvery renders a fixed delay, e.g. "Every 90 seconds" or "Every 2 hours"
+func describeEvery(d time.Duration) string {
+	n, unit := int64(d/time.Second), "second"
+	switch {
+	case d%time.Hour == 0:
+		n, unit = int64(d/time.Hour), "hour"
+	case d%time.Minute == 0:
+		n, unit = int64(d/time.Minute), "minute"
+	}
+	if n == 1 {
+		return "Every " + unit
+	}
+	return fmt.Sprintf("Every %d %ss", n, unit)
+}
+
+// naturalAliases are single word natural language schedules
+var naturalAliases = map[string]string{
+	"secondly": "every second", "minutely": "every minute", "hourly": "every hour", "daily": "every day",
+	"weekly": "every week", "monthly": "every month", "yearly": "every year", "annually": "every year",
+}
+
+// isNaturalSchedule reports whether a (lower case) schedule is written in natural language
+func isNaturalSchedule(s string) bool {
+	first, _, _ := strings.Cut(s, " ")
+	_, alias := naturalAliases[first]
+	return first == "every" || alias
+}
+
+var naturalFreqs = map[string]string{
+	"second": "SECONDLY", "minute": "MINUTELY", "hour": "HOURLY", "day": "DAILY",
+	"week": "WEEKLY", "month": "MONTHLY", "year": "YEARLY",
+}
+
+var naturalWeekdays = map[string]string{
+	"monday": "MO", "mon": "MO", "tuesday": "TU", "tue": "TU", "tues": "TU", "wednesday": "WE", "wed": "WE",
+	"thursday": "TH", "thu": "TH", "thurs": "TH", "friday": "FR", "fri": "FR", "saturday": "SA", "sat": "SA",
+	"sunday": "SU", "sun": "SU",
+	"weekday": "MO,TU,WE,TH,FR", "weekend": "SA,SU",
+}
+
+// naturalToRRule translates a natural language schedule to an RRULE
+//
+//	every [N] second(s)|minute(s)|hour(s)|day(s)|week(s)|month(s)|year(s)
+//	every weekday|weekend|<weekday>[, <weekday> and <weekday>]
+//	every month on the 1st|15th|last day|last friday|2nd tuesday
+//	... at 09:30|9am|9:30pm|noon|midnight[ and 17:30]   (daily or less frequent schedules)
+func naturalToRRule(s string) (string, error) {
+	s = strings.Join(strings.Fields(s), " ")
+	first, rest, _ := strings.Cut(s, " ")
+	if alias, ok := naturalAliases[first]; ok {
+		s = strings.TrimSpace(alias + " " + rest)
+	}
+
+	body, at, hasAt := strings.Cut(strings.TrimPrefix(s, "every "), " at ")
+	body, on, hasOn := strings.Cut(body, " on ")
+
+	parts := []string{}
+	words := strings.Fields(strings.NewReplacer(",", " , ").Replace(body))
+	if len(words) == 0 {
+		return "", fmt.Errorf("invalid schedule %q", s)
+	}
+
+	// An optional interval
+	if n, err := strconv.Atoi(words[0]); err == nil {
+		if n < 1 || len(words) < 2 {
+			return "", fmt.Errorf("invalid schedule %q", s)
+		}
+		parts = append(parts, "INTERVAL="+words[0])
+		words = words[1:]
+	} else if words[0] == "other" {
+		parts = append(parts, "INTERVAL=2")
+		words = words[1:]
+	}
+
+	freq := ""
+	if len(words) == 1 {
+		freq = naturalFreqs[strings.TrimSuffix(words[0], "s")]
+	}
+	if freq == "" {
+		days, err := naturalDays(words)
+		if err != nil {
+			return "", fmt.Errorf("invalid schedule %q: %w", s, err)
+		}
+		freq = "WEEKLY"
+		parts = append(parts, "BYDAY="+days)
+	}
+
+	if hasOn {
+		if freq != "WEEKLY" && freq != "MONTHLY" && freq != "YEARLY" || strings.Contains(strings.Join(parts, ";"), "BYDAY") {
+			return "", fmt.Errorf("invalid schedule %q: \"on\" needs a weekly, monthly or yearly schedule", s)
+		}
+		onPart, err := naturalOn(on)
+		if err != nil {
+			return "", fmt.Errorf("invalid schedule %q: %w", s, err)
+		}
+		parts = append(parts, onPart)
+	}
+
+	switch {
+	case hasAt:
+		if freq == "SECONDLY" || freq == "MINUTELY" || freq == "HOURLY" {
+			return "", fmt.Errorf("invalid schedule %q: \"at\" needs a daily or less frequent schedule", s)
+		}
+		timeParts, err := naturalTimes(at)
+		if err != nil {
+			return "", fmt.Errorf("invalid schedule %q: %w", s, err)
+		}
+		parts = append(parts, timeParts)
+	case freq == "SECONDLY":
+	case freq == "MINUTELY":
+		parts = append(parts, "BYSECOND=0")
+	case freq == "HOURLY":
+		parts = append(parts, "BYMINUTE=0;BYSECOND=0")
+	default:
+		parts = append(parts, "BYHOUR=0;BYMINUTE=0;BYSECOND=0")
+	}
+
+	return "FREQ=" + freq + ";" + strings.Join(parts, ";"), nil
+}
+
+// naturalDays translates a list of weekdays, e.g. "monday, wednesday and friday", to BYDAY values
+func naturalDays(words []string) (string, error) {
+	var days []string
+	for _, w := range words {
+		if w == "," || w == "and" {
+			continue
+		}
+		day, ok := naturalWeekdays[strings.TrimSuffix(w, "s")]
+		if !ok {
+			day, ok = naturalWeekdays[w]
+		}
+		if !ok {
+			return "", fmt.Errorf("unknown day %q", w)
+		}
+		days = append(days, day)
+	}
+	return strings.Join(days, ","), nil
+}
+
+// naturalOn translates "the 1st", "the 1st and 15th", "the last day" or "the last friday"
+func naturalOn(s string) (string, error) {
+	words := strings.Fields(strings.NewReplacer(",", " ", " and ", " ").Replace(strings.TrimPrefix(s, "the ")))
+	if len(words) == 2 && words[1] != "day" {
+		if day, ok := naturalWeekdays[words[1]]; ok && len(day) == 2 {
+			n, err := naturalOrdinal(words[0])
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("BYDAY=%d%s", n, day), nil
+		}
+	}
+
+	var days []string
+	for _, w := range words {
+		if w == "day" || w == "the" {
+			continue
+		}
+		n, err := naturalOrdinal(w)
+		if err != nil {
+			return "", err
+		}
+		days = append(days, strconv.Itoa(n))
+	}
+	if len(days) == 0 {
+		return "", fmt.Errorf("no day in %q", s)
+	}
+	return "BYMONTHDAY=" + strings.Join(days, ","), nil
+}
+
+// naturalOrdinal translates "1st", "first", "15th" or "last" to a number, -1 for last
+func naturalOrdinal(w string) (int, error) {
+	words := map[string]int{"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "last": -1}
+	if n, ok := words[w]; ok {
+		return n, nil
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if num, ok := strings.CutSuffix(w, suffix); ok {
+			if n, err := strconv.Atoi(num); err == nil && n >= 1 && n <= 31 {
+				return n, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown day %q", w)
+}
+
+// naturalTimes translates times of day, e.g. "09:30", "9am and 5pm", to BYHOUR, BYMINUTE and BYSECOND.
+// Times must share their minutes, as the RRULE combines every hour with every minute.
+func naturalTimes(s string) (string, error) {
+	var hours []string
+	minute := -1
+	for _, t := range strings.Fields(strings.NewReplacer(",", " ", " and ", " ").Replace(s)) {
+		h, m, err := naturalTime(t)
+		if err != nil {
+			return "", err
+		}
+		if minute >= 0 && m != minute {
+			return "", fmt.Errorf("times %q must be at the same minute past the hour", s)
+		}
+		minute = m
+		hours = append(hours, strconv.Itoa(h))
+	}
+	if minute < 0 {
+		return "", fmt.Errorf("no time in %q", s)
+	}
+	return fmt.Sprintf("BYHOUR=%s;BYMINUTE=%d;BYSECOND=0", strings.Join(hours, ","), minute), nil
+}
+
+// naturalTime parses "09:30", "9am", "9:30pm", "noon" or "midnight"
+func naturalTime(t string) (hour, minute int, err error) {
+	switch t {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 0, 0, nil
+	}
+
+	pm, am := strings.HasSuffix(t, "pm"), strings.HasSuffix(t, "am")
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "pm"), "am")
+	hs, ms, hasMin := strings.Cut(t, ":")
+
+	hour, err = strconv.Atoi(hs)
+	if err == nil && hasMin {
+		minute, err = strconv.Atoi(ms)
+	}
+	if err != nil || minute < 0 || minute > 59 || hour < 0 || hour > 23 || (am || pm) && (hour < 1 || hour > 12) {
+		return 0, 0, fmt.Errorf("invalid time %q", t)
+	}
+	if !hasMin && !am && !pm {
+		return 0, 0, fmt.Errorf("invalid time %q, use 09:00 or 9am", t)
+	}
+
+	switch {
+	case pm && hour != 12:
+		hour += 12
+	case am && hour == 12:
+		hour = 0
+	}
+	return hour, minute, nil
+}
@@ -0,0 +1,174 @@
+package jobpro
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// Thursday
+	from := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		want     []string // successive runs, in UTC
+		desc     string
+	}{
+		{
+			name:     "Cron",
+			schedule: "0 30 9 * * 1-5",
+			want:     []string{"2026-10-16T09:30:00Z", "2026-10-19T09:30:00Z"},
+		},
+		{
+			name:     "Every weekday",
+			schedule: "every weekday at 09:30",
+			want:     []string{"2026-10-16T09:30:00Z", "2026-10-19T09:30:00Z"},
+			desc:     "Every weekday at 09:30",
+		},
+		{
+			name:     "Every 2 hours",
+			schedule: "Every 2 hours",
+			want:     []string{"2026-10-15T12:00:00Z", "2026-10-15T14:00:00Z"},
+			desc:     "Every 2 hours",
+		},
+		{
+			name:     "Every 90 minutes is anchored, not relative to start",
+			schedule: "every 90 minutes",
+			want:     []string{"2026-10-15T10:30:00Z", "2026-10-15T12:00:00Z"},
+			desc:     "Every 90 minutes",
+		},
+		{
+			name:     "Days of the week",
+			schedule: "every mon, wed and friday at 5:15pm",
+			want:     []string{"2026-10-16T17:15:00Z", "2026-10-19T17:15:00Z", "2026-10-21T17:15:00Z"},
+			desc:     "Every Monday, Wednesday and Friday at 17:15",
+		},
+		{
+			name:     "Twice a day",
+			schedule: "daily at 9am and 9pm",
+			want:     []string{"2026-10-15T21:00:00Z", "2026-10-16T09:00:00Z"},
+			desc:     "Daily at 09:00 and 21:00",
+		},
+		{
+			name:     "Last day of the month",
+			schedule: "every month on the last day at noon",
+			want:     []string{"2026-10-31T12:00:00Z", "2026-11-30T12:00:00Z"},
+			desc:     "Monthly on the last day at 12:00",
+		},
+		{
+			name:     "Second Tuesday",
+			schedule: "every month on the 2nd tuesday at 08:00",
+			want:     []string{"2026-11-10T08:00:00Z", "2026-12-08T08:00:00Z"},
+			desc:     "Monthly on the 2nd Tuesday at 08:00",
+		},
+		{
+			name:     "Fixed delay",
+			schedule: "@every 90s",
+			want:     []string{"2026-10-15T10:01:30Z", "2026-10-15T10:03:00Z"},
+			desc:     "Every 90 seconds",
+		},
+		{
+			name:     "RRULE last Friday",
+			schedule: "FREQ=MONTHLY;BYDAY=-1FR",
+			want:     []string{"2026-10-30T00:00:00Z", "2026-11-27T00:00:00Z"},
+			desc:     "Monthly on the last Friday at 00:00",
+		},
+		{
+			name:     "RRULE fortnightly",
+			schedule: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;BYHOUR=7;BYMINUTE=45",
+			want:     []string{"2026-10-20T07:45:00Z", "2026-10-22T07:45:00Z", "2026-11-03T07:45:00Z"},
+			desc:     "Every 2 weeks on Tuesday and Thursday at 07:45",
+		},
+		{
+			name:     "RRULE with DTSTART and COUNT",
+			schedule: "DTSTART:20261016T060000Z RRULE:FREQ=DAILY;COUNT=2",
+			want:     []string{"2026-10-16T06:00:00Z", "2026-10-17T06:00:00Z", "0001-01-01T00:00:00Z"},
+			desc:     "Daily at 06:00, 2 times",
+		},
+		{
+			name:     "RRULE yearly",
+			schedule: "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15;BYHOUR=9;BYMINUTE=0",
+			want:     []string{"2027-03-15T09:00:00Z", "2028-03-15T09:00:00Z"},
+			desc:     "Yearly on the 15th in March at 09:00",
+		},
+		{
+			name:     "Timezone prefix",
+			schedule: "CRON_TZ=America/New_York every day at 09:00",
+			want:     []string{"2026-10-15T13:00:00Z", "2026-10-16T13:00:00Z"},
+			desc:     "Daily at 09:00 (America/New_York)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := ParseSchedule(tt.schedule, "UTC")
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q): %v", tt.schedule, err)
+			}
+
+			next := from
+			for i, want := range tt.want {
+				next = sched.Next(next)
+				if got := next.UTC().Format(time.RFC3339); got != want {
+					t.Errorf("Run %d: expected %s, got %s", i+1, want, got)
+				}
+			}
+
+			if desc := strings.TrimSuffix(sched.Describe(), " (UTC)"); tt.desc != "" && desc != tt.desc {
+				t.Errorf("Expected description %q, got %q", tt.desc, desc)
+			}
+		})
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	bad := []string{
+		"every",
+		"every 0 hours",
+		"every fortnight",
+		"every hour at 09:30",
+		"every day at 25:00",
+		"every day at 9",
+		"daily at 09:00 and 17:30",
+		"every month on the 40th",
+		"@every 1ms",
+		"FREQ=FORTNIGHTLY",
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"BYDAY=MO",
+		"CRON_TZ=Nowhere/Special every day",
+	}
+	for _, s := range bad {
+		if _, err := ParseSchedule(s, ""); err == nil {
+			t.Errorf("Expected schedule %q to be invalid", s)
+		}
+	}
+}
+
+func TestNaturalScheduleJob(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	jc := JobConfig{Id: "natural", Name: "Natural", IsPeriodic: true, Schedule: "every weekday at 09:30",
+		Timezone: "Europe/Paris", JobFunction: func() error { return nil }}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	jobDef, err := store.GetJob("natural")
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	paris, _ := time.LoadLocation("Europe/Paris")
+	local := jobDef.NextRunTime.In(paris)
+	if local.Hour() != 9 || local.Minute() != 30 || local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		t.Errorf("Expected the next run on a weekday at 09:30 Paris time, got %s", local)
+	}
+}
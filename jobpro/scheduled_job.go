@@ -13,7 +13,9 @@ import (
 // ScheduledJob is a job that logs messages at possibly multiple intervals
 type ScheduledJob struct {
 	BaseJob
-	Call func() error // Function to call at each interval
+	Call    func() error                    // Function to call at each interval
+	CallCtx func(ctx context.Context) error // Context aware alternative to Call, preferred when set
+	cfg     JobConfig                       // the config the job was built from
 }
 
 // NewScheduledJob creates a new logging job
@@ -24,53 +26,88 @@ func NewScheduledJob(jc JobConfig) *ScheduledJob {
 			name:        jc.Name,
 			freqType:    util.If(jc.IsPeriodic, Periodic, OneTime),
 			maxWorkTime: time.Duration(jc.MaxRunTime) * time.Second,
+			tags:        jc.Tags,
+			namespace:   jc.Namespace,
+			timezone:    jc.Timezone,
+			calendars:   jc.Calendars,
+			calPolicy:   jc.CalendarPolicy,
+			jitter:      time.Duration(jc.Jitter) * time.Second,
+			spread:      time.Duration(jc.Spread) * time.Second,
 		},
-		Call: jc.JobFunction,
+		Call:    jc.JobFunction,
+		CallCtx: jc.RunFunction,
+		cfg:     jc,
 	}
 
 	// Set the work function
 	if jc.TriggerEndpoint != "" {
-		job.BaseJob.workFunc = func(_ context.Context) (results string, err error) {
-			err = TriggerRemoteJob(jc)
+		job.BaseJob.workFunc = func(ctx context.Context) (results string, err error) {
+			err = TriggerRemoteJob(ctx, jc)
 			return
 		}
+	} else if jc.Command != "" {
+		job.BaseJob.workFunc = func(ctx context.Context) (string, error) {
+			return runCommand(ctx, jc.Command)
+		}
 	} else {
 		job.BaseJob.workFunc = job.scheduledRun
 	}
+	job.BaseJob.workFunc = withRetries(jc.RetryCount, job.BaseJob.workFunc)
 
 	return job
 }
 
+// withRetries runs the work function up to retries more times while it fails,
+// waiting a second longer before each retry
+func withRetries(retries int, workFunc func(context.Context) (string, error)) func(context.Context) (string, error) {
+	if retries <= 0 {
+		return workFunc
+	}
+	return func(ctx context.Context) (results string, err error) {
+		for attempt := 1; ; attempt++ {
+			if results, err = workFunc(ctx); err == nil || attempt > retries {
+				return results, err
+			}
+			logger.LogErr(err, "Retrying job", "attempt", fmt.Sprint(attempt))
+
+			select {
+			case <-ctx.Done():
+				return results, err
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+}
+
+// Config returns the config the job was built from
+func (j *ScheduledJob) Config() JobConfig {
+	return j.cfg
+}
+
 // scheduledRun is the work function for ScheduledJob overriding the base job's Run
+// The context is cancelled when the run times out or is stopped. Only a RunFunction can stop on it;
+// a JobFunction runs on (and counts as a leaked worker) until it returns.
 func (j *ScheduledJob) scheduledRun(ctx context.Context) (results string, err error) {
 	jobTypeName := util.If(j.freqType == Periodic, "Periodic", "Onetime")
 
-	// Create a timer for the job duration only if maxWorkTime > 0
-	var timer *time.Timer
-	if j.maxWorkTime > 0 {
-		timer = time.NewTimer(j.maxWorkTime)
-		defer timer.Stop()
-	}
-
-	select {
-	case <-ctx.Done():
+	if ctx.Err() != nil {
 		return "Job interrupted before execution", ctx.Err()
+	}
 
-	case <-j.timerOrNil(timer):
-		return "Job timed out before execution", nil
-
-	default:
-		// Execute once
-		fmt.Printf("Running %s job: %s\n", jobTypeName, j.name)
+	// Execute once
+	fmt.Printf("Running %s job: %s\n", jobTypeName, j.name)
+	if j.CallCtx != nil {
+		err = j.CallCtx(ctx) // the job can record output via OutputFromContext(ctx)
+	} else {
 		err = j.Call()
-		if err != nil {
-			ser := serr.Wrap(err)
-			logger.LogErr(ser, "Error executing %s job", j.name)
-			return results, ser
-		}
-
-		return fmt.Sprintf("%s job %s, completed", jobTypeName, j.name), nil
 	}
+	if err != nil {
+		ser := serr.Wrap(err)
+		logger.LogErr(ser, "Error executing %s job", j.name)
+		return results, ser
+	}
+
+	return fmt.Sprintf("%s job %s, completed", jobTypeName, j.name), nil
 }
 
 // RunAt will run a function at a certain time and return a timer
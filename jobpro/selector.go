@@ -0,0 +1,162 @@
+package jobpro
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SelectorOp is the comparison made by a selector requirement
+type SelectorOp string
+
+const (
+	OpEquals    SelectorOp = "="
+	OpNotEquals SelectorOp = "!="
+	OpExists    SelectorOp = "exists"
+	OpNotExists SelectorOp = "!exists"
+)
+
+// Requirement is a single term of a label selector
+type Requirement struct {
+	Key   string
+	Op    SelectorOp
+	Value string
+}
+
+// Selector selects jobs by their tags. All requirements must match.
+// An empty selector matches every job.
+type Selector []Requirement
+
+// ParseSelector parses a comma separated label selector such as "team=etl,env!=dev".
+// Supported terms are "key=value" (or "key==value"), "key!=value", "key" (tag present) and "!key" (tag absent).
+// As with Kubernetes label selectors, "key!=value" also matches jobs without the tag.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var req Requirement
+		switch {
+		case strings.Contains(term, "!="):
+			k, v, _ := strings.Cut(term, "!=")
+			req = Requirement{Key: strings.TrimSpace(k), Op: OpNotEquals, Value: strings.TrimSpace(v)}
+		case strings.Contains(term, "="):
+			k, v, _ := strings.Cut(term, "=")
+			req = Requirement{Key: strings.TrimSpace(k), Op: OpEquals,
+				Value: strings.TrimSpace(strings.TrimPrefix(v, "="))}
+		case strings.HasPrefix(term, "!"):
+			req = Requirement{Key: strings.TrimSpace(term[1:]), Op: OpNotExists}
+		default:
+			req = Requirement{Key: term, Op: OpExists}
+		}
+
+		if !validTagKey(req.Key) {
+			return nil, fmt.Errorf("invalid selector term %q", term)
+		}
+		sel = append(sel, req)
+	}
+
+	return sel, nil
+}
+
+// validTagKey reports whether k can be used as a tag key in a selector
+func validTagKey(k string) bool {
+	return k != "" && !strings.ContainsAny(k, "=!, ")
+}
+
+// Matches reports whether tags satisfy every requirement of the selector
+func (sel Selector) Matches(tags map[string]string) bool {
+	for _, req := range sel {
+		val, ok := tags[req.Key]
+		switch req.Op {
+		case OpEquals:
+			if !ok || val != req.Value {
+				return false
+			}
+		case OpNotEquals:
+			if ok && val == req.Value {
+				return false
+			}
+		case OpExists:
+			if !ok {
+				return false
+			}
+		case OpNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Empty reports whether the selector has no requirements
+func (sel Selector) Empty() bool {
+	return len(sel) == 0
+}
+
+// String returns the selector in the form accepted by ParseSelector
+func (sel Selector) String() string {
+	terms := make([]string, 0, len(sel))
+	for _, req := range sel {
+		switch req.Op {
+		case OpExists:
+			terms = append(terms, req.Key)
+		case OpNotExists:
+			terms = append(terms, "!"+req.Key)
+		default:
+			terms = append(terms, req.Key+string(req.Op)+req.Value)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// sqlWhere returns SQL conditions (joined with AND) and args applying the selector
+// to the JSON tags column given, or an empty string if the selector is empty
+func (sel Selector) sqlWhere(column string) (string, []any) {
+	conds := make([]string, 0, len(sel))
+	args := make([]any, 0, len(sel)*2)
+
+	for _, req := range sel {
+		expr := "json_extract_string(" + column + ", ?)"
+		args = append(args, jsonKeyPath(req.Key))
+
+		switch req.Op {
+		case OpEquals:
+			conds = append(conds, expr+" = ?")
+			args = append(args, req.Value)
+		case OpNotEquals:
+			conds = append(conds, expr+" IS DISTINCT FROM ?")
+			args = append(args, req.Value)
+		case OpExists:
+			conds = append(conds, expr+" IS NOT NULL")
+		case OpNotExists:
+			conds = append(conds, expr+" IS NULL")
+		}
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// SortedTags returns tags as key, value pairs sorted by key
+func SortedTags(tags map[string]string) [][2]string {
+	pairs := make([][2]string, 0, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, [2]string{k, tags[k]})
+	}
+	return pairs
+}
+
+// FormatTags renders tags as "key=value" pairs sorted by key, e.g. "env=prod,team=etl"
+func FormatTags(tags map[string]string) string {
+	terms := make([]string, 0, len(tags))
+	for _, kv := range SortedTags(tags) {
+		terms = append(terms, kv[0]+"="+kv[1])
+	}
+	return strings.Join(terms, ",")
+}
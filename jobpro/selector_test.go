@@ -0,0 +1,148 @@
+package jobpro
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSelector(t *testing.T) {
+	tags := map[string]string{"team": "etl", "env": "prod"}
+
+	tests := []struct {
+		selector string
+		matches  bool
+		wantErr  bool
+	}{
+		{"", true, false},
+		{"team=etl", true, false},
+		{"team==etl", true, false},
+		{"team=etl,env!=dev", true, false},
+		{" team = etl , env != prod ", false, false},
+		{"owner!=bob", true, false}, // missing tag satisfies !=
+		{"team", true, false},
+		{"!team", false, false},
+		{"!owner", true, false},
+		{"team=billing", false, false},
+		{"=etl", false, true},
+		{"!", false, true},
+		{"a b=c", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := ParseSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSelector(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := sel.Matches(tags); got != tt.matches {
+				t.Errorf("ParseSelector(%q).Matches(%v) = %v, want %v", tt.selector, tags, got, tt.matches)
+			}
+
+			// String round trips through ParseSelector
+			again, err := ParseSelector(sel.String())
+			if err != nil || again.String() != sel.String() {
+				t.Errorf("Selector %q did not round trip: %q (err %v)", sel.String(), again.String(), err)
+			}
+		})
+	}
+}
+
+func TestSelectJobsByTags(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	for _, jc := range []JobConfig{
+		{Id: "etl-prod", Name: "ETL Prod", IsPeriodic: true, Schedule: "0 0 * * * *",
+			Tags: map[string]string{"team": "etl", "env": "prod"}},
+		{Id: "etl-dev", Name: "ETL Dev", IsPeriodic: true, Schedule: "0 0 * * * *",
+			Tags: map[string]string{"team": "etl", "env": "dev"}},
+		{Id: "billing", Name: "Billing", IsPeriodic: true, Schedule: "0 0 * * * *",
+			Tags: map[string]string{"team": "billing"}},
+		{Id: "untagged", Name: "Untagged", IsPeriodic: true, Schedule: "0 0 * * * *"},
+	} {
+		jc.JobFunction = func() error { return nil }
+		if err := setupJob(mgr, jc); err != nil {
+			t.Fatalf("Failed to set up job %s: %v", jc.Id, err)
+		}
+		if err := mgr.StartJob(jc.Id); err != nil {
+			t.Fatalf("Failed to start job %s: %v", jc.Id, err)
+		}
+	}
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"team=etl", []string{"etl-dev", "etl-prod"}},
+		{"team=etl,env!=dev", []string{"etl-prod"}},
+		{"env!=dev", []string{"billing", "etl-prod", "untagged"}},
+		{"!team", []string{"untagged"}},
+		{"team", []string{"billing", "etl-dev", "etl-prod"}},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q): %v", tt.selector, err)
+		}
+
+		jobs, err := store.ListJobs("", "", sel)
+		if err != nil {
+			t.Fatalf("ListJobs(%q): %v", tt.selector, err)
+		}
+		got := map[string]bool{}
+		for _, job := range jobs {
+			got[job.JobID] = true
+			if !sel.Matches(job.Tags) {
+				t.Errorf("ListJobs(%q) returned non-matching job %s %v", tt.selector, job.JobID, job.Tags)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ListJobs(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+		for _, id := range tt.want {
+			if !got[id] {
+				t.Errorf("ListJobs(%q) is missing %s", tt.selector, id)
+			}
+		}
+
+		runs, _, err := store.GetJobRunsWithPagination(10, sel)
+		if err != nil {
+			t.Fatalf("GetJobRunsWithPagination(%q): %v", tt.selector, err)
+		}
+		if len(runs) != len(tt.want) {
+			t.Errorf("GetJobRunsWithPagination(%q) returned %d rows, want %d", tt.selector, len(runs), len(tt.want))
+		}
+	}
+
+	// Bulk actions need a selector
+	if _, err := mgr.Bulk(BulkPause, nil); err == nil {
+		t.Errorf("Expected bulk action with an empty selector to fail")
+	}
+
+	sel, _ := ParseSelector("team=etl")
+	results, err := mgr.Bulk(BulkPause, sel)
+	if err != nil {
+		t.Fatalf("Bulk pause failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 bulk results, got %+v", results)
+	}
+	for _, id := range []string{"etl-prod", "etl-dev"} {
+		if status, _ := mgr.GetJobStatus(id); status != StatusPaused {
+			t.Errorf("Expected %s to be paused, got %s", id, status)
+		}
+	}
+	if status, _ := mgr.GetJobStatus("billing"); status == StatusPaused {
+		t.Errorf("Expected billing not to be paused")
+	}
+}
@@ -0,0 +1,207 @@
+package jobpro
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// blockingJob returns the config of a job whose runs last until they are cancelled,
+// and a channel receiving a value as each run starts
+func blockingJob(id string) (JobConfig, <-chan struct{}) {
+	started := make(chan struct{}, 10)
+	return JobConfig{Id: id, Name: id,
+		RunFunction: func(ctx context.Context) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}}, started
+}
+
+func TestOverlapAndCancelStatuses(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	jc, started := blockingJob("blocking")
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the job to start")
+	}
+
+	// A second run while the first is going is skipped
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job again: %v", err)
+	}
+	if result := waitForResults(t, store, jc.Id, 1)[0]; result.Status != StatusSkippedOverlap {
+		t.Errorf("Expected the overlapping run to be %s, got %s", StatusSkippedOverlap, result.Status)
+	}
+
+	// Stopping the running one records it as cancelled, not failed
+	if err := mgr.StopJob(jc.Id); err != nil {
+		t.Fatalf("Failed to stop job: %v", err)
+	}
+	// Results are most recent first - the stopped run started before the skipped one
+	if result := waitForResults(t, store, jc.Id, 2)[1]; result.Status != StatusCancelled {
+		t.Errorf("Expected the stopped run to be %s, got %s (%s)", StatusCancelled, result.Status, result.ErrorMsg)
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	jc, started := blockingJob("nightly")
+	jc.IsPeriodic, jc.Schedule, jc.AutoStart = true, "0 0 0 1 1 *", true
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	<-started
+
+	runs := mgr.RunningRuns()
+	if len(runs) != 1 || runs[0].JobID != jc.Id || runs[0].RunID == "" {
+		t.Fatalf("Expected the run in progress to be listed, got %+v", runs)
+	}
+	if err := mgr.CancelRun("no-such-run"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("Expected %v for an unknown run, got %v", ErrRunNotFound, err)
+	}
+
+	// Cancelling the run records it as cancelled, and leaves the job scheduled
+	if err := mgr.CancelRun(runs[0].RunID); err != nil {
+		t.Fatalf("Failed to cancel run: %v", err)
+	}
+	if result := waitForResult(t, store, jc.Id, 5*time.Second); result.Status != StatusCancelled {
+		t.Errorf("Expected the run to be %s, got %s (%s)", StatusCancelled, result.Status, result.ErrorMsg)
+	}
+	waitNotRunning(t, mgr, 2*time.Second)
+	mgr.mu.RLock()
+	_, inCron := mgr.cronEntries[jc.Id]
+	mgr.mu.RUnlock()
+	if def, err := store.GetJob(jc.Id); err != nil || def.Status == StatusStopped || !inCron {
+		t.Errorf("Expected the job to stay scheduled, got %s (in cron: %v, %v)", def.Status, inCron, err)
+	}
+
+	// The next run goes ahead
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job again: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the next run to start")
+	}
+}
+
+func TestInterruptedByShutdown(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shutdown.db")
+
+	store1, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr1 := NewJobManager(store1)
+
+	jc, started := blockingJob("interrupted")
+	if err := setupJob(mgr1, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr1.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the job to start")
+	}
+
+	if err := mgr1.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+
+	store2, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store2.Close()
+
+	results, err := store2.GetJobResults(jc.Id, 1)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Expected the interrupted run to be recorded, got %v (%v)", results, err)
+	}
+	if results[0].Status != StatusInterruptedByShutdown {
+		t.Errorf("Expected status %s, got %s", StatusInterruptedByShutdown, results[0].Status)
+	}
+}
+
+func TestStatusCounts(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC()
+	if err := store.SaveJob(JobDef{JobID: "counted", JobName: "Counted", SchedType: Periodic,
+		Status: StatusCreated, CreatedAt: now, UpdatedAt: now, NextRunTime: now}); err != nil {
+		t.Fatalf("Failed to save job: %v", err)
+	}
+
+	// 6 runs with an outcome, of which 3 completed - the others don't count against the success rate
+	statuses := []JobStatus{StatusComplete, StatusComplete, StatusComplete, StatusFailed, StatusTimedOut,
+		StatusTimedOut, StatusCancelled, StatusInterruptedByShutdown, StatusSkippedOverlap, StatusSkippedCalendar,
+		StatusSkippedPool}
+	for i, status := range statuses {
+		start := now.Add(-time.Duration(i+1) * time.Minute)
+		if err := store.RecordJobResult(JobResult{JobID: "counted", StartTime: start, EndTime: start,
+			Status: status}); err != nil {
+			t.Fatalf("Failed to record job result: %v", err)
+		}
+	}
+
+	summary, err := store.GetJobSummary("counted", time.Hour, 1)
+	if err != nil {
+		t.Fatalf("Failed to get job summary: %v", err)
+	}
+	want := StatusCounts{Successes: 3, Failures: 1, TimedOut: 2, Cancelled: 1, Interrupted: 1,
+		SkippedOverlap: 1, SkippedCalendar: 1, SkippedPool: 1}
+	if summary.Runs != 11 || summary.StatusCounts != want {
+		t.Errorf("Expected 11 runs counted as %+v, got %d as %+v", want, summary.Runs, summary.StatusCounts)
+	}
+	if summary.SuccessRate != 50 {
+		t.Errorf("Expected a 50%% success rate, got %.1f%%", summary.SuccessRate)
+	}
+
+	points, err := store.GetSuccessRateSeries("counted", BucketDay, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get success rate series: %v", err)
+	}
+	var timedOut int
+	for _, p := range points {
+		timedOut += p.TimedOut
+	}
+	if timedOut != 2 {
+		t.Errorf("Expected 2 timed out runs in the series, got %d", timedOut)
+	}
+}
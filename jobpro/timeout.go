@@ -0,0 +1,57 @@
+package jobpro
+
+import (
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/rohanthewiz/logger"
+)
+
+// ErrTimedOut is the error of runs that exceeded the job's MaxRunTime
+var ErrTimedOut = errors.New("job timed out")
+
+// workerGracePeriod is how long a cancelled worker has to return before it is counted as leaked
+const workerGracePeriod = 5 * time.Second
+
+// leakedWorkers counts, by job Id, workers still running after being cancelled and given the grace period to stop
+var leakedWorkers = struct {
+	sync.Mutex
+	byJob map[string]int
+}{byJob: make(map[string]int)}
+
+// LeakedWorkers returns the number of leaked workers by job Id: workers that timed out or were stopped,
+// but ignored the cancellation of their context and are still running
+func LeakedWorkers() map[string]int {
+	leakedWorkers.Lock()
+	defer leakedWorkers.Unlock()
+	return maps.Clone(leakedWorkers.byJob)
+}
+
+// trackLeakedWorker counts a job's worker as leaked until it finishes
+func trackLeakedWorker(jobID string, done <-chan struct{}) {
+	logger.Warn("Worker did not stop within the grace period of being cancelled", "jobID", jobID)
+
+	leakedWorkers.Lock()
+	leakedWorkers.byJob[jobID]++
+	leakedWorkers.Unlock()
+
+	<-done
+
+	leakedWorkers.Lock()
+	if leakedWorkers.byJob[jobID]--; leakedWorkers.byJob[jobID] <= 0 {
+		delete(leakedWorkers.byJob, jobID)
+	}
+	leakedWorkers.Unlock()
+	logger.Info("Leaked worker finished", "jobID", jobID)
+}
+
+// awaitCancelledWorker gives a cancelled worker the grace period to finish, then tracks it as leaked
+func awaitCancelledWorker(jobID string, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(workerGracePeriod):
+		trackLeakedWorker(jobID, done)
+	}
+}
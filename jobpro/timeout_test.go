@@ -0,0 +1,128 @@
+package jobpro
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForResult polls the store for up to wait until the job has a result
+func waitForResult(t *testing.T, store JobStore, jobID string, wait time.Duration) JobResult {
+	t.Helper()
+	for deadline := time.Now().Add(wait); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if results, err := store.GetJobResults(jobID, 1); err == nil && len(results) > 0 {
+			return results[0]
+		}
+	}
+	t.Fatalf("Expected a result of job %s within %s", jobID, wait)
+	return JobResult{}
+}
+
+func TestTimeoutCancelsWork(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	stopped := make(chan error, 1)
+	jc := JobConfig{Id: "slow", Name: "Slow", MaxRunTime: 1, AutoStart: true,
+		RunFunction: func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				stopped <- context.Cause(ctx)
+				return ctx.Err()
+			case <-time.After(time.Minute):
+				return nil
+			}
+		}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	result := waitForResult(t, store, jc.Id, 5*time.Second)
+	if result.Status != StatusTimedOut {
+		t.Errorf("Expected status %s, got %s (%s)", StatusTimedOut, result.Status, result.ErrorMsg)
+	}
+	select {
+	case cause := <-stopped:
+		if !errors.Is(cause, ErrTimedOut) {
+			t.Errorf("Expected the work context to be cancelled with ErrTimedOut, got %v", cause)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the worker to be cancelled")
+	}
+	if n := LeakedWorkers()[jc.Id]; n != 0 {
+		t.Errorf("Expected no leaked worker, got %d", n)
+	}
+}
+
+func TestTimeoutKillsCommand(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	// The command ignores SIGTERM, so has to be killed
+	jc := JobConfig{Id: "stubborn", Name: "Stubborn", MaxRunTime: 1, AutoStart: true,
+		Command: "trap '' TERM; sleep 30"}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	start := time.Now()
+	result := waitForResult(t, store, jc.Id, 10*time.Second)
+	if result.Status != StatusTimedOut || !strings.Contains(result.ErrorMsg, "killed") {
+		t.Errorf("Expected the command to be killed on timing out, got %s (%s)", result.Status, result.ErrorMsg)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second+commandKillDelay+2*time.Second {
+		t.Errorf("Expected the command to be killed soon after the kill delay, took %s", elapsed)
+	}
+}
+
+func TestLeakedWorker(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits out the worker grace period")
+	}
+
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	// A JobFunction has no context, so runs on after timing out
+	release := make(chan struct{})
+	jc := JobConfig{Id: "deaf", Name: "Deaf", MaxRunTime: 1, AutoStart: true,
+		JobFunction: func() error {
+			<-release
+			return nil
+		}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	result := waitForResult(t, store, jc.Id, time.Second+workerGracePeriod+2*time.Second)
+	if result.Status != StatusTimedOut {
+		t.Errorf("Expected status %s, got %s", StatusTimedOut, result.Status)
+	}
+	if n := LeakedWorkers()[jc.Id]; n != 1 {
+		t.Errorf("Expected 1 leaked worker, got %d", n)
+	}
+
+	close(release)
+	for deadline := time.Now().Add(2 * time.Second); LeakedWorkers()[jc.Id] > 0; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the leaked worker to be untracked once it returned")
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package jobpro
+
+import (
+	"fmt"
+	"job_processor/util"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ZonedJob is implemented by jobs whose schedule is in a specific timezone
+// The timezone is an IANA name such as "America/New_York"
+type ZonedJob interface {
+	Timezone() string
+}
+
+// cronParser parses 6 field cron expressions (seconds first), optionally prefixed with
+// "CRON_TZ=Zone" (or "TZ=Zone"). Without a prefix, schedules are in server local time.
+var cronParser = zonedParser{cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)}
+
+// zonedParser makes the schedules of a cron parser follow the wall clock across DST changes
+type zonedParser struct {
+	cron.Parser
+}
+
+// Parse parses a schedule - a cron expression or any of the other forms of Schedule
+func (p zonedParser) Parse(spec string) (cron.Schedule, error) {
+	return p.parse(spec)
+}
+
+// wallClockSchedule runs a cron schedule by the wall clock of its location:
+//   - a time skipped when clocks go forward (e.g. 02:30 on the spring DST day) runs once,
+//     shifted forward by the gap (03:30)
+//   - a time repeated when clocks go back (e.g. 01:30 on the autumn DST day) runs once,
+//     at its first occurrence
+type wallClockSchedule struct {
+	spec *cron.SpecSchedule
+	desc string
+}
+
+// Describe renders the schedule in English
+func (s wallClockSchedule) Describe() string { return s.desc }
+
+// Next returns the next activation time after t
+func (s wallClockSchedule) Next(t time.Time) time.Time {
+	loc := s.spec.Location
+
+	// Find the next matching wall clock time, evaluated as if in UTC where there is no DST
+	floating := *s.spec
+	floating.Location = time.UTC
+
+	local := t.In(loc)
+	wall := time.Date(local.Year(), local.Month(), local.Day(),
+		local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+
+	for {
+		wall = floating.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+
+		// Map back to the location. time.Date resolves repeated times to their
+		// first occurrence, which may be before t.
+		next := time.Date(wall.Year(), wall.Month(), wall.Day(),
+			wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+
+		// Times in a gap come back with a different wall clock - shift them forward by the gap
+		got := next.In(loc)
+		gotWall := time.Date(got.Year(), got.Month(), got.Day(), got.Hour(), got.Minute(), got.Second(), 0, time.UTC)
+		if shift := wall.Sub(gotWall); shift > 0 {
+			next = next.Add(shift)
+		}
+
+		if next.After(t) {
+			return next
+		}
+	}
+}
+
+// cronSpec returns the schedule to give the cron parser for a job's schedule and timezone
+// A CRON_TZ prefix in the schedule takes precedence over the timezone.
+func cronSpec(schedule, timezone string) string {
+	if tz, _ := util.SplitCronTZ(schedule); tz != "" || timezone == "" {
+		return schedule
+	}
+	return "CRON_TZ=" + timezone + " " + schedule
+}
+
+// scheduleTimezone returns the timezone of a job: that of a CRON_TZ prefix on its schedule, or the one given
+func scheduleTimezone(schedule, timezone string) string {
+	if tz, _ := util.SplitCronTZ(schedule); tz != "" {
+		return tz
+	}
+	return timezone
+}
+
+// loadLocation returns the location of an IANA timezone name, or nil (server local time) if empty
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	return loc, nil
+}
+
+// nextRunTime returns when a job with the schedule and timezone next runs after from
+func nextRunTime(freqType FreqType, schedule, timezone string, from time.Time) (time.Time, error) {
+	loc, err := loadLocation(scheduleTimezone(schedule, timezone))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if freqType == Periodic {
+		sched, err := cronParser.Parse(cronSpec(schedule, timezone))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to parse schedule: %w", err)
+		}
+		return sched.Next(from), nil
+	}
+
+	// One-time jobs without a schedule run when started
+	if schedule == "" {
+		return from, nil
+	}
+	return util.ParseScheduleIn(schedule, loc)
+}
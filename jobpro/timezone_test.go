@@ -0,0 +1,147 @@
+package jobpro
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRunTime_DST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		timezone string
+		from     time.Time
+		want     []string // successive runs, in UTC
+	}{
+		{
+			name:     "Daily in zone",
+			schedule: "0 0 9 * * *",
+			timezone: "America/New_York",
+			from:     time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-07-01T13:00:00Z", "2026-07-02T13:00:00Z"},
+		},
+		{
+			name:     "CRON_TZ prefix wins over timezone",
+			schedule: "CRON_TZ=Europe/London 0 0 9 * * *",
+			timezone: "America/New_York",
+			from:     time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+			want:     []string{"2026-07-01T08:00:00Z"},
+		},
+		{
+			name:     "Same local time either side of spring forward",
+			schedule: "0 0 9 * * *",
+			timezone: "America/New_York",
+			from:     time.Date(2026, 3, 7, 12, 0, 0, 0, ny),
+			want:     []string{"2026-03-08T13:00:00Z", "2026-03-09T13:00:00Z"},
+		},
+		{
+			name:     "Skipped hour runs once, shifted by the gap",
+			schedule: "0 30 2 * * *",
+			timezone: "America/New_York",
+			from:     time.Date(2026, 3, 7, 12, 0, 0, 0, ny),
+			// 02:30 EST doesn't exist on Mar 8 - it runs at 03:30 EDT
+			want: []string{"2026-03-08T07:30:00Z", "2026-03-09T06:30:00Z"},
+		},
+		{
+			name:     "Hourly across spring forward",
+			schedule: "0 0 * * * *",
+			timezone: "America/New_York",
+			from:     time.Date(2026, 3, 8, 0, 30, 0, 0, ny),
+			// 01:00 EST, then 02:00 (skipped) runs at 03:00 EDT, then 04:00 EDT
+			want: []string{"2026-03-08T06:00:00Z", "2026-03-08T07:00:00Z", "2026-03-08T08:00:00Z"},
+		},
+		{
+			name:     "Doubled hour runs once",
+			schedule: "0 30 1 * * *",
+			timezone: "America/New_York",
+			from:     time.Date(2026, 10, 31, 12, 0, 0, 0, ny),
+			// 01:30 happens twice on Nov 1 (EDT then EST) - only the first runs
+			want: []string{"2026-11-01T05:30:00Z", "2026-11-02T06:30:00Z"},
+		},
+		{
+			name:     "Doubled hour from within the repeat",
+			schedule: "0 30 1 * * *",
+			timezone: "America/New_York",
+			from:     time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC), // 01:00 EST, after 01:30 EDT has run
+			want:     []string{"2026-11-02T06:30:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := tt.from
+			for i, want := range tt.want {
+				next, err = nextRunTime(Periodic, tt.schedule, tt.timezone, next)
+				if err != nil {
+					t.Fatalf("nextRunTime(%q, %q) error: %v", tt.schedule, tt.timezone, err)
+				}
+				if got := next.UTC().Format(time.RFC3339); got != want {
+					t.Errorf("run %d: got %s, want %s", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNextRunTime_OneTimeInZone(t *testing.T) {
+	got, err := nextRunTime(OneTime, "2030-01-15 09:00:00", "Asia/Tokyo", time.Now())
+	if err != nil {
+		t.Fatalf("nextRunTime error: %v", err)
+	}
+	if want := "2030-01-15T00:00:00Z"; got.UTC().Format(time.RFC3339) != want {
+		t.Errorf("got %s, want %s", got.UTC().Format(time.RFC3339), want)
+	}
+
+	if _, err := nextRunTime(Periodic, "0 0 9 * * *", "Mars/Olympus_Mons", time.Now()); err == nil {
+		t.Error("Expected an invalid timezone to be rejected")
+	}
+}
+
+func TestSetupJob_Timezone(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	jc := JobConfig{Id: "tz1", Name: "Zoned", IsPeriodic: true, Schedule: "0 0 9 * * 1-5",
+		Timezone: "America/New_York", JobFunction: func() error { return nil }}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	def, err := store.GetJob("tz1")
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if def.Timezone != "America/New_York" {
+		t.Errorf("Expected timezone America/New_York, got %q", def.Timezone)
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+	if local := def.NextRunTime.In(ny); local.Hour() != 9 || local.Minute() != 0 {
+		t.Errorf("Expected next run at 09:00 New York time, got %s", local)
+	}
+
+	// The timezone of a CRON_TZ prefix is recorded
+	jc = JobConfig{Id: "tz2", Name: "Prefixed", IsPeriodic: true, Schedule: "CRON_TZ=Asia/Tokyo 0 0 9 * * *",
+		JobFunction: func() error { return nil }}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if def, err := store.GetJob("tz2"); err != nil || def.Timezone != "Asia/Tokyo" {
+		t.Errorf("Expected timezone Asia/Tokyo, got %q (err %v)", def.Timezone, err)
+	}
+
+	jc = JobConfig{Id: "tz3", Name: "Bad zone", IsPeriodic: true, Schedule: "0 0 9 * * *",
+		Timezone: "Nowhere/Special", JobFunction: func() error { return nil }}
+	if err := setupJob(mgr, jc); err == nil {
+		t.Error("Expected setting up a job with an invalid timezone to fail")
+	}
+}
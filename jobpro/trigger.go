@@ -0,0 +1,74 @@
+package jobpro
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// TriggerSource tells what started a run
+type TriggerSource string
+
+const (
+	// TriggerCron is a run of the job's schedule, one-time schedules and deferred runs included
+	TriggerCron TriggerSource = "cron"
+	// TriggerManual is a run started on demand, by run now or a manual start
+	TriggerManual TriggerSource = "manual"
+	// TriggerWebhook is a run started by a webhook delivery
+	TriggerWebhook TriggerSource = "webhook"
+	// TriggerResume is a run resuming one interrupted by a shutdown or crash
+	TriggerResume TriggerSource = "resume"
+	// TriggerWatch is a run started by a file landing in a watched directory
+	TriggerWatch TriggerSource = "watch"
+)
+
+// runTrigger is what started a run, and what with
+type runTrigger struct {
+	source    TriggerSource
+	scheduled time.Time      // when a scheduled run was due, zero for others
+	params    map[string]any // the parameters of the run, such as the payload of a webhook
+	// done, if set, is called with the result of the run
+	done func(JobResult)
+}
+
+// finished hands the result of the run to the trigger's done function, if any
+func (t runTrigger) finished(result JobResult) {
+	if t.done != nil {
+		t.done(result)
+	}
+}
+
+// scheduledRun is the trigger of a run of the job's schedule, due at the time given
+func scheduledRun(scheduled time.Time) runTrigger {
+	return runTrigger{source: TriggerCron, scheduled: scheduled}
+}
+
+type paramsKey struct{}
+
+// withParams returns a context carrying the parameters of a run
+func withParams(ctx context.Context, params map[string]any) context.Context {
+	if len(params) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// ParamsFromContext returns the parameters of the run owning ctx, such as the payload of the webhook
+// that triggered it, or nil if the run has none. The map must not be changed.
+func ParamsFromContext(ctx context.Context) map[string]any {
+	params, _ := ctx.Value(paramsKey{}).(map[string]any)
+	return params
+}
+
+// encodeParams returns the parameters of the run owning ctx as JSON, or nil if it has none
+func encodeParams(ctx context.Context) []byte {
+	params := ParamsFromContext(ctx)
+	if len(params) == 0 {
+		return nil
+	}
+	byts, err := json.Marshal(params)
+	if err != nil {
+		return nil
+	}
+	return byts
+}
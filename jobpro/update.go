@@ -0,0 +1,266 @@
+package jobpro
+
+import (
+	"cmp"
+	"fmt"
+	"job_processor/util"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rohanthewiz/serr"
+)
+
+// ConfigurableJob is implemented by jobs built from a JobConfig, which UpdateJob rebuilds them from
+type ConfigurableJob interface {
+	Job
+	// Config returns the config the job was built from, including any runtime edits
+	Config() JobConfig
+}
+
+// JobSettings are the settings of a job that can be changed while it is loaded.
+// In an update, nil fields are left unchanged; empty (non-nil) Tags or Calendars clear them.
+// Durations are in seconds, as in JobConfig.
+type JobSettings struct {
+	Name            *string           `json:",omitzero"`
+	Schedule        *string           `json:",omitzero"`
+	Timezone        *string           `json:",omitzero"`
+	MaxRunTime      *int              `json:",omitzero"`
+	TriggerEndpoint *string           `json:",omitzero"`
+	Tags            map[string]string `json:",omitzero"`
+	Calendars       []string          `json:",omitzero"`
+	CalendarPolicy  *CalendarPolicy   `json:",omitzero"`
+	Jitter          *int              `json:",omitzero"`
+	Spread          *int              `json:",omitzero"`
+}
+
+// settingsOf returns all the settings of a job config
+func settingsOf(jc JobConfig) JobSettings {
+	if jc.Tags == nil {
+		jc.Tags = map[string]string{}
+	}
+	if jc.Calendars == nil {
+		jc.Calendars = []string{}
+	}
+	return JobSettings{
+		Name:            &jc.Name,
+		Schedule:        &jc.Schedule,
+		Timezone:        &jc.Timezone,
+		MaxRunTime:      &jc.MaxRunTime,
+		TriggerEndpoint: &jc.TriggerEndpoint,
+		Tags:            jc.Tags,
+		Calendars:       jc.Calendars,
+		CalendarPolicy:  &jc.CalendarPolicy,
+		Jitter:          &jc.Jitter,
+		Spread:          &jc.Spread,
+	}
+}
+
+// applyTo returns the job config with the settings applied
+func (s JobSettings) applyTo(jc JobConfig) JobConfig {
+	set(&jc.Name, s.Name)
+	set(&jc.Schedule, s.Schedule)
+	set(&jc.Timezone, s.Timezone)
+	set(&jc.MaxRunTime, s.MaxRunTime)
+	set(&jc.TriggerEndpoint, s.TriggerEndpoint)
+	set(&jc.CalendarPolicy, s.CalendarPolicy)
+	set(&jc.Jitter, s.Jitter)
+	set(&jc.Spread, s.Spread)
+	if s.Tags != nil {
+		jc.Tags = s.Tags
+	}
+	if s.Calendars != nil {
+		jc.Calendars = s.Calendars
+	}
+	return jc
+}
+
+// merge returns the settings overridden by those set in upd
+func (s JobSettings) merge(upd JobSettings) JobSettings {
+	s.Name = cmp.Or(upd.Name, s.Name)
+	s.Schedule = cmp.Or(upd.Schedule, s.Schedule)
+	s.Timezone = cmp.Or(upd.Timezone, s.Timezone)
+	s.MaxRunTime = cmp.Or(upd.MaxRunTime, s.MaxRunTime)
+	s.TriggerEndpoint = cmp.Or(upd.TriggerEndpoint, s.TriggerEndpoint)
+	s.CalendarPolicy = cmp.Or(upd.CalendarPolicy, s.CalendarPolicy)
+	s.Jitter = cmp.Or(upd.Jitter, s.Jitter)
+	s.Spread = cmp.Or(upd.Spread, s.Spread)
+	if upd.Tags != nil {
+		s.Tags = upd.Tags
+	}
+	if upd.Calendars != nil {
+		s.Calendars = upd.Calendars
+	}
+	return s
+}
+
+// empty reports whether no setting is set
+func (s JobSettings) empty() bool {
+	return s.Name == nil && s.Schedule == nil && s.Timezone == nil && s.MaxRunTime == nil &&
+		s.TriggerEndpoint == nil && s.Tags == nil && s.Calendars == nil && s.CalendarPolicy == nil &&
+		s.Jitter == nil && s.Spread == nil
+}
+
+// set replaces *dst with *src unless src is nil
+func set[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// GetJobSettings returns the current settings of a loaded job
+func (m *DefaultJobManager) GetJobSettings(id string) (JobSettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, exists := m.job(id)
+	if !exists {
+		return JobSettings{}, fmt.Errorf("job %s not found", id)
+	}
+	cj, ok := job.(ConfigurableJob)
+	if !ok {
+		return JobSettings{}, fmt.Errorf("job %s has no editable settings", id)
+	}
+	return settingsOf(cj.Config()), nil
+}
+
+// UpdateJob changes the settings of a loaded job. The job is rebuilt from its config with the
+// settings applied, and a periodic job's cron entry is replaced by one on the new schedule
+// only once the change is saved, so the job keeps its old schedule if the update fails.
+// A run in progress finishes with the old settings. The edits are stored with the job and
+// applied again when it is set up after a restart.
+func (m *DefaultJobManager) UpdateJob(id string, upd JobSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.shutdown {
+		return fmt.Errorf("job manager is shutting down")
+	}
+	if err := m.checkLeader(); err != nil {
+		return err
+	}
+
+	job, exists := m.job(id)
+	if !exists {
+		return fmt.Errorf("job %s not found", id)
+	}
+	cj, ok := job.(ConfigurableJob)
+	if !ok {
+		return fmt.Errorf("job %s has no editable settings", id)
+	}
+
+	jobDef, err := m.store.GetJob(id)
+	if err != nil {
+		return fmt.Errorf("failed to get job details: %w", err)
+	}
+
+	cfg := upd.applyTo(cj.Config())
+	timezone, nextRun, err := m.validateConfig(cfg)
+	if err != nil {
+		return serr.Wrap(err, "invalid job settings")
+	}
+	rescheduled := cfg.Schedule != jobDef.Schedule || timezone != jobDef.Timezone
+
+	if rescheduled && job.Type() == OneTime {
+		if jobDef.Status != StatusScheduled && jobDef.Status != StatusCreated {
+			return fmt.Errorf("job %s cannot be rescheduled in status %s", id, jobDef.Status)
+		}
+		if cfg.Schedule == "" && jobDef.Status == StatusScheduled {
+			return fmt.Errorf("job %s is scheduled and needs a schedule", id)
+		}
+	}
+
+	// Add the new cron entry first, so a bad schedule leaves the old one in place
+	oldEntry, scheduled := m.cronEntries[id]
+	active := scheduled && jobDef.Status != StatusPaused // paused jobs are added back on resume
+	var newEntry cron.EntryID
+	if rescheduled && active {
+		if newEntry, err = m.cron.AddFunc(cronSpec(cfg.Schedule, timezone), func() {
+			m.runTick(id)
+		}); err != nil {
+			return serr.Wrap(err, "failed to schedule job")
+		}
+	}
+
+	jobDef.JobName = cfg.Name
+	jobDef.Schedule = cfg.Schedule
+	jobDef.Timezone = timezone
+	jobDef.Tags = cfg.Tags
+	jobDef.Calendars = cfg.Calendars
+	jobDef.CalendarPolicy = cfg.CalendarPolicy
+	jobDef.Edits = jobDef.Edits.merge(upd)
+	jobDef.UpdatedAt = time.Now().UTC()
+	if rescheduled {
+		jobDef.NextRunTime = nextRun
+	}
+	if err := m.store.SaveJob(jobDef); err != nil {
+		if newEntry != 0 {
+			m.cron.Remove(newEntry)
+		}
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+
+	// Swap in the rebuilt job, then the new schedule
+	m.jobs[id] = NewScheduledJob(cfg)
+	if newEntry != 0 {
+		m.cron.Remove(oldEntry)
+		m.cronEntries[id] = newEntry
+		m.updateNextRun(id, newEntry)
+	}
+	if _, pending := m.scheduledJobs[id]; pending && rescheduled && job.Type() == OneTime {
+		m.scheduleOnce(id, nextRun)
+	}
+
+	// Let the system know that jobs have been updated
+	select {
+	case m.jobsUpdated <- "updated":
+		fmt.Println("Job update (edited) notification sent")
+	default: // Non-blocking send to avoid blocking if no one is listening
+		// If the channel is full, we don't want to block
+	}
+
+	return nil
+}
+
+// validateConfig checks the settings of a job config, returning the timezone of its schedule and its next run time
+// The caller must hold m.mu
+func (m *DefaultJobManager) validateConfig(jc JobConfig) (timezone string, nextRun time.Time, err error) {
+	switch {
+	case strings.TrimSpace(jc.Name) == "":
+		return "", nextRun, fmt.Errorf("name is required")
+	case jc.MaxRunTime < 0 || jc.HeartbeatTimeout < 0 || jc.Jitter < 0 || jc.Spread < 0:
+		return "", nextRun, fmt.Errorf("max run time, heartbeat timeout, jitter and spread can't be negative")
+	case jc.TriggerEndpoint != "" && !strings.HasPrefix(jc.TriggerEndpoint, "/"):
+		return "", nextRun, fmt.Errorf("trigger endpoint %q must be a path starting with /", jc.TriggerEndpoint)
+	case jc.TriggerEndpoint != "" && jc.Command != "":
+		return "", nextRun, fmt.Errorf("a job runs either a trigger endpoint or a command, not both")
+	case jc.TriggerEndpoint == "" && jc.Command == "" && jc.JobFunction == nil && jc.RunFunction == nil:
+		return "", nextRun, fmt.Errorf("a trigger endpoint or command is required, as the job has no function")
+	case jc.RetryCount < 0:
+		return "", nextRun, fmt.Errorf("retry count can't be negative")
+	case jc.IsPeriodic && jc.Schedule == "":
+		return "", nextRun, fmt.Errorf("periodic jobs need a schedule")
+	case jc.CalendarPolicy != "" && jc.CalendarPolicy != CalendarSkip && jc.CalendarPolicy != CalendarDefer:
+		return "", nextRun, fmt.Errorf("invalid calendar policy %q", jc.CalendarPolicy)
+	case jc.Requires != "" && !jc.Remote:
+		return "", nextRun, fmt.Errorf("only remote jobs can require worker labels")
+	}
+	if _, err := ParseSelector(jc.Requires); err != nil {
+		return "", nextRun, fmt.Errorf("invalid worker requirements: %w", err)
+	}
+	if err := m.checkCalendars(jc.Calendars); err != nil {
+		return "", nextRun, err
+	}
+	if err := m.checkPools(jc.Pools); err != nil {
+		return "", nextRun, err
+	}
+	if err := checkPoolPolicy(jc.PoolPolicy); err != nil {
+		return "", nextRun, err
+	}
+
+	timezone = scheduleTimezone(jc.Schedule, jc.Timezone)
+	if nextRun, err = nextRunTime(util.If(jc.IsPeriodic, Periodic, OneTime), jc.Schedule, timezone, time.Now()); err != nil {
+		return "", nextRun, err
+	}
+	return timezone, nextRun, nil
+}
@@ -0,0 +1,158 @@
+package jobpro
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdatePeriodicJob(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	jc := JobConfig{Id: "edit-me", Name: "Edit Me", IsPeriodic: true, Schedule: "0 0 * * * *", AutoStart: true,
+		JobFunction: func() error { return nil }}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	oldEntry := mgr.cronEntries[jc.Id]
+
+	// A bad schedule leaves the job as it was
+	badSchedule := "every fortnight"
+	if err := mgr.UpdateJob(jc.Id, JobSettings{Schedule: &badSchedule}); err == nil {
+		t.Fatal("Expected an invalid schedule to be rejected")
+	}
+	if mgr.cronEntries[jc.Id] != oldEntry || len(mgr.cron.Entries()) != 1 {
+		t.Errorf("Expected the cron entry to be unchanged, got %d entries", len(mgr.cron.Entries()))
+	}
+
+	schedule, maxRunTime := "0 30 9 * * *", 120
+	if err := mgr.UpdateJob(jc.Id, JobSettings{Schedule: &schedule, MaxRunTime: &maxRunTime}); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+
+	if entry := mgr.cronEntries[jc.Id]; entry == oldEntry || len(mgr.cron.Entries()) != 1 {
+		t.Errorf("Expected the cron entry to be swapped, got entry %d of %d", entry, len(mgr.cron.Entries()))
+	}
+
+	jobDef, err := store.GetJob(jc.Id)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if jobDef.Schedule != schedule || jobDef.Status != StatusRunning {
+		t.Errorf("Expected schedule %q and status running, got %q and %s", schedule, jobDef.Schedule, jobDef.Status)
+	}
+	if next := jobDef.NextRunTime.In(time.Local); next.Hour() != 9 || next.Minute() != 30 {
+		t.Errorf("Expected the next run at 09:30, got %s", next)
+	}
+	if jobDef.Edits.Schedule == nil || jobDef.Edits.Name != nil {
+		t.Errorf("Expected only the changed settings to be stored as edits, got %+v", jobDef.Edits)
+	}
+
+	settings, err := mgr.GetJobSettings(jc.Id)
+	if err != nil {
+		t.Fatalf("Failed to get settings: %v", err)
+	}
+	if *settings.MaxRunTime != maxRunTime || *settings.Name != jc.Name {
+		t.Errorf("Expected max run time %d and name %q, got %d and %q",
+			maxRunTime, jc.Name, *settings.MaxRunTime, *settings.Name)
+	}
+
+	// Settings are validated
+	for _, upd := range []JobSettings{
+		{Name: new(string)},
+		{TriggerEndpoint: ptrTo("no-slash")},
+		{Calendars: []string{"unknown"}},
+		{Jitter: ptrTo(-1)},
+	} {
+		if err := mgr.UpdateJob(jc.Id, upd); err == nil {
+			t.Errorf("Expected update %+v to be rejected", upd)
+		}
+	}
+}
+
+func TestUpdateOneTimeJob(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	jc := JobConfig{Id: "later", Name: "Later", Schedule: "in 1h", AutoStart: true,
+		JobFunction: func() error { return nil }}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	if err := mgr.UpdateJob(jc.Id, JobSettings{Schedule: ptrTo("in 2h")}); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+
+	jobDef, err := store.GetJob(jc.Id)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if until := time.Until(jobDef.NextRunTime); until < 119*time.Minute || until > 2*time.Hour {
+		t.Errorf("Expected the next run in 2h, got %s", until)
+	}
+	if _, pending := mgr.scheduledJobs[jc.Id]; !pending || jobDef.Status != StatusScheduled {
+		t.Errorf("Expected the job to stay scheduled, got status %s", jobDef.Status)
+	}
+}
+
+func TestJobEditsOutliveRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "edits.db")
+	defer os.Remove(dbPath)
+
+	jc := JobConfig{Id: "kept", Name: "Kept", IsPeriodic: true, Schedule: "0 0 * * * *",
+		JobFunction: func() error { return nil }}
+
+	store1, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr1 := NewJobManager(store1)
+	if err := setupJob(mgr1, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr1.UpdateJob(jc.Id, JobSettings{Schedule: ptrTo("0 15 * * * *"), Tags: map[string]string{"team": "ops"}}); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+	if err := mgr1.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("Failed to shutdown manager: %v", err)
+	}
+
+	// Set up again from the original config
+	store2, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to create store after restart: %v", err)
+	}
+	mgr2 := NewJobManager(store2)
+	defer mgr2.Shutdown(5 * time.Second)
+	if err := setupJob(mgr2, jc); err != nil {
+		t.Fatalf("Failed to setup job after restart: %v", err)
+	}
+
+	jobDef, err := store2.GetJob(jc.Id)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if jobDef.Schedule != "0 15 * * * *" || jobDef.Tags["team"] != "ops" {
+		t.Errorf("Expected the edited schedule and tags, got %q and %v", jobDef.Schedule, jobDef.Tags)
+	}
+	if settings, _ := mgr2.GetJobSettings(jc.Id); *settings.Schedule != "0 15 * * * *" {
+		t.Errorf("Expected the job to be rebuilt with the edits, got schedule %q", *settings.Schedule)
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
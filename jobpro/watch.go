@@ -0,0 +1,393 @@
+package jobpro
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultWatchStable is how long a file must be unchanged before it is processed, when a watch doesn't set it
+	defaultWatchStable = 5 * time.Second
+	// defaultWatchPoll is how often a watched directory is scanned, when a watch doesn't set it
+	defaultWatchPoll = 2 * time.Second
+)
+
+// WatchConfig declares a directory watch starting a run of a job for each file landing in the directory,
+// such as the inbox of an ETL job. The directory is polled, so it may be on a network share.
+// The run gets the file's path in its "file" parameter (see ParamsFromContext).
+type WatchConfig struct {
+	Name    string // defaults to the job Id
+	JobID   string
+	Dir     string
+	Pattern string `json:",omitempty"` // glob of the file names to process, all files by default
+	// Stable (in seconds, 5 by default) is how long a file's size and modification time must be unchanged
+	// before it is processed, so files still being written are left alone
+	Stable int `json:",omitempty"`
+	// Poll (in seconds, 2 by default) is how often the directory is scanned
+	Poll int `json:",omitempty"`
+	// ProcessedDir and ErrorDir, if set, are where files are moved once their run completed or failed.
+	// Files are otherwise left in place, and not processed again unless they change.
+	ProcessedDir string `json:",omitempty"`
+	ErrorDir     string `json:",omitempty"`
+}
+
+// Validate checks the watch has a job, a directory and a sound pattern
+func (c WatchConfig) Validate() error {
+	switch {
+	case c.JobID == "":
+		return fmt.Errorf("watch %q has no job", c.Name)
+	case c.Dir == "":
+		return fmt.Errorf("watch %s has no directory", c.name())
+	case c.Stable < 0 || c.Poll < 0:
+		return fmt.Errorf("watch %s: intervals can't be negative", c.name())
+	}
+	if _, err := filepath.Match(c.Pattern, ""); err != nil {
+		return fmt.Errorf("watch %s: invalid pattern %q", c.name(), c.Pattern)
+	}
+	return nil
+}
+
+// name returns the name of the watch
+func (c WatchConfig) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.JobID
+}
+
+func (c WatchConfig) stable() time.Duration {
+	if c.Stable > 0 {
+		return time.Duration(c.Stable) * time.Second
+	}
+	return defaultWatchStable
+}
+
+func (c WatchConfig) poll() time.Duration {
+	if c.Poll > 0 {
+		return time.Duration(c.Poll) * time.Second
+	}
+	return defaultWatchPoll
+}
+
+// WatchedFileStatus is the state of a file picked up by a watch
+type WatchedFileStatus string
+
+const (
+	WatchedFileRunning   WatchedFileStatus = "running"
+	WatchedFileProcessed WatchedFileStatus = "processed" // its run completed
+	WatchedFileFailed    WatchedFileStatus = "failed"    // its run failed, timed out or was cancelled
+)
+
+// WatchedFile is a file picked up by a watch. A file is picked up once for each size and modification time,
+// so it is not processed again after a restart unless it changes.
+type WatchedFile struct {
+	Watch   string
+	Path    string
+	Size    int64
+	ModTime time.Time
+	JobID   string
+	Status  WatchedFileStatus
+	SeenAt  time.Time // when the file was picked up
+	MovedTo string    // where the file was moved once processed, if anywhere
+}
+
+// fileState is what a watch last saw of a file
+type fileState struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when the file was first seen like this
+}
+
+// dirWatch is a configured watch, and the files it is waiting on to be stable
+type dirWatch struct {
+	cfg   WatchConfig
+	stop  chan struct{}        // closed to stop the watch
+	files map[string]fileState // by path, only used by the watch's goroutine
+}
+
+// ConfigureWatches validates and starts the directory watches given, replacing any of the same name.
+// Files left running by a shutdown or crash are picked up again by their new watch.
+// Watches are shared by all namespaces.
+func (m *DefaultJobManager) ConfigureWatches(cfgs ...WatchConfig) error {
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shutdown {
+		return fmt.Errorf("job manager is shutting down")
+	}
+
+	for _, cfg := range cfgs {
+		cfg.Name = cfg.name()
+		if old, ok := m.watches[cfg.Name]; ok {
+			close(old.stop)
+		} else if err := m.rootStore.ReleaseWatchedFiles(cfg.Name, WatchedFileRunning); err != nil {
+			return fmt.Errorf("watch %s: %w", cfg.Name, err)
+		}
+
+		w := &dirWatch{cfg: cfg, stop: make(chan struct{}), files: make(map[string]fileState)}
+		m.watches[cfg.Name] = w
+		go m.runWatch(w)
+	}
+	return nil
+}
+
+// Watches returns the configured directory watches, by name
+func (m *DefaultJobManager) Watches() []WatchConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cfgs := make([]WatchConfig, 0, len(m.watches))
+	for _, w := range m.watches {
+		cfgs = append(cfgs, w.cfg)
+	}
+	slices.SortFunc(cfgs, func(a, b WatchConfig) int { return strings.Compare(a.Name, b.Name) })
+	return cfgs
+}
+
+// WatchedFiles returns the latest files picked up by a watch, newest first
+func (m *DefaultJobManager) WatchedFiles(watch string, limit int) ([]WatchedFile, error) {
+	return m.rootStore.ListWatchedFiles(watch, limit)
+}
+
+// stopWatches stops all directory watches
+// The caller must hold m.mu
+func (m *DefaultJobManager) stopWatches() {
+	for name, w := range m.watches {
+		close(w.stop)
+		delete(m.watches, name)
+	}
+}
+
+// runWatch scans the directory of a watch every poll interval until the watch is stopped
+func (m *DefaultJobManager) runWatch(w *dirWatch) {
+	ticker := time.NewTicker(w.cfg.poll())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			m.scanWatch(w)
+		}
+	}
+}
+
+// scanWatch starts a run for each file of the watch that has been stable long enough
+// and was not picked up already
+func (m *DefaultJobManager) scanWatch(w *dirWatch) {
+	pattern := w.cfg.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+	paths, err := filepath.Glob(filepath.Join(w.cfg.Dir, pattern))
+	if err != nil {
+		log.Printf("Watch %s: %v", w.cfg.Name, err)
+		return
+	}
+
+	now := time.Now()
+	present := make(map[string]struct{}, len(paths))
+	var stable []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		present[path] = struct{}{}
+
+		state, seen := w.files[path]
+		if !seen || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			w.files[path] = fileState{size: info.Size(), modTime: info.ModTime(), since: now}
+			continue
+		}
+		if now.Sub(state.since) >= w.cfg.stable() {
+			stable = append(stable, path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := present[path]; !ok {
+			delete(w.files, path)
+		}
+	}
+	if len(stable) == 0 || !m.watchActive(w.cfg.JobID) {
+		return
+	}
+
+	for _, path := range stable {
+		state := w.files[path]
+		file := WatchedFile{Watch: w.cfg.Name, Path: path, Size: state.size, ModTime: state.modTime.UTC(),
+			JobID: w.cfg.JobID, Status: WatchedFileRunning, SeenAt: now.UTC()}
+
+		// Only one pick up of each version of a file, across restarts
+		claimed, err := m.rootStore.ClaimWatchedFile(file)
+		if err != nil {
+			log.Printf("Watch %s: failed to pick up %s: %v", w.cfg.Name, path, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		log.Printf("Watch %s: running job %s for %s", w.cfg.Name, w.cfg.JobID, path)
+		params := map[string]any{"file": path, "name": filepath.Base(path), "size": state.size}
+		cfg := w.cfg
+		go m.executeJob(cfg.JobID, runTrigger{source: TriggerWatch, params: params,
+			done: func(result JobResult) { m.fileProcessed(cfg, file, result) }})
+
+		// Runs of a job don't overlap, so the other files wait for a poll finding the job free
+		return
+	}
+}
+
+// watchActive reports whether a watch may start a run of its job: only the leader starts them,
+// not for paused or stopped jobs, and not while the job is running
+func (m *DefaultJobManager) watchActive(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.shutdown || m.checkLeader() != nil {
+		return false
+	}
+	if _, exists := m.jobs[id]; !exists {
+		return false
+	}
+	if _, running := m.runningJobs[id]; running {
+		return false
+	}
+	jobDef, err := m.rootStore.GetJob(id)
+	if err != nil {
+		log.Printf("Error getting the status of job %s: %v", id, err)
+		return false
+	}
+	return jobDef.Status != StatusPaused && jobDef.Status != StatusStopped
+}
+
+// fileProcessed moves a file to the processed or error directory of its watch once its run is over.
+// Files whose run did not go, or was interrupted by the shutdown, are released to be picked up again.
+// Watches don't start runs while the job is running, so a run skipped for overlap only comes of a race with another trigger.
+func (m *DefaultJobManager) fileProcessed(cfg WatchConfig, file WatchedFile, result JobResult) {
+	var dir string
+	switch result.Status {
+	case StatusComplete:
+		dir, file.Status = cfg.ProcessedDir, WatchedFileProcessed
+	case StatusSkippedOverlap, StatusSkippedPool, StatusSkippedCalendar, StatusInterruptedByShutdown:
+		if err := m.rootStore.DeleteWatchedFile(file); err != nil {
+			log.Printf("Watch %s: failed to release %s: %v", cfg.Name, file.Path, err)
+		}
+		return
+	default:
+		dir, file.Status = cfg.ErrorDir, WatchedFileFailed
+	}
+
+	if dir != "" {
+		moved, err := moveFile(file.Path, dir)
+		if err != nil {
+			log.Printf("Watch %s: failed to move %s to %s: %v", cfg.Name, file.Path, dir, err)
+		} else {
+			file.MovedTo = moved
+		}
+	}
+	if err := m.rootStore.SaveWatchedFile(file); err != nil {
+		log.Printf("Watch %s: failed to record %s as %s: %v", cfg.Name, file.Path, file.Status, err)
+	}
+}
+
+// moveFile moves a file into dir, creating it if needed, and returns its new path.
+// A file of the same name already there is kept, the moved one taking a timestamped name.
+func moveFile(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	target := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		target = filepath.Join(dir, time.Now().UTC().Format("20060102T150405.000")+"-"+filepath.Base(path))
+	}
+	return target, os.Rename(path, target)
+}
+
+// ClaimWatchedFile records a file picked up by a watch, reporting false if that version of it already was
+func (s *DuckDBStore) ClaimWatchedFile(file WatchedFile) (bool, error) {
+	res, err := s.db.Exec(`
+		INSERT INTO watched_files (watch, path, size, mod_time, job_id, status, seen_at, moved_to)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT DO NOTHING
+	`, file.Watch, file.Path, file.Size, file.ModTime.UTC(), file.JobID, file.Status, file.SeenAt.UTC(), file.MovedTo)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim watched file: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim watched file: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SaveWatchedFile updates the status of a file picked up by a watch, and where it was moved
+func (s *DuckDBStore) SaveWatchedFile(file WatchedFile) error {
+	_, err := s.db.Exec(`
+		UPDATE watched_files SET status = ?, moved_to = ?
+		WHERE watch = ? AND path = ? AND size = ? AND mod_time = ?
+	`, file.Status, file.MovedTo, file.Watch, file.Path, file.Size, file.ModTime.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save watched file: %w", err)
+	}
+	return nil
+}
+
+// DeleteWatchedFile forgets a file picked up by a watch, so it is picked up again
+func (s *DuckDBStore) DeleteWatchedFile(file WatchedFile) error {
+	_, err := s.db.Exec(`DELETE FROM watched_files WHERE watch = ? AND path = ? AND size = ? AND mod_time = ?`,
+		file.Watch, file.Path, file.Size, file.ModTime.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to delete watched file: %w", err)
+	}
+	return nil
+}
+
+// ReleaseWatchedFiles forgets the files of a watch in the given status, so they are picked up again
+func (s *DuckDBStore) ReleaseWatchedFiles(watch string, status WatchedFileStatus) error {
+	if _, err := s.db.Exec(`DELETE FROM watched_files WHERE watch = ? AND status = ?`, watch, status); err != nil {
+		return fmt.Errorf("failed to release watched files: %w", err)
+	}
+	return nil
+}
+
+// ListWatchedFiles returns the latest files picked up by a watch, newest first
+func (s *DuckDBStore) ListWatchedFiles(watch string, limit int) ([]WatchedFile, error) {
+	rows, err := s.db.Query(`
+		SELECT watch, path, size, mod_time, job_id, status, seen_at, moved_to
+		FROM watched_files
+		WHERE watch = ?
+		ORDER BY seen_at DESC, path
+		LIMIT ?
+	`, watch, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watched files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []WatchedFile{}
+	for rows.Next() {
+		var file WatchedFile
+		var movedTo sql.NullString
+		if err := rows.Scan(&file.Watch, &file.Path, &file.Size, &file.ModTime, &file.JobID, &file.Status,
+			&file.SeenAt, &movedTo); err != nil {
+			return nil, fmt.Errorf("failed to scan watched file: %w", err)
+		}
+		file.MovedTo = movedTo.String
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
@@ -0,0 +1,204 @@
+package jobpro
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForFile waits for a file to exist
+func waitForFile(t *testing.T, path string, wait time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(wait); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+	}
+	t.Fatalf("Expected %s within %s", path, wait)
+}
+
+// etlJob returns the config of a job loading the file of its run, failing on files reading "bad"
+func etlJob(id string, files chan<- string) JobConfig {
+	return JobConfig{Id: id, Name: "ETL", RunFunction: func(ctx context.Context) error {
+		path, _ := ParamsFromContext(ctx)["file"].(string)
+		files <- path
+		byts, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if string(byts) == "bad" {
+			return errors.New("bad rows")
+		}
+		return nil
+	}}
+}
+
+func TestWatchMovesFiles(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	dir := t.TempDir()
+	inbox, processed, failed := filepath.Join(dir, "inbox"), filepath.Join(dir, "processed"), filepath.Join(dir, "error")
+	if err := os.Mkdir(inbox, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(chan string, 8)
+	jc := etlJob("etl", files)
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr.ConfigureWatches(WatchConfig{JobID: jc.Id, Dir: inbox, Pattern: "["}); err == nil {
+		t.Error("Expected a watch with an invalid pattern to be refused")
+	}
+	err = mgr.ConfigureWatches(WatchConfig{JobID: jc.Id, Dir: inbox, Pattern: "*.csv", Stable: 1, Poll: 1,
+		ProcessedDir: processed, ErrorDir: failed})
+	if err != nil {
+		t.Fatalf("Failed to configure watches: %v", err)
+	}
+
+	for name, content := range map[string]string{"good.csv": "ok", "bad.csv": "bad", "notes.txt": "ignored"} {
+		if err := os.WriteFile(filepath.Join(inbox, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Completed files are moved to the processed directory, failed ones to the error directory
+	waitForFile(t, filepath.Join(processed, "good.csv"), 5*time.Second)
+	waitForFile(t, filepath.Join(failed, "bad.csv"), 5*time.Second)
+	if _, err := os.Stat(filepath.Join(inbox, "notes.txt")); err != nil {
+		t.Errorf("Expected files not matching the pattern to be left alone: %v", err)
+	}
+	if path := <-files; filepath.Dir(path) != inbox {
+		t.Errorf("Expected the run to get the path of the file, got %q", path)
+	}
+	if result := waitForResult(t, store, jc.Id, 2*time.Second); result.Trigger != TriggerWatch {
+		t.Errorf("Expected the run to be recorded as triggered by %s, got %q", TriggerWatch, result.Trigger)
+	}
+
+	picked, err := mgr.WatchedFiles(jc.Id, 10)
+	if err != nil {
+		t.Fatalf("Failed to list watched files: %v", err)
+	}
+	statuses := map[string]WatchedFileStatus{}
+	for _, f := range picked {
+		statuses[filepath.Base(f.Path)] = f.Status
+	}
+	if statuses["good.csv"] != WatchedFileProcessed || statuses["bad.csv"] != WatchedFileFailed || len(statuses) != 2 {
+		t.Errorf("Expected good.csv processed and bad.csv failed, got %v", statuses)
+	}
+}
+
+func TestWatchDedupesAcrossRestarts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "watch.db")
+	inbox := t.TempDir()
+	path := filepath.Join(inbox, "orders.csv")
+	if err := os.WriteFile(path, []byte("ok"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a processed directory the file stays in the inbox, and runs once, restarts included
+	run := func() int {
+		store, err := NewDuckDBStore(dbPath)
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		mgr := NewJobManager(store)
+		defer mgr.Shutdown(5 * time.Second)
+
+		files := make(chan string, 4)
+		if err := setupJob(mgr, etlJob("orders", files)); err != nil {
+			t.Fatalf("Failed to setup job: %v", err)
+		}
+		if err := mgr.ConfigureWatches(WatchConfig{JobID: "orders", Dir: inbox, Stable: 1, Poll: 1}); err != nil {
+			t.Fatalf("Failed to configure watches: %v", err)
+		}
+
+		runs := 0
+		for timeout := time.After(3500 * time.Millisecond); ; {
+			select {
+			case <-files:
+				runs++
+			case <-timeout:
+				return runs
+			}
+		}
+	}
+	if runs := run(); runs != 1 {
+		t.Fatalf("Expected the file to be processed once, got %d runs", runs)
+	}
+	if runs := run(); runs != 0 {
+		t.Errorf("Expected the file not to be processed again after a restart, got %d runs", runs)
+	}
+
+	// A new version of the file is processed
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if runs := run(); runs != 1 {
+		t.Errorf("Expected the changed file to be processed, got %d runs", runs)
+	}
+}
+
+func TestWatchWaitsForRunningJob(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	inbox, processed := t.TempDir(), t.TempDir()
+	started, release := make(chan struct{}, 4), make(chan struct{})
+	jc := JobConfig{Id: "slow-etl", Name: "Slow ETL", RunFunction: func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	names := []string{"a.csv", "b.csv", "c.csv"}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(inbox, name), []byte("ok"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = mgr.ConfigureWatches(WatchConfig{JobID: jc.Id, Dir: inbox, Stable: 1, Poll: 1, ProcessedDir: processed})
+	if err != nil {
+		t.Fatalf("Failed to configure watches: %v", err)
+	}
+
+	// While the first file's run goes on, the others wait, without recording skipped runs
+	<-started
+	time.Sleep(3 * time.Second)
+	if results, _ := store.GetJobResults(jc.Id, 10); len(results) != 0 {
+		t.Errorf("Expected no results while the job is running, got %d", len(results))
+	}
+
+	close(release)
+	for _, name := range names {
+		waitForFile(t, filepath.Join(processed, name), 6*time.Second)
+	}
+	waitForResults(t, store, jc.Id, len(names))
+	results, err := store.GetJobResults(jc.Id, 10)
+	if err != nil {
+		t.Fatalf("Failed to get results: %v", err)
+	}
+	for _, result := range results {
+		if result.Status != StatusComplete {
+			t.Errorf("Expected every file's run to complete, got %s", result.Status)
+		}
+	}
+	if len(results) != len(names) {
+		t.Errorf("Expected a run per file, got %d", len(results))
+	}
+}
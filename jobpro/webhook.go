@@ -0,0 +1,233 @@
+package jobpro
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrHookNotFound is the error of a delivery to a webhook that is not configured, or whose job is gone
+	ErrHookNotFound = errors.New("webhook not found")
+	// ErrBadSignature is the error of a delivery whose signature doesn't match the body
+	ErrBadSignature = errors.New("invalid webhook signature")
+	// ErrBadPayload is the error of a delivery whose body is not JSON
+	ErrBadPayload = errors.New("invalid webhook payload")
+	// ErrHookJobInactive is the error of a delivery to the webhook of a paused or stopped job
+	ErrHookJobInactive = errors.New("job is paused or stopped")
+)
+
+// HookConfig declares an inbound webhook starting runs of a job, such as on a git push or an upload.
+// Deliveries are posted to /hooks/<Name>, signed with Secret, and their JSON payload becomes the run's
+// parameters (see ParamsFromContext).
+type HookConfig struct {
+	Name   string // defaults to the job Id
+	JobID  string
+	Secret string // key of the HMAC-SHA256 signature of each delivery
+	// Debounce (in seconds) delays the run until no delivery came in for that long.
+	// Coalesce (in seconds) caps how long the run waits after the first delivery.
+	// Deliveries within the wait make one run, with the payload of the latest. Zero for both runs each delivery at once.
+	Debounce int `json:",omitempty"`
+	Coalesce int `json:",omitempty"`
+}
+
+// Validate checks the webhook has a job, a secret and sound windows
+func (c HookConfig) Validate() error {
+	switch {
+	case c.JobID == "":
+		return fmt.Errorf("webhook %q has no job", c.Name)
+	case c.Secret == "":
+		return fmt.Errorf("webhook %s has no secret", c.name())
+	case c.Debounce < 0 || c.Coalesce < 0:
+		return fmt.Errorf("webhook %s: windows can't be negative", c.name())
+	}
+	return nil
+}
+
+// name returns the name the webhook is posted to
+func (c HookConfig) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.JobID
+}
+
+// VerifySignature reports whether signature is the hex HMAC-SHA256 of body keyed with the webhook's secret.
+// A "sha256=" prefix, as sent by GitHub, is accepted.
+func (c HookConfig) VerifySignature(body []byte, signature string) bool {
+	sig, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signature), "sha256="))
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// runAt returns when the run of deliveries that began at first, the latest at last, is due
+func (c HookConfig) runAt(first, last time.Time) time.Time {
+	coalesced := first.Add(time.Duration(c.Coalesce) * time.Second)
+	if c.Debounce == 0 {
+		return coalesced
+	}
+	debounced := last.Add(time.Duration(c.Debounce) * time.Second)
+	if c.Coalesce > 0 && coalesced.Before(debounced) {
+		return coalesced
+	}
+	return debounced
+}
+
+// HookDelivery tells what became of a webhook delivery
+type HookDelivery struct {
+	JobID  string
+	Status string    // "triggered" when the run started, "pending" when it waits for the hook's windows
+	Events int       // deliveries the run is made of
+	RunAt  time.Time // when a pending run is due
+}
+
+// pendingHook is a run waiting for the windows of a webhook
+type pendingHook struct {
+	timer  *time.Timer
+	first  time.Time
+	params map[string]any // of the latest delivery
+	events int
+}
+
+// ConfigureHooks validates and sets the webhooks given, replacing any of the same name.
+// Webhooks are shared by all namespaces.
+func (m *DefaultJobManager) ConfigureHooks(cfgs ...HookConfig) error {
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cfg := range cfgs {
+		cfg.Name = cfg.name()
+		m.hooks[cfg.Name] = cfg
+	}
+	return nil
+}
+
+// DeliverHook verifies a delivery to the named webhook and runs its job with the payload as parameters,
+// at once or once the hook's windows have passed
+func (m *DefaultJobManager) DeliverHook(name string, body []byte, signature string) (HookDelivery, error) {
+	m.mu.RLock()
+	hook, ok := m.hooks[name]
+	m.mu.RUnlock()
+	if !ok {
+		return HookDelivery{}, fmt.Errorf("%w: %s", ErrHookNotFound, name)
+	}
+	if !hook.VerifySignature(body, signature) {
+		return HookDelivery{}, fmt.Errorf("%w: %s", ErrBadSignature, name)
+	}
+	params, err := hookParams(body)
+	if err != nil {
+		return HookDelivery{}, err
+	}
+
+	id := hook.JobID
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkLeader(); err != nil {
+		return HookDelivery{}, err
+	}
+	if m.shutdown {
+		return HookDelivery{}, fmt.Errorf("job manager is shutting down")
+	}
+	if _, exists := m.jobs[id]; !exists {
+		return HookDelivery{}, fmt.Errorf("%w: %s, job %s not found", ErrHookNotFound, name, id)
+	}
+	jobDef, err := m.rootStore.GetJob(id)
+	if err != nil {
+		return HookDelivery{}, fmt.Errorf("failed to get job status: %w", err)
+	}
+	if jobDef.Status == StatusPaused || jobDef.Status == StatusStopped {
+		return HookDelivery{}, fmt.Errorf("%w: %s", ErrHookJobInactive, id)
+	}
+
+	if hook.Debounce == 0 && hook.Coalesce == 0 {
+		go m.executeJob(id, runTrigger{source: TriggerWebhook, params: params})
+		m.notifyHook()
+		return HookDelivery{JobID: id, Status: "triggered", Events: 1}, nil
+	}
+
+	// Coalesce the delivery into the pending run of the job, pushing it back when debounced
+	now := time.Now()
+	pending, ok := m.hookRuns[id]
+	if !ok {
+		pending = &pendingHook{first: now}
+		m.hookRuns[id] = pending
+	}
+	pending.params = params
+	pending.events++
+
+	at := hook.runAt(pending.first, now)
+	if pending.timer == nil {
+		pending.timer = time.AfterFunc(time.Until(at), func() { m.runPendingHook(id, pending) })
+	} else {
+		pending.timer.Reset(time.Until(at))
+	}
+	log.Printf("Job %s: webhook %s delivery %d, run due at %s", id, name, pending.events, at.UTC().Format(time.TimeOnly))
+
+	return HookDelivery{JobID: id, Status: "pending", Events: pending.events, RunAt: at.UTC()}, nil
+}
+
+// runPendingHook runs a job once the windows of its webhook have passed
+func (m *DefaultJobManager) runPendingHook(id string, pending *pendingHook) {
+	m.mu.Lock()
+	if m.hookRuns[id] != pending { // cancelled, or already run
+		m.mu.Unlock()
+		return
+	}
+	delete(m.hookRuns, id)
+	params, events := pending.params, pending.events
+	m.mu.Unlock()
+
+	log.Printf("Job %s: running for %d webhook deliveries", id, events)
+	m.executeJob(id, runTrigger{source: TriggerWebhook, params: params})
+}
+
+// cancelHookRun drops the run of a job waiting for the windows of its webhook, if any
+// The caller must hold m.mu
+func (m *DefaultJobManager) cancelHookRun(id string) {
+	if pending, ok := m.hookRuns[id]; ok {
+		pending.timer.Stop()
+		delete(m.hookRuns, id)
+	}
+}
+
+// notifyHook lets the system know a webhook started a run
+func (m *DefaultJobManager) notifyHook() {
+	select {
+	case m.jobsUpdated <- "updated":
+		fmt.Println("Job update (webhook) notification sent")
+	default: // Non-blocking send to avoid blocking if no one is listening
+	}
+}
+
+// hookParams returns the parameters of a run from the body of a webhook delivery.
+// A JSON object gives the parameters, any other JSON value is the "payload" parameter.
+func hookParams(body []byte) (map[string]any, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadPayload, err)
+	}
+	if params, ok := payload.(map[string]any); ok {
+		return params, nil
+	}
+	return map[string]any{"payload": payload}, nil
+}
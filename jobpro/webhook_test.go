@@ -0,0 +1,144 @@
+package jobpro
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sign returns the signature of a webhook delivery
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	params := make(chan map[string]any, 2)
+	jc := JobConfig{Id: "deploy", Name: "Deploy", RunFunction: func(ctx context.Context) error {
+		params <- ParamsFromContext(ctx)
+		return nil
+	}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr.ConfigureHooks(HookConfig{JobID: jc.Id}); err == nil {
+		t.Error("Expected a webhook without a secret to be refused")
+	}
+	if err := mgr.ConfigureHooks(HookConfig{JobID: jc.Id, Secret: "s3cret"}); err != nil {
+		t.Fatalf("Failed to configure webhooks: %v", err)
+	}
+
+	body := `{"ref": "main"}`
+	for _, tc := range []struct {
+		name, hook, body, signature string
+		want                        error
+	}{
+		{"unknown hook", "nope", body, sign("s3cret", body), ErrHookNotFound},
+		{"bad signature", jc.Id, body, sign("guess", body), ErrBadSignature},
+		{"no signature", jc.Id, body, "", ErrBadSignature},
+		{"bad payload", jc.Id, "{", sign("s3cret", "{"), ErrBadPayload},
+	} {
+		if _, err := mgr.DeliverHook(tc.hook, []byte(tc.body), tc.signature); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	// A signed delivery runs the job at once, with the payload as parameters
+	delivery, err := mgr.DeliverHook(jc.Id, []byte(body), sign("s3cret", body))
+	if err != nil {
+		t.Fatalf("Failed to deliver webhook: %v", err)
+	}
+	if delivery.Status != "triggered" || delivery.JobID != jc.Id {
+		t.Errorf("Expected the run to be triggered, got %+v", delivery)
+	}
+	if p := <-params; p["ref"] != "main" {
+		t.Errorf("Expected the payload as params, got %v", p)
+	}
+	if result := waitForResult(t, store, jc.Id, 2*time.Second); result.Trigger != TriggerWebhook {
+		t.Errorf("Expected the run to be recorded as triggered by %s, got %q", TriggerWebhook, result.Trigger)
+	}
+
+	// Runs on demand have no params, and are recorded as manual
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if p := <-params; p != nil {
+		t.Errorf("Expected no params, got %v", p)
+	}
+	if result := waitForResults(t, store, jc.Id, 2)[0]; result.Trigger != TriggerManual {
+		t.Errorf("Expected the run to be recorded as triggered by %s, got %q", TriggerManual, result.Trigger)
+	}
+}
+
+func TestWebhookCoalescing(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	defer mgr.Shutdown(5 * time.Second)
+
+	params := make(chan map[string]any, 4)
+	jc := JobConfig{Id: "build", Name: "Build", RunFunction: func(ctx context.Context) error {
+		params <- ParamsFromContext(ctx)
+		return nil
+	}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	if err := mgr.ConfigureHooks(HookConfig{Name: "pushes", JobID: jc.Id, Secret: "s3cret", Debounce: 1}); err != nil {
+		t.Fatalf("Failed to configure webhooks: %v", err)
+	}
+
+	// A burst of deliveries makes one run, with the payload of the latest
+	for i, body := range []string{`{"commit": "a"}`, `{"commit": "b"}`, `"c"`} {
+		delivery, err := mgr.DeliverHook("pushes", []byte(body), sign("s3cret", body))
+		if err != nil {
+			t.Fatalf("Failed to deliver webhook: %v", err)
+		}
+		if delivery.Status != "pending" || delivery.Events != i+1 {
+			t.Errorf("Expected delivery %d to be pending, got %+v", i+1, delivery)
+		}
+	}
+	select {
+	case p := <-params:
+		if p["payload"] != "c" {
+			t.Errorf("Expected the latest payload as params, got %v", p)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected the deliveries to run the job")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if results, err := store.GetJobResults(jc.Id, 10); err != nil || len(results) != 1 {
+		t.Errorf("Expected one run of the burst, got %d (%v)", len(results), err)
+	}
+
+	// Pausing the job drops a pending run, and refuses deliveries
+	body := `{}`
+	if _, err := mgr.DeliverHook("pushes", []byte(body), sign("s3cret", body)); err != nil {
+		t.Fatalf("Failed to deliver webhook: %v", err)
+	}
+	if err := mgr.PauseJob(jc.Id); err != nil {
+		t.Fatalf("Failed to pause job: %v", err)
+	}
+	if _, err := mgr.DeliverHook("pushes", []byte(body), sign("s3cret", body)); !errors.Is(err, ErrHookJobInactive) {
+		t.Errorf("Expected deliveries to a paused job to be refused, got %v", err)
+	}
+	select {
+	case p := <-params:
+		t.Errorf("Expected the pending run to be dropped, got a run with %v", p)
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
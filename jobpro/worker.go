@@ -0,0 +1,220 @@
+package jobpro
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// defaultClaimWait is how long a worker's claim waits for a run to be queued
+const defaultClaimWait = 30 * time.Second
+
+// WorkerConfig configures a worker running the runs of remote jobs claimed from a scheduler
+type WorkerConfig struct {
+	SchedulerURL string            // e.g. http://scheduler:8000
+	Token        string            // API token of an admin principal, if the scheduler requires tokens
+	ID           string            // unique among the scheduler's workers
+	Labels       map[string]string // capabilities matched against the Requires selector of jobs, e.g. {"gpu": "true"}
+	Slots        int               // how many runs the worker runs at once, 1 by default
+	// Handlers run the jobs without a command or trigger endpoint, by job Id
+	Handlers  map[string]func(ctx context.Context) error
+	ClaimWait time.Duration // how long a claim waits for a run, 30s by default
+	Client    *http.Client  // http.DefaultClient by default
+}
+
+// errLeaseLost is the cause of the cancellation of a run the worker lost the lease on
+var errLeaseLost = errors.New("lost the lease on the run")
+
+// RunWorker claims runs from the scheduler and runs them until ctx is cancelled.
+// Runs in progress are then cancelled without being reported, so the scheduler queues them again
+// once their lease expires. Claims are retried while the scheduler can't be reached.
+func RunWorker(ctx context.Context, cfg WorkerConfig) error {
+	if cfg.SchedulerURL == "" || cfg.ID == "" {
+		return errors.New("a worker needs the scheduler URL and an Id")
+	}
+	cfg.SchedulerURL = strings.TrimSuffix(cfg.SchedulerURL, "/")
+	cfg.Slots = max(cfg.Slots, 1)
+	if cfg.ClaimWait <= 0 {
+		cfg.ClaimWait = defaultClaimWait
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	slots := make(chan struct{}, cfg.Slots)
+	backoff := time.Duration(0)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return waitForRuns(slots)
+		}
+
+		var claim WorkerClaim
+		err := cfg.post(ctx, "/api/v1/workers/claim?wait="+url.QueryEscape(cfg.ClaimWait.String()),
+			WorkerInfo{ID: cfg.ID, Labels: cfg.Labels}, &claim)
+		if err != nil || claim.Run == nil {
+			<-slots
+			if ctx.Err() != nil {
+				return waitForRuns(slots)
+			}
+			if err != nil {
+				// Back off up to 30s while the scheduler is unreachable or a follower
+				backoff = min(max(2*backoff, time.Second), 30*time.Second)
+				log.Printf("Worker %s failed to claim a run, retrying in %s: %v", cfg.ID, backoff, err)
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+				}
+			}
+			continue
+		}
+		backoff = 0
+
+		go func() {
+			defer func() { <-slots }()
+			cfg.runClaim(ctx, *claim.Run, claim.LeaseTTL)
+		}()
+	}
+}
+
+// waitForRuns waits for the runs holding slots to return
+func waitForRuns(slots chan struct{}) error {
+	for range cap(slots) {
+		slots <- struct{}{}
+	}
+	return nil
+}
+
+// runClaim runs a claimed run, sending heartbeats every third of the lease, and reports it
+func (cfg WorkerConfig) runClaim(ctx context.Context, run QueuedRun, leaseTTL time.Duration) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// The job gets the run's parameters, and its progress is forwarded with the heartbeats
+	runCtx = withParams(runCtx, run.Params)
+	runCtx, progress := withProgress(runCtx, run.JobID, "", 0, nil)
+
+	jc := run.Config
+	jc.Id = run.JobID
+	if handler, ok := cfg.Handlers[run.JobID]; ok {
+		jc.RunFunction = handler
+	}
+	log.Printf("Worker %s running job %s (run %s)", cfg.ID, run.JobID, run.RunID)
+
+	// Heartbeats keep the lease, and tell when the scheduler wants the run stopped
+	stopHeartbeats := make(chan struct{})
+	heartbeatsDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatsDone)
+		ticker := time.NewTicker(max(leaseTTL/3, 100*time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-stopHeartbeats:
+				return
+			}
+			heartbeat := struct {
+				Worker   string
+				Progress *Progress `json:",omitempty"`
+			}{Worker: cfg.ID}
+			if pr := progress.Progress(); !pr.LastHeartbeat.IsZero() {
+				heartbeat.Progress = &pr
+			}
+			var reply struct{ Stop bool }
+			err := cfg.post(runCtx, "/api/v1/workers/runs/"+run.RunID+"/heartbeat", heartbeat, &reply)
+			switch {
+			case errors.Is(err, ErrRunLost):
+				cancel(errLeaseLost)
+				return
+			case err != nil:
+				log.Printf("Worker %s failed to send a heartbeat for run %s: %v", cfg.ID, run.RunID, err)
+			case reply.Stop:
+				cancel(ErrCancelled)
+			}
+		}
+	}()
+
+	var stats Stats
+	var err error
+	if jc.TriggerEndpoint == "" && jc.Command == "" && jc.RunFunction == nil {
+		stats.StartTimeUTC = time.Now().UTC()
+		err = fmt.Errorf("worker %s has no handler for job %s", cfg.ID, run.JobID)
+	} else {
+		stats, err = NewScheduledJob(jc).Run(runCtx)
+	}
+	close(stopHeartbeats)
+	<-heartbeatsDone
+
+	// Runs lost, or stopped by the worker shutting down, are left to the scheduler to queue again
+	cause := context.Cause(runCtx)
+	if errors.Is(cause, errLeaseLost) || ctx.Err() != nil {
+		log.Printf("Worker %s dropped run %s: %v", cfg.ID, run.RunID, cause)
+		return
+	}
+
+	report := RunReport{Worker: cfg.ID, Stats: stats, TimedOut: errors.Is(err, ErrTimedOut),
+		Cancelled: errors.Is(cause, ErrCancelled)}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	for attempt := 1; ; attempt++ {
+		err := cfg.post(ctx, "/api/v1/workers/runs/"+run.RunID+"/complete", report, nil)
+		if err == nil || errors.Is(err, ErrRunLost) || attempt == 3 || ctx.Err() != nil {
+			if err != nil {
+				log.Printf("Worker %s failed to report run %s: %v", cfg.ID, run.RunID, err)
+			}
+			return
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+// post sends body as JSON to the scheduler, decoding the response into out if given.
+// A 410 Gone response returns ErrRunLost.
+func (cfg WorkerConfig) post(ctx context.Context, path string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return serr.Wrap(err, "failed to encode request")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.SchedulerURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return serr.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return serr.Wrap(err, "request to scheduler failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return ErrRunLost
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return serr.F("scheduler responded %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return serr.Wrap(err, "failed to decode scheduler response")
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package jobpro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// workerServer serves the worker endpoints of mgr, as the web package does
+func workerServer(t *testing.T, mgr *DefaultJobManager) *httptest.Server {
+	reply := func(w http.ResponseWriter, out any, err error) {
+		switch {
+		case errors.Is(err, ErrRunLost):
+			w.WriteHeader(http.StatusGone)
+		case err != nil:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		if err != nil {
+			out = map[string]string{"error": err.Error()}
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/workers/claim", func(w http.ResponseWriter, r *http.Request) {
+		var worker WorkerInfo
+		json.NewDecoder(r.Body).Decode(&worker)
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		claim, err := mgr.ClaimRun(r.Context(), worker, wait)
+		reply(w, claim, err)
+	})
+	mux.HandleFunc("POST /api/v1/workers/runs/{id}/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Worker   string
+			Progress *Progress
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		stop, err := mgr.HeartbeatRun(r.PathValue("id"), req.Worker, req.Progress)
+		reply(w, map[string]bool{"Stop": stop}, err)
+	})
+	mux.HandleFunc("POST /api/v1/workers/runs/{id}/complete", func(w http.ResponseWriter, r *http.Request) {
+		var report RunReport
+		json.NewDecoder(r.Body).Decode(&report)
+		reply(w, map[string]string{}, mgr.CompleteRun(r.PathValue("id"), report))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// startWorker runs a worker until the test ends
+func startWorker(t *testing.T, cfg WorkerConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := RunWorker(ctx, cfg); err != nil {
+			t.Errorf("Worker %s failed: %v", cfg.ID, err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestRemoteRunOnLabelledWorker(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	mgr.SetRunLease(600 * time.Millisecond) // heartbeats every 200ms
+	defer mgr.Shutdown(5 * time.Second)
+	srv := workerServer(t, mgr)
+
+	var scheduled atomic.Bool
+	jc := JobConfig{Id: "train", Name: "Train", Remote: true, Requires: "gpu", AutoStart: true,
+		RunFunction: func(ctx context.Context) error {
+			scheduled.Store(true)
+			return nil
+		}}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	// A worker without the label never claims the run
+	var wrongRuns atomic.Int32
+	startWorker(t, WorkerConfig{SchedulerURL: srv.URL, ID: "cpu-1", ClaimWait: 100 * time.Millisecond,
+		Handlers: map[string]func(ctx context.Context) error{
+			"train": func(ctx context.Context) error {
+				wrongRuns.Add(1)
+				return nil
+			},
+		}})
+	time.Sleep(300 * time.Millisecond)
+	if runs := mgr.QueuedRuns(); len(runs) != 1 || runs[0].Status != RunQueued {
+		t.Fatalf("Expected the run to wait for a worker with a GPU, got %+v", runs)
+	}
+
+	release := make(chan struct{})
+	startWorker(t, WorkerConfig{SchedulerURL: srv.URL, ID: "gpu-1", Labels: map[string]string{"gpu": ""},
+		ClaimWait: 100 * time.Millisecond,
+		Handlers: map[string]func(ctx context.Context) error{
+			"train": func(ctx context.Context) error {
+				ProgressFromContext(ctx).Report(60, "epoch 3", "")
+				<-release
+				OutputFromContext(ctx).SetRecords(42)
+				return nil
+			},
+		}})
+
+	// The job's progress on the worker comes with the heartbeats
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		if p, _ := mgr.RunProgress("train"); p.Step == "epoch 3" && p.Percent == 60 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the worker to forward the run's progress")
+		}
+	}
+	close(release)
+
+	result := waitForResult(t, store, "train", 5*time.Second)
+	if result.Status != StatusComplete {
+		t.Fatalf("Expected the remote run to succeed, got %s (%s)", result.Status, result.ErrorMsg)
+	}
+	if records, _ := result.Output[OutputRecords].(float64); records != 42 {
+		t.Errorf("Expected the worker's output to be recorded, got %v", result.Output)
+	}
+	if wrongRuns.Load() != 0 || scheduled.Load() {
+		t.Errorf("Expected only the GPU worker to run the job, got %d runs elsewhere (scheduler ran it: %v)",
+			wrongRuns.Load(), scheduled.Load())
+	}
+	if runs := mgr.QueuedRuns(); len(runs) != 0 {
+		t.Errorf("Expected the queue to be empty, got %+v", runs)
+	}
+}
+
+func TestLostRunLeaseIsRequeued(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+	mgr.SetRunLease(500 * time.Millisecond)
+	defer mgr.Shutdown(5 * time.Second)
+	srv := workerServer(t, mgr)
+
+	jc := JobConfig{Id: "report", Name: "Report", Remote: true, AutoStart: true, Command: "true"}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+
+	// A worker claims the run and goes silent
+	claim, err := mgr.ClaimRun(context.Background(), WorkerInfo{ID: "gone"}, time.Second)
+	if err != nil || claim.Run == nil {
+		t.Fatalf("Expected to claim the run, got %+v (%v)", claim, err)
+	}
+	if claim.Run.Config.Command != "true" || claim.LeaseTTL != 500*time.Millisecond {
+		t.Errorf("Expected the claim to carry the job config and lease, got %+v", claim)
+	}
+
+	// Once its lease expires the run goes back to the queue, and another worker runs it
+	startWorker(t, WorkerConfig{SchedulerURL: srv.URL, ID: "alive", ClaimWait: 100 * time.Millisecond})
+	result := waitForResult(t, store, "report", 5*time.Second)
+	if result.Status != StatusComplete {
+		t.Fatalf("Expected the requeued run to succeed, got %s (%s)", result.Status, result.ErrorMsg)
+	}
+
+	// The silent worker's late report is refused
+	if err := mgr.CompleteRun(claim.Run.RunID, RunReport{Worker: "gone"}); !errors.Is(err, ErrRunLost) {
+		t.Errorf("Expected the lost run's report to be refused, got %v", err)
+	}
+}
+
+func TestClaimEndsWhenWorkerClaimsAgain(t *testing.T) {
+	store, err := NewDuckDBStore("")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	mgr := NewJobManager(store)
+
+	jc := JobConfig{Id: "report", Name: "Report", Remote: true, Command: "true"}
+	if err := setupJob(mgr, jc); err != nil {
+		t.Fatalf("Failed to setup job: %v", err)
+	}
+	claimAsync := func(workerID string) <-chan WorkerClaim {
+		claims := make(chan WorkerClaim, 1)
+		go func() {
+			claim, _ := mgr.ClaimRun(context.Background(), WorkerInfo{ID: workerID}, 10*time.Second)
+			claims <- claim
+		}()
+		time.Sleep(200 * time.Millisecond) // let the claim wait
+		return claims
+	}
+	received := func(claims <-chan WorkerClaim) WorkerClaim {
+		t.Helper()
+		select {
+		case claim := <-claims:
+			return claim
+		case <-time.After(3 * time.Second):
+			t.Fatal("Expected the claim to end")
+			return WorkerClaim{}
+		}
+	}
+
+	// A claim the worker gave up on, as when its request was dropped, ends without a run once it claims again
+	stale := claimAsync("w1")
+	fresh := claimAsync("w1")
+	if claim := received(stale); claim.Run != nil {
+		t.Errorf("Expected the superseded claim to get no run, got %+v", claim.Run)
+	}
+	if err := mgr.TriggerJobNow(jc.Id); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	claim := received(fresh)
+	if claim.Run == nil {
+		t.Fatal("Expected the worker's current claim to get the run")
+	}
+	if err := mgr.CompleteRun(claim.Run.RunID, RunReport{Worker: "w1"}); err != nil {
+		t.Fatalf("Failed to complete the run: %v", err)
+	}
+	waitForResult(t, store, jc.Id, 2*time.Second)
+
+	// Shutting down ends the claims waiting for a run
+	waiting := claimAsync("w2")
+	if err := mgr.Shutdown(5 * time.Second); err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+	if claim := received(waiting); claim.Run != nil {
+		t.Errorf("Expected no run for a claim ended by the shutdown, got %+v", claim.Run)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"job_processor/jobpro"
+	"os"
+	"time"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// defaultLeaderLeaseTTL is used when LEADER_LEASE_TTL is not set
+const defaultLeaderLeaseTTL = 15 * time.Second
+
+// leaderElectionFromEnv sets up leader election when several instances on one host run the same jobs.
+// LEADER_LOCK_FILE elects with a lock on a file. LEADER_LEASE_TTL sets how long a lease lasts,
+// INSTANCE_ID the Id of this instance (host name and pid by default). Without LEADER_LOCK_FILE, the instance always leads.
+func leaderElectionFromEnv(jobMgr *jobpro.DefaultJobManager) error {
+	lockFile := os.Getenv("LEADER_LOCK_FILE")
+	if lockFile == "" {
+		return nil
+	}
+	lease := jobpro.NewFileLease(lockFile)
+
+	ttl := defaultLeaderLeaseTTL
+	if val := os.Getenv("LEADER_LEASE_TTL"); val != "" {
+		var err error
+		if ttl, err = time.ParseDuration(val); err != nil || ttl < 3*time.Second {
+			return serr.F("LEADER_LEASE_TTL must be a duration of at least 3s, got %q", val)
+		}
+	}
+
+	instanceID := os.Getenv("INSTANCE_ID")
+	if instanceID == "" {
+		host, _ := os.Hostname()
+		instanceID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	jobMgr.SetLeaderElection(lease, instanceID, ttl)
+	return nil
+}
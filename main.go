@@ -13,13 +13,71 @@ import (
 	"time"
 
 	"github.com/rohanthewiz/logger"
+	"github.com/rohanthewiz/serr"
 )
 
 func main() {
+	// Subcommands (export, import) run against the store and exit
+	if len(os.Args) > 1 {
+		os.Exit(runSubcommand(os.Args[1:]))
+	}
+
 	done := make(chan struct{}) // done channel will signal when shutdown complete
 	shutdown.InitShutdownService(done)
 
-	jobMgr := jobpro.Init("jobs.ddb")
+	tenants, err := loadTenants()
+	if err != nil {
+		logger.LogErr(err, "Failed to load tenants config")
+		os.Exit(1)
+	}
+
+	// Each instance needs its own store file, DuckDB locks it against other processes, e.g. DB_FILE_PATH=/data/jobs-a.ddb
+	dbPath := os.Getenv("DB_FILE_PATH")
+	if dbPath == "" {
+		dbPath = defaultDBPath
+	}
+	jobMgr := jobpro.Init(dbPath)
+	jobMgr.ConfigureNamespaces(tenants.Namespaces...)
+
+	// Jobs running shell commands can only be created, imported and loaded by admins, once allowed
+	jobMgr.AllowCommandJobs(os.Getenv("ALLOW_COMMAND_JOBS") == "true")
+
+	// Delay scheduled runs of jobs without their own jitter or spread, e.g. JOB_SPREAD=5m
+	jitter, err := jitterFromEnv()
+	if err != nil {
+		logger.LogErr(err, "Invalid jitter settings")
+		os.Exit(1)
+	}
+	jobMgr.SetJitter(jitter)
+
+	// With several instances, only the elected leader schedules jobs
+	if err := leaderElectionFromEnv(jobMgr); err != nil {
+		logger.LogErr(err, "Invalid leader election settings")
+		os.Exit(1)
+	}
+
+	// Calendars must be in place before jobs referencing them are registered
+	if err := loadCalendars(jobMgr); err != nil {
+		logger.LogErr(err, "Failed to load calendars")
+		os.Exit(1)
+	}
+
+	// And so must pools
+	if err := loadPools(jobMgr); err != nil {
+		logger.LogErr(err, "Failed to load pools")
+		os.Exit(1)
+	}
+
+	if err := loadHooks(jobMgr); err != nil {
+		logger.LogErr(err, "Failed to load webhooks")
+		os.Exit(1)
+	}
+
+	// Watches wait for their jobs to be registered before starting runs
+	if err := loadWatches(jobMgr); err != nil {
+		logger.LogErr(err, "Failed to load watches")
+		os.Exit(1)
+	}
 
 	// Start PubSub so UI can receive SSE events
 	if err := pubsub.StartPubSub(); err != nil {
@@ -30,9 +88,13 @@ func main() {
 	if err := pubsub.ListenForUpdates(jobMgr.GetJobsUpdatedChan()); err != nil {
 		logger.LogErr(err, "Failed to setup listener for job updates")
 	}
+	// The progress of runs goes out on the same SSE channel, to update progress bars in place
+	if err := pubsub.ListenForUpdates(jobMgr.GetProgressChan()); err != nil {
+		logger.LogErr(err, "Failed to setup listener for run progress")
+	}
 
 	// Start the frontend
-	go web.StartWebServer(jobMgr)
+	go web.StartWebServer(jobMgr, tenants.Principals)
 
 	// Give the backend server a moment to start
 	logger.F("Giving the backend server a %s head start...", 10*time.Second)
@@ -74,6 +136,11 @@ func registerJobs(jobMgr *jobpro.DefaultJobManager, configs []jobpro.JobConfig)
 		os.Exit(1)
 	}
 
+	// Jobs created from the web UI are stored with their config
+	if err := jobMgr.LoadJobs(); err != nil {
+		logger.LogErr(err, "Failed to load stored jobs")
+	}
+
 	// Hardcoded jobConfigs
 	// jobpro.RegisterJob(jobpro.JobConfig{
 	// 	Id:          "periodicJob1",
@@ -105,3 +172,18 @@ func registerJobs(jobMgr *jobpro.DefaultJobManager, configs []jobpro.JobConfig)
 	// 	JobFunction: jobFunctions["manualJob"],
 	// })
 }
+
+// jitterFromEnv reads the default delay of scheduled runs from JOB_JITTER (maximum random delay)
+// and JOB_SPREAD (window to spread runs over by job Id), both durations such as "30s"
+func jitterFromEnv() (cfg jobpro.JitterConfig, err error) {
+	for envVar, d := range map[string]*time.Duration{"JOB_JITTER": &cfg.Jitter, "JOB_SPREAD": &cfg.Spread} {
+		val := os.Getenv(envVar)
+		if val == "" {
+			continue
+		}
+		if *d, err = time.ParseDuration(val); err != nil || *d < 0 {
+			return cfg, serr.F("%s must be a duration such as 30s, got %q", envVar, val)
+		}
+	}
+	return cfg, nil
+}
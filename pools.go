@@ -0,0 +1,27 @@
+package main
+
+import (
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// defaultPoolsPath is used when POOLS_CONFIG is not set
+const defaultPoolsPath = "pools.json"
+
+// loadPools configures the resource pools of the file given by POOLS_CONFIG, or pools.json.
+// Pools are not stored, the file declares them at each start.
+func loadPools(jobMgr *jobpro.DefaultJobManager) error {
+	var pools []jobpro.PoolConfig
+	if err := readConfigFile("POOLS_CONFIG", defaultPoolsPath, &pools); err != nil {
+		return err
+	}
+	if len(pools) == 0 {
+		return nil
+	}
+
+	if err := jobMgr.ConfigurePools(pools...); err != nil {
+		return serr.Wrap(err, "error configuring pools")
+	}
+	return nil
+}
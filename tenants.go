@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"job_processor/jobpro"
+	"job_processor/web"
+	"os"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// defaultTenantsPath is used when TENANTS_CONFIG is not set.
+// Keep it out of artifacts/config, which is served statically, as it holds API tokens.
+const defaultTenantsPath = "tenants.json"
+
+// tenantsConfig holds the namespace limits and the API principals
+type tenantsConfig struct {
+	Namespaces []jobpro.NamespaceConfig
+	Principals []web.Principal
+}
+
+// loadTenants reads the tenants config from the file given by TENANTS_CONFIG, or tenants.json.
+// A missing default file means a single tenant setup without authentication.
+func loadTenants() (cfg tenantsConfig, err error) {
+	if err = readConfigFile("TENANTS_CONFIG", defaultTenantsPath, &cfg); err != nil {
+		return cfg, err
+	}
+
+	for _, p := range cfg.Principals {
+		if p.Token == "" {
+			return cfg, serr.F("principal %q has no token", p.Name)
+		}
+		if !p.Admin && p.Namespace == "" {
+			return cfg, serr.F("principal %q needs a namespace or admin rights", p.Name)
+		}
+	}
+	return cfg, nil
+}
+
+// readConfigFile decodes the JSON file named by the environment variable, or the default path, into v.
+// A missing default file is not an error - v is left as is.
+func readConfigFile(envVar, defaultPath string, v any) error {
+	path := os.Getenv(envVar)
+	explicit := path != ""
+	if !explicit {
+		path = defaultPath
+	}
+
+	byts, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return serr.Wrap(err, "error reading config", "path", path)
+	}
+
+	if err = json.Unmarshal(byts, v); err != nil {
+		return serr.Wrap(err, "error parsing config", "path", path)
+	}
+	return nil
+}
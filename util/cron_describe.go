@@ -0,0 +1,420 @@
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cronField describes the values one field of a cron expression can take
+type cronField struct {
+	unit, units string // "second", "seconds"
+	min, max    int
+	names       map[string]int // accepted names, e.g. "JAN" or "MON"
+	label       func(int) string
+	span        string // how a range is put, e.g. "seconds %s past the minute"
+	from        string // where a step starts, e.g. "from second %s"
+}
+
+var (
+	secondField = cronField{unit: "second", units: "seconds", max: 59, label: strconv.Itoa,
+		span: "seconds %s past the minute", from: "from second %s"}
+	minuteField = cronField{unit: "minute", units: "minutes", max: 59, label: strconv.Itoa,
+		span: "minutes %s past the hour", from: "from minute %s"}
+	hourField = cronField{unit: "hour", units: "hours", max: 23, label: func(h int) string { return fmt.Sprintf("%d:00", h) },
+		span: "%s", from: "from %s"}
+	domField = cronField{unit: "day", units: "days", min: 1, max: 31, label: strconv.Itoa,
+		span: "days %s of the month", from: "from day %s of the month"}
+	monthField = cronField{unit: "month", units: "months", min: 1, max: 12,
+		names: map[string]int{"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+			"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12},
+		label: func(m int) string { return monthNames[m-1] }, span: "%s", from: "from %s"}
+	dowField = cronField{unit: "day of the week", units: "days of the week", max: 7,
+		names: map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6},
+		label: func(d int) string { return dayNames[d%7] }, span: "%s", from: "from %s"}
+)
+
+var monthNames = []string{"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+var dayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+// cronDescriptors are the predefined schedules
+var cronDescriptors = map[string]string{
+	"@yearly": "0 0 0 1 1 *", "@annually": "0 0 0 1 1 *", "@monthly": "0 0 0 1 * *",
+	"@weekly": "0 0 0 * * 0", "@daily": "0 0 0 * * *", "@midnight": "0 0 0 * * *", "@hourly": "0 0 * * * *",
+}
+
+// cronItem is one comma separated part of a field: a value, a range or "*", optionally with a step
+type cronItem struct {
+	start, end int
+	step       int // 0 without a step
+	all        bool
+}
+
+// single reports whether the item is a plain value
+func (c cronItem) single() bool { return !c.all && c.step == 0 && c.start == c.end }
+
+// parse parses a field into its items. "?" is the same as "*".
+func (f cronField) parse(expr string) ([]cronItem, error) {
+	var items []cronItem
+	for _, part := range strings.Split(expr, ",") {
+		base, stepStr, hasStep := strings.Cut(part, "/")
+		item := cronItem{}
+
+		switch {
+		case base == "*" || base == "?":
+			item = cronItem{start: f.min, end: f.max, all: true}
+		default:
+			from, to, isRange := strings.Cut(base, "-")
+			start, err := f.value(from)
+			if err != nil {
+				return nil, err
+			}
+			end := start
+			if isRange {
+				if end, err = f.value(to); err != nil {
+					return nil, err
+				}
+				if end < start {
+					return nil, fmt.Errorf("%s range %s is backwards", f.unit, base)
+				}
+			} else if hasStep {
+				end = f.max // "5/15" runs from 5 to the end of the range
+			}
+			item = cronItem{start: start, end: end}
+		}
+
+		if hasStep {
+			step, err := strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid %s step %q", f.unit, stepStr)
+			}
+			item.step = step
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// value parses a number or name in the field's range
+func (f cronField) value(s string) (int, error) {
+	if n, ok := f.names[strings.ToUpper(s)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.unit, s)
+	}
+	return n, nil
+}
+
+// values returns the plain values of the items, and whether every item is a plain value
+func values(items []cronItem) ([]int, bool) {
+	var vals []int
+	for _, it := range items {
+		if !it.single() {
+			return nil, false
+		}
+		vals = append(vals, it.start)
+	}
+	return vals, true
+}
+
+// isAll reports whether the field matches every value
+func isAll(items []cronItem) bool {
+	return len(items) == 1 && items[0].all && items[0].step == 0
+}
+
+// describe renders the items, e.g. "every 15 minutes", "Monday through Friday" or "January, April and July".
+// in wraps plain values, e.g. "in %s".
+func (f cronField) describe(items []cronItem, in string) string {
+	if vals, ok := values(items); ok {
+		labels := make([]string, len(vals))
+		for i, v := range vals {
+			labels[i] = f.label(v)
+		}
+		return fmt.Sprintf(in, joinAnd(labels))
+	}
+
+	var phrases []string
+	for _, it := range items {
+		var p string
+		switch {
+		case it.step > 0:
+			p = fmt.Sprintf("every %d %s", it.step, f.units)
+			if it.step == 1 {
+				p = "every " + f.unit
+			}
+			switch {
+			case it.all || it.start == f.min && it.end == f.max:
+			case it.end == f.max:
+				p += ", " + fmt.Sprintf(f.from, f.label(it.start))
+			default:
+				p += ", " + fmt.Sprintf(f.span, f.label(it.start)+" through "+f.label(it.end))
+			}
+		case it.start == it.end:
+			p = fmt.Sprintf(in, f.label(it.start))
+		default:
+			p = fmt.Sprintf(f.span, f.label(it.start)+" through "+f.label(it.end))
+		}
+		phrases = append(phrases, p)
+	}
+	return joinAnd(phrases)
+}
+
+// describeCron renders a 5 or 6 field cron expression (or a descriptor such as @daily) in English.
+// Besides lists, ranges, steps and names, the day fields may use L, W and # as in Quartz:
+// "L" (last day of the month), "15W" (nearest weekday to the 15th), "LW", "5L" (last Friday), "5#2" (2nd Friday).
+func describeCron(expr string) (string, error) {
+	if spec, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = spec
+	}
+	if every, ok := strings.CutPrefix(expr, "@every "); ok {
+		return "Every " + strings.TrimSpace(every), nil
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return "", fmt.Errorf("expected 5 or 6 fields, found %d", len(fields))
+	}
+
+	secs, err := secondField.parse(fields[0])
+	if err != nil {
+		return "", err
+	}
+	mins, err := minuteField.parse(fields[1])
+	if err != nil {
+		return "", err
+	}
+	hours, err := hourField.parse(fields[2])
+	if err != nil {
+		return "", err
+	}
+	dom, err := describeDom(fields[3])
+	if err != nil {
+		return "", err
+	}
+	months, err := monthField.parse(fields[4])
+	if err != nil {
+		return "", err
+	}
+	dow, weekdays, err := describeDow(fields[5])
+	if err != nil {
+		return "", err
+	}
+
+	month := ""
+	if !isAll(months) {
+		month = monthField.describe(months, "in %s")
+	}
+
+	// At fixed times of day, e.g. "Weekdays at 9:30", "Monthly on day 15 at 9:00"
+	if times, ok := clockTimes(secs, mins, hours); ok {
+		var desc string
+		switch {
+		case dom == "" && dow == "":
+			desc = "Daily at " + times
+		case dom == "" && slices.Equal(weekdays, []int{1, 2, 3, 4, 5}):
+			desc = "Weekdays at " + times
+		case dom == "" && slices.Equal(weekdays, []int{0, 6}):
+			desc = "Weekends at " + times
+		case dom == "" && len(weekdays) == 1:
+			desc = "Every " + dayNames[weekdays[0]] + " at " + times
+		case dow == "" && strings.HasPrefix(dom, "on day ") && month == "":
+			return "Monthly " + strings.TrimSuffix(dom, " of the month") + " at " + times, nil
+		default:
+			desc = "At " + times
+			for _, p := range []string{dom, dow} {
+				if p != "" {
+					desc += ", " + p
+				}
+			}
+		}
+		if month != "" {
+			desc += ", " + month
+		}
+		return desc, nil
+	}
+
+	parts := describeTimeOfDay(secs, mins, hours)
+	for _, p := range []string{dom, dow, month} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	desc := strings.Join(parts, ", ")
+	return strings.ToUpper(desc[:1]) + desc[1:], nil
+}
+
+// clockTimes renders fixed times of day, e.g. "9:30", "midnight" or "9:00 and 17:00"
+func clockTimes(secs, mins, hours []cronItem) (string, bool) {
+	s, ok1 := values(secs)
+	m, ok2 := values(mins)
+	h, ok3 := values(hours)
+	if !ok1 || !ok2 || !ok3 || len(s) != 1 || len(m) != 1 {
+		return "", false
+	}
+
+	times := make([]string, len(h))
+	for i, hour := range h {
+		times[i] = fmt.Sprintf("%d:%02d", hour, m[0])
+		if s[0] != 0 {
+			times[i] += fmt.Sprintf(":%02d", s[0])
+		}
+	}
+	if len(times) == 1 && s[0] == 0 && m[0] == 0 {
+		switch h[0] {
+		case 0:
+			return "midnight", true
+		case 12:
+			return "noon", true
+		}
+	}
+	return joinAnd(times), true
+}
+
+// describeTimeOfDay renders the time fields when they are not fixed times,
+// e.g. "every 15 minutes", "between 9:00 and 17:59"
+func describeTimeOfDay(secs, mins, hours []cronItem) []string {
+	var parts []string
+	secVals, secPlain := values(secs)
+	onTheMinute := secPlain && len(secVals) == 1 && secVals[0] == 0
+	minVals, minPlain := values(mins)
+
+	switch {
+	case isAll(secs):
+		parts = append(parts, "every second")
+	case !onTheMinute:
+		parts = append(parts, secondField.describe(secs, "at %s seconds past the minute"))
+	}
+
+	switch {
+	case isAll(mins):
+		if onTheMinute {
+			parts = append(parts, "every minute")
+		}
+	case onTheMinute && minPlain && len(minVals) == 1 && minVals[0] == 0 && isAll(hours):
+		return []string{"every hour, on the hour"}
+	case onTheMinute && minPlain && len(minVals) == 1 && minVals[0] == 0:
+		if len(hours) == 1 && hours[0].step > 0 {
+			// "0 0 */2 * * *"
+			return []string{hourField.describe(hours, "%s")}
+		}
+		return []string{"on the hour", hourField.describe(hours, "%s")}
+	default:
+		parts = append(parts, minuteField.describe(mins, "at %s minutes past the hour"))
+	}
+
+	if isAll(hours) {
+		return parts
+	}
+	if len(hours) == 1 && !hours[0].all && hours[0].step == 0 {
+		// A single hour or a range
+		return append(parts, fmt.Sprintf("between %d:00 and %d:59", hours[0].start, hours[0].end))
+	}
+	return append(parts, hourField.describe(hours, "during the %s hour"))
+}
+
+// describeDom renders the day of month field, with L and W, e.g. "on the last day of the month"
+func describeDom(expr string) (string, error) {
+	upper := strings.ToUpper(expr)
+	switch {
+	case upper == "*" || upper == "?":
+		return "", nil
+	case upper == "L":
+		return "on the last day of the month", nil
+	case upper == "LW":
+		return "on the last weekday of the month", nil
+	case strings.HasPrefix(upper, "L-"):
+		n, err := strconv.Atoi(upper[2:])
+		if err != nil || n < 1 || n > 30 {
+			return "", fmt.Errorf("invalid day %q", expr)
+		}
+		return fmt.Sprintf("%d days before the last day of the month", n), nil
+	case strings.HasSuffix(upper, "W"):
+		n, err := domField.value(upper[:len(upper)-1])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("on the weekday nearest day %d of the month", n), nil
+	}
+
+	items, err := domField.parse(expr)
+	if err != nil {
+		return "", err
+	}
+	if vals, ok := values(items); ok && len(vals) > 1 {
+		return domField.describe(items, "on days %s of the month"), nil
+	}
+	if len(items) == 1 && items[0].step == 0 && !items[0].all && items[0].start != items[0].end {
+		return fmt.Sprintf("between day %d and %d of the month", items[0].start, items[0].end), nil
+	}
+	return domField.describe(items, "on day %s of the month"), nil
+}
+
+// describeDow renders the day of week field, with L and #, e.g. "Monday through Friday" or
+// "on the last Friday of the month". It also returns the days of the week for a plain list or range.
+func describeDow(expr string) (string, []int, error) {
+	upper := strings.ToUpper(expr)
+	if upper == "*" || upper == "?" {
+		return "", nil, nil
+	}
+
+	if day, nth, ok := strings.Cut(upper, "#"); ok {
+		d, err := dowField.value(day)
+		n, err2 := strconv.Atoi(nth)
+		if err != nil || err2 != nil || n < 1 || n > 5 {
+			return "", nil, fmt.Errorf("invalid day of the week %q", expr)
+		}
+		return fmt.Sprintf("on the %s %s of the month", ordinalWord(n), dayNames[d%7]), nil, nil
+	}
+	if day, ok := strings.CutSuffix(upper, "L"); ok && day != "" {
+		d, err := dowField.value(day)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("on the last %s of the month", dayNames[d%7]), nil, nil
+	}
+
+	items, err := dowField.parse(expr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The days, with 7 as Sunday, when no steps are involved
+	var days []int
+	for _, it := range items {
+		if it.step > 0 || it.all {
+			days = nil
+			break
+		}
+		for d := it.start; d <= it.end; d++ {
+			days = append(days, d%7)
+		}
+	}
+	slices.Sort(days)
+	days = slices.Compact(days)
+
+	if isAll(items) || len(days) == 7 {
+		return "", nil, nil
+	}
+	return dowField.describe(items, "on %s"), days, nil
+}
+
+func ordinalWord(n int) string {
+	return []string{"", "first", "second", "third", "fourth", "fifth"}[n]
+}
+
+// joinAnd joins items as "a, b and c"
+func joinAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
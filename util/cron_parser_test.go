@@ -21,6 +21,7 @@ func TestParseCronToEnglish(t *testing.T) {
 		{"Daily at midnight", "0 0 0 * * *", "Daily at midnight"},
 		{"Daily at noon", "0 0 12 * * *", "Daily at noon"},
 		{"Weekdays at 9:30", "0 30 9 * * 1-5", "Weekdays at 9:30"},
+		{"With timezone", "CRON_TZ=America/New_York 0 30 9 * * 1-5", "Weekdays at 9:30 (America/New_York)"},
 
 		// 5-field (without seconds) tests
 		{"Every minute (5-field)", "* * * * *", "Every minute"},
@@ -39,6 +40,76 @@ func TestParseCronToEnglish(t *testing.T) {
 	}
 }
 
+func TestParseCronToEnglish_Fields(t *testing.T) {
+	tests := []struct {
+		cronExpr string
+		want     string
+	}{
+		// Steps
+		{"*/14 * * * JAN MON-FRI", "Every 14 seconds, Monday through Friday, in January"},
+		{"*/14 * * * * *", "Every 14 seconds"},
+		{"0 0/20 * * * *", "Every 20 minutes"},
+		{"0 5/20 * * * *", "Every 20 minutes, from minute 5"},
+		{"0 0 */2 * * *", "Every 2 hours"},
+		{"0 0 9-17/2 * * *", "Every 2 hours, 9:00 through 17:00"},
+		{"0 0 6 */2 * *", "At 6:00, every 2 days"},
+		{"0 0 9 ? */3 *", "Daily at 9:00, every 3 months"},
+		{"0 0 0 * * 1-5/2", "At midnight, every 2 days of the week, Monday through Friday"},
+
+		// Ranges and lists
+		{"5-10 * * * * *", "Seconds 5 through 10 past the minute"},
+		{"1,2,3 * * * * *", "At 1, 2 and 3 seconds past the minute"},
+		{"0 */5 9-17 * * *", "Every 5 minutes, between 9:00 and 17:59"},
+		{"0 * 9 * * *", "Every minute, between 9:00 and 9:59"},
+		{"0 0 1-5,10 * * *", "On the hour, 1:00 through 5:00 and 10:00"},
+		{"0 0 9,17 * * *", "Daily at 9:00 and 17:00"},
+		{"0 0 6 1,15 * *", "At 6:00, on days 1 and 15 of the month"},
+		{"0 0 6 1-7 * *", "At 6:00, between day 1 and 7 of the month"},
+
+		// Fixed times
+		{"30 * * * * *", "At 30 seconds past the minute"},
+		{"0 15 * * * *", "At 15 minutes past the hour"},
+		{"0 5 9 * * *", "Daily at 9:05"},
+		{"15 5 9 * * *", "Daily at 9:05:15"},
+		{"0 0 9 15 * *", "Monthly on day 15 at 9:00"},
+		{"0 0 9 15 6 *", "At 9:00, on day 15 of the month, in June"},
+
+		// Names
+		{"0 30 9 * * MON,WED,FRI", "At 9:30, on Monday, Wednesday and Friday"},
+		{"0 0 9 * JAN-MAR SUN", "Every Sunday at 9:00, January through March"},
+		{"0 0 12 * * 6,0", "Weekends at noon"},
+		{"0 0 9 * * 0-7", "Daily at 9:00"},
+		{"0 9 * * mon-fri", "Weekdays at 9:00"},
+
+		// ? and L, W, #
+		{"0 0 8 L * ?", "At 8:00, on the last day of the month"},
+		{"0 0 8 LW * *", "At 8:00, on the last weekday of the month"},
+		{"0 0 8 15W * *", "At 8:00, on the weekday nearest day 15 of the month"},
+		{"0 0 9 L-3 * *", "At 9:00, 3 days before the last day of the month"},
+		{"0 0 8 ? * 5L", "At 8:00, on the last Friday of the month"},
+		{"0 0 8 ? * FRI#2", "At 8:00, on the second Friday of the month"},
+
+		// Descriptors
+		{"@daily", "Daily at midnight"},
+		{"@hourly", "Every hour, on the hour"},
+		{"@every 1h30m", "Every 1h30m"},
+
+		// Invalid
+		{"bad", "Invalid cron: expected 5 or 6 fields, found 1"},
+		{"0 0 25 * * *", `Invalid cron: invalid hour "25"`},
+		{"0 0 9 * * 5-1", "Invalid cron: day of the week range 5-1 is backwards"},
+		{"*/0 * * * * *", `Invalid cron: invalid second step "0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cronExpr, func(t *testing.T) {
+			if got := ParseCronToEnglish(tt.cronExpr); got != tt.want {
+				t.Errorf("ParseCronToEnglish(%q) = %q, want %q", tt.cronExpr, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFormatDurationUntil(t *testing.T) {
 	// Test past time
 	pastTime := time.Now().Add(-1 * time.Hour)
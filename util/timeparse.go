@@ -20,6 +20,14 @@ var supportedFormats = []string{
 	// time.RFC3339Nano,              // 2006-01-02T15:04:05.999999999Z07:00
 }
 
+// layoutsNoTZ are formats without timezone info that we'll try with locations
+var layoutsNoTZ = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"01/02/2006 3:04 PM",
+	"Jan 2, 2006 3:04 PM",
+}
+
 // ParseSchedule parses a schedule string which can be:
 // - An absolute time in various formats (with optional timezone)
 // - A relative time (e.g., "in 30m", "+1h", "5m")
@@ -44,6 +52,22 @@ func ParseSchedule(scheduleStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unsupported time format: %s", scheduleStr)
 }
 
+// ParseScheduleIn is ParseSchedule, but absolute times without a timezone,
+// e.g. "2024-01-15 14:30:00", are also accepted and taken to be in loc
+func ParseScheduleIn(scheduleStr string, loc *time.Location) (time.Time, error) {
+	t, err := ParseSchedule(scheduleStr)
+	if err == nil || loc == nil {
+		return t, err
+	}
+
+	for _, layout := range layoutsNoTZ {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(scheduleStr), loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format: %s", scheduleStr)
+}
+
 // parseRelativeTime attempts to parse relative time expressions
 func parseRelativeTime(s string) (time.Time, bool) {
 	s = strings.TrimSpace(strings.ToLower(s))
@@ -74,14 +98,6 @@ func parseRelativeTime(s string) (time.Time, bool) {
 
 // parseWithLocation attempts to parse time strings with location/timezone names
 func parseWithLocation(scheduleStr string) (time.Time, error) {
-	// Formats without timezone info that we'll try with locations
-	layoutsNoTZ := []string{
-		"2006-01-02 15:04:05",
-		"2006-01-02T15:04:05",
-		"01/02/2006 3:04 PM",
-		"Jan 2, 2006 3:04 PM",
-	}
-
 	// Common timezone names to try
 	timezones := []string{
 		"America/New_York",
@@ -126,3 +142,17 @@ func parseWithLocation(scheduleStr string) (time.Time, error) {
 
 	return time.Time{}, fmt.Errorf("could not parse with location")
 }
+
+// ParseDateOrTime parses a date ("2006-01-02", taken as UTC midnight) or any time accepted
+// by ParseSchedule, including relative times such as "-24h" for range bounds.
+// An empty string returns the zero time.
+func ParseDateOrTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return ParseSchedule(s)
+}
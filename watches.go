@@ -0,0 +1,27 @@
+package main
+
+import (
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/serr"
+)
+
+// defaultWatchesPath is used when WATCHES_CONFIG is not set
+const defaultWatchesPath = "watches.json"
+
+// loadWatches starts the directory watches of the file given by WATCHES_CONFIG, or watches.json.
+// Watches are not stored, the file declares them at each start. The files they picked up are.
+func loadWatches(jobMgr *jobpro.DefaultJobManager) error {
+	var watches []jobpro.WatchConfig
+	if err := readConfigFile("WATCHES_CONFIG", defaultWatchesPath, &watches); err != nil {
+		return err
+	}
+	if len(watches) == 0 {
+		return nil
+	}
+
+	if err := jobMgr.ConfigureWatches(watches...); err != nil {
+		return serr.Wrap(err, "error configuring watches")
+	}
+	return nil
+}
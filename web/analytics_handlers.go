@@ -0,0 +1,200 @@
+package web
+
+import (
+	"fmt"
+	"job_processor/jobpro"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rohanthewiz/logger"
+	"github.com/rohanthewiz/rweb"
+)
+
+const defaultAnalyticsWindow = 7 * 24 * time.Hour // matches result retention
+
+// registerAnalyticsRoutes adds the JSON analytics endpoints
+// Each endpoint accepts an optional "window" query param (e.g. "24h", "7d")
+// The ":job-id" variants restrict the results to one job
+func registerAnalyticsRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	percentiles := func(ctx rweb.Context) error {
+		window, err := windowParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		stats, err := managerFor(ctx, jobMgr).GetDurationStats(ctx.Request().Param("job-id"), window)
+		if err != nil {
+			return serverError(ctx, err, "Failed to get duration stats")
+		}
+		return ctx.WriteJSON(stats)
+	}
+	s.Get("/api/v1/analytics/percentiles", percentiles)
+	s.Get("/api/v1/analytics/jobs/:job-id/percentiles", percentiles)
+
+	// Success rate by hour or day - "bucket" query param is "hour" (default) or "day"
+	successRate := func(ctx rweb.Context) error {
+		window, err := windowParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		bucket := jobpro.TimeBucket(ctx.Request().QueryParam("bucket"))
+		if bucket == "" {
+			bucket = jobpro.BucketHour
+		}
+		if err := bucket.Validate(); err != nil {
+			return badRequest(ctx, err)
+		}
+		points, err := managerFor(ctx, jobMgr).GetSuccessRateSeries(ctx.Request().Param("job-id"), bucket, window)
+		if err != nil {
+			return serverError(ctx, err, "Failed to get success rate series")
+		}
+		return ctx.WriteJSON(points)
+	}
+	s.Get("/api/v1/analytics/success-rate", successRate)
+	s.Get("/api/v1/analytics/jobs/:job-id/success-rate", successRate)
+
+	runsPerDay := func(ctx rweb.Context) error {
+		window, err := windowParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		points, err := managerFor(ctx, jobMgr).GetSuccessRateSeries(ctx.Request().Param("job-id"), jobpro.BucketDay, window)
+		if err != nil {
+			return serverError(ctx, err, "Failed to get runs per day")
+		}
+
+		type dayCount struct {
+			Day  string
+			Runs int
+		}
+		days := make([]dayCount, 0, len(points))
+		for _, p := range points {
+			days = append(days, dayCount{Day: p.Bucket.Format("2006-01-02"), Runs: p.Runs})
+		}
+		return ctx.WriteJSON(days)
+	}
+	s.Get("/api/v1/analytics/runs-per-day", runsPerDay)
+	s.Get("/api/v1/analytics/jobs/:job-id/runs-per-day", runsPerDay)
+
+	// Top recurring errors - "limit" query param defaults to 10
+	topErrors := func(ctx rweb.Context) error {
+		window, err := windowParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		limit := intQueryParam(ctx, "limit", 10)
+		clusters, err := managerFor(ctx, jobMgr).GetTopErrors(ctx.Request().Param("job-id"), window, limit)
+		if err != nil {
+			return serverError(ctx, err, "Failed to get top errors")
+		}
+		return ctx.WriteJSON(clusters)
+	}
+	s.Get("/api/v1/analytics/errors", topErrors)
+	s.Get("/api/v1/analytics/jobs/:job-id/errors", topErrors)
+
+	// Summary used by the jobs table charts - "recent" query param is the number of runs to include
+	s.Get("/api/v1/analytics/jobs/:job-id/summary", func(ctx rweb.Context) error {
+		jobID := ctx.Request().Param("job-id")
+		window, err := windowParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		summary, err := managerFor(ctx, jobMgr).GetJobSummary(jobID, window, intQueryParam(ctx, "recent", 20))
+		if err != nil {
+			return serverError(ctx, err, "Failed to get job summary", "jobID", jobID)
+		}
+		return ctx.WriteJSON(summary)
+	})
+
+	// Structured output per run - "key" query param selects the output value to chart.
+	// Without a key, "records" is used if recorded, otherwise the first recorded key.
+	s.Get("/api/v1/analytics/jobs/:job-id/output", func(ctx rweb.Context) error {
+		jobID := ctx.Request().Param("job-id")
+		window, err := windowParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		keys, err := managerFor(ctx, jobMgr).GetOutputKeys(jobID, window)
+		if err != nil {
+			return serverError(ctx, err, "Failed to get output keys", "jobID", jobID)
+		}
+
+		key := ctx.Request().QueryParam("key")
+		if key == "" && len(keys) > 0 {
+			key = keys[0]
+			if slices.Contains(keys, jobpro.OutputRecords) {
+				key = jobpro.OutputRecords
+			}
+		}
+
+		points := []jobpro.OutputPoint{}
+		if key != "" {
+			points, err = managerFor(ctx, jobMgr).GetOutputSeries(jobID, key, window)
+			if err != nil {
+				return serverError(ctx, err, "Failed to get output series", "jobID", jobID, "key", key)
+			}
+		}
+
+		return ctx.WriteJSON(map[string]any{
+			"Keys":   keys,
+			"Key":    key,
+			"Points": points,
+		})
+	})
+}
+
+// windowParam reads the "window" query param
+// It accepts Go durations ("36h") and a day suffix ("7d")
+func windowParam(ctx rweb.Context) (time.Duration, error) {
+	return parseWindow(ctx.Request().QueryParam("window"), defaultAnalyticsWindow)
+}
+
+// parseWindow parses a lookback window, returning def if s is empty
+func parseWindow(s string, def time.Duration) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, errInvalidParam("window", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, errInvalidParam("window", s)
+	}
+	return d, nil
+}
+
+// intQueryParam reads a positive integer query param, returning def if missing or invalid
+func intQueryParam(ctx rweb.Context, name string, def int) int {
+	if val, err := strconv.Atoi(ctx.Request().QueryParam(name)); err == nil && val > 0 {
+		return val
+	}
+	return def
+}
+
+func errInvalidParam(name, value string) error {
+	return fmt.Errorf("invalid %s: %q", name, value)
+}
+
+// badRequest writes a 400 JSON error
+func badRequest(ctx rweb.Context, err error) error {
+	ctx.Status(400)
+	return ctx.WriteJSON(map[string]string{
+		"error": err.Error(),
+	})
+}
+
+// serverError logs err and writes a 500 JSON error
+func serverError(ctx rweb.Context, err error, msg string, keyVals ...string) error {
+	logger.LogErr(err, append([]string{msg}, keyVals...)...)
+	ctx.Status(500)
+	return ctx.WriteJSON(map[string]string{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,77 @@
+	// Open the edit dialog filled in with the job's current settings
+	function openJobEdit(jobID) {
+		const dialog = document.getElementById('job-edit-dialog');
+		const form = dialog.querySelector('form');
+		fetch('/api/v1/jobs/' + encodeURIComponent(jobID) + '/settings')
+			.then(response => response.json().then(data => ({ ok: response.ok, data })))
+			.then(({ ok, data }) => {
+				if (!ok) {
+					alert('Cannot edit job: ' + (data.error || 'unknown error'));
+					return;
+				}
+				const values = jobEditValues(data);
+				form.elements.jobID.value = jobID;
+				Object.entries(values).forEach(([name, value]) => { form.elements[name].value = value; });
+				form.dataset.original = JSON.stringify(values);
+				form.querySelector('.job-edit-error').textContent = '';
+				dialog.showModal();
+			})
+			.catch(error => console.error('Error loading job settings:', error));
+	}
+
+	// Send the settings changed in the dialog - unchanged ones keep following the job's config
+	function saveJobEdit(form) {
+		const original = JSON.parse(form.dataset.original || '{}');
+		const settings = {};
+		Object.keys(original).forEach(name => {
+			const value = form.elements[name].value.trim();
+			if (value === original[name]) {
+				return;
+			}
+			switch (name) {
+			case 'MaxRunTime': case 'Jitter': case 'Spread':
+				settings[name] = parseInt(value || '0', 10);
+				break;
+			case 'Tags':
+				settings.Tags = Object.fromEntries(value.split(',').map(t => t.trim()).filter(t => t)
+					.map(t => { const i = t.indexOf('='); return i < 0 ? [t, ''] : [t.slice(0, i).trim(), t.slice(i + 1).trim()]; }));
+				break;
+			case 'Calendars':
+				settings.Calendars = value.split(',').map(c => c.trim()).filter(c => c);
+				break;
+			default:
+				settings[name] = value;
+			}
+		});
+
+		const error = form.querySelector('.job-edit-error');
+		fetch('/api/v1/jobs/' + encodeURIComponent(form.elements.jobID.value) + '/settings', {
+			method: 'PUT', headers: {'Content-Type': 'application/json'}, body: JSON.stringify(settings)
+		})
+			.then(response => response.json().then(data => ({ ok: response.ok, data })))
+			.then(({ ok, data }) => {
+				if (!ok) {
+					error.textContent = data.error || 'Update failed';
+					return;
+				}
+				console.log('Job updated:', data);
+				form.closest('dialog').close();
+			})
+			.catch(err => { error.textContent = 'Update failed: ' + err.message; });
+	}
+
+	// The form values of job settings
+	function jobEditValues(s) {
+		return {
+			Name: s.Name || '',
+			Schedule: s.Schedule || '',
+			Timezone: s.Timezone || '',
+			MaxRunTime: String(s.MaxRunTime || 0),
+			TriggerEndpoint: s.TriggerEndpoint || '',
+			Tags: Object.entries(s.Tags || {}).sort().map(([k, v]) => v ? k + '=' + v : k).join(','),
+			Calendars: (s.Calendars || []).join(','),
+			CalendarPolicy: s.CalendarPolicy || '',
+			Jitter: String(s.Jitter || 0),
+			Spread: String(s.Spread || 0),
+		};
+	}
@@ -0,0 +1,87 @@
+	let newJobPreviewTimer;
+
+	// Show the endpoint or command input of the selected job type
+	function newJobTypeChanged(form) {
+		const command = form.elements.Type.value === 'command';
+		form.elements.Command.hidden = !command;
+		form.elements.TriggerEndpoint.hidden = command;
+	}
+
+	// Validate and describe the schedule as it is typed, blocking submission while it is invalid
+	function newJobScheduleChanged(form) {
+		clearTimeout(newJobPreviewTimer);
+		newJobPreviewTimer = setTimeout(() => {
+			const preview = form.querySelector('.new-job-preview');
+			const schedule = form.elements.Schedule;
+			schedule.setCustomValidity('');
+			preview.replaceChildren();
+			if (!schedule.value.trim()) {
+				if (form.elements.IsPeriodic.value === 'true') {
+					schedule.setCustomValidity('Periodic jobs need a schedule');
+				}
+				return;
+			}
+
+			const params = new URLSearchParams({ schedule: schedule.value, timezone: form.elements.Timezone.value, count: 3 });
+			fetch('/api/v1/schedules/preview?' + params.toString())
+				.then(response => response.json().then(data => ({ ok: response.ok, data })))
+				.then(({ ok, data }) => {
+					if (!ok) {
+						schedule.setCustomValidity(data.error || 'Invalid schedule');
+						preview.appendChild(previewLine('preview-error', data.error || 'Invalid schedule'));
+						return;
+					}
+					if (data.Periodic !== (form.elements.IsPeriodic.value === 'true')) {
+						schedule.setCustomValidity(data.Periodic ? 'This is a periodic schedule' : 'This is a one-time schedule');
+						preview.appendChild(previewLine('preview-error', schedule.validationMessage));
+						return;
+					}
+					const runs = (data.Runs || []).map(run => formatPreviewTime(run.Time, data.Timezone)).join('; ');
+					preview.appendChild(previewLine('preview-description', data.Description + (runs ? ' - next: ' + runs : '')));
+					(data.Warnings || []).forEach(warning => preview.appendChild(previewLine('preview-warning', '⚠ ' + warning)));
+				})
+				.catch(error => console.error('Error previewing schedule:', error));
+		}, 300);
+	}
+
+	// Create the job - the table refreshes on the job-update event
+	function createJob(form) {
+		const f = form.elements;
+		const job = {
+			Id: f.Id.value.trim(),
+			Name: f.Name.value.trim(),
+			IsPeriodic: f.IsPeriodic.value === 'true',
+			Schedule: f.Schedule.value.trim(),
+			Timezone: f.Timezone.value.trim(),
+			MaxRunTime: parseInt(f.MaxRunTime.value || '0', 10),
+			RetryCount: parseInt(f.RetryCount.value || '0', 10),
+			AutoStart: f.AutoStart.checked,
+			Tags: Object.fromEntries(f.Tags.value.split(',').map(t => t.trim()).filter(t => t)
+				.map(t => { const i = t.indexOf('='); return i < 0 ? [t, ''] : [t.slice(0, i).trim(), t.slice(i + 1).trim()]; })),
+		};
+		if (f.Type.value === 'command') {
+			job.Command = f.Command.value.trim();
+		} else {
+			job.TriggerEndpoint = f.TriggerEndpoint.value.trim();
+		}
+
+		const status = form.querySelector('.new-job-status');
+		fetch('/api/v1/jobs', { method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify(job) })
+			.then(response => response.json().then(data => ({ ok: response.ok, data })))
+			.then(({ ok, data }) => {
+				if (!ok) {
+					status.className = 'new-job-status preview-error';
+					status.textContent = data.error || 'Failed to create job';
+					return;
+				}
+				status.className = 'new-job-status';
+				status.textContent = 'Created job ' + data.jobID;
+				form.reset();
+				newJobTypeChanged(form);
+				form.querySelector('.new-job-preview').replaceChildren();
+			})
+			.catch(error => {
+				status.className = 'new-job-status preview-error';
+				status.textContent = 'Failed to create job: ' + error.message;
+			});
+	}
@@ -11,12 +11,13 @@
 // Get success rate container
 									const successRateContainer = document.querySelector('#success-rate-' + jobID);
 
-									fetch('/jobs/history/' + jobID)
+									fetch('/api/v1/analytics/jobs/' + jobID + '/summary?recent=20')
 										.then(response => response.json())
-										.then(data => {
+										.then(summary => {
+											const data = summary ? summary.RecentRuns : summary;
 											// Check if the response is an error object
-											if (data && data.error) {
-												console.error('Error from server:', data.error);
+											if (summary && summary.error) {
+												console.error('Error from server:', summary.error);
 												if (successRateContainer) {
 													successRateContainer.innerHTML = '<span style="color: #ef4444;">Error</span>';
 												}
@@ -42,19 +43,18 @@
 											// Sort data by time (newest first)
 											data.sort((a, b) => new Date(b.StartTime) - new Date(a.StartTime));
 
-											// Take only the last 20 runs for the chart
+											// Chart the recent runs oldest to newest
 											const chartData = data.slice(0, 20).reverse();
 
-											// Calculate success rate
-											const successCount = data.filter(d => d.Status === 'complete').length;
-											const successRate = Math.round((successCount / data.length) * 100);
+											// Success rate is computed server side over the whole analytics window
+											const successRate = Math.round(summary.SuccessRate);
 
 											// Update success rate display
 											if (successRateContainer) {
 												const rateColor = successRate >= 80 ? '#22c55e' : successRate >= 50 ? '#f59e0b' : '#ef4444';
 												successRateContainer.innerHTML =
 													'<div style="font-weight: 600; color: ' + rateColor + ';">' + successRate + '%</div>' +
-													'<div style="font-size: 0.7rem; color: #999;">success</div>';
+													'<div style="font-size: 0.7rem; color: #999;" title="' + summary.Runs + ' runs in ' + summary.Window + '">success</div>';
 											}
 
 											// Prepare data for Chart.js
@@ -69,10 +69,24 @@
 
 											const durations = chartData.map(d => d.Duration / 1000000); // Convert to milliseconds
 
+											// Structured output - chart "records" if present, otherwise the first numeric key
+											let outputKey = null;
+											for (const d of chartData) {
+												if (!d.Output) continue;
+												if (typeof d.Output.records === 'number') { outputKey = 'records'; break; }
+												if (!outputKey) outputKey = Object.keys(d.Output).find(k => typeof d.Output[k] === 'number') || null;
+											}
+											const outputValues = outputKey ? chartData.map(d =>
+												d.Output && typeof d.Output[outputKey] === 'number' ? d.Output[outputKey] : null) : [];
+
 											// Create gradient colors based on status
+											// Only failed and timed out runs are failures - skipped, cancelled and interrupted ones are grey
+											const isFailure = d => d.Status === 'failed' || d.Status === 'timed_out';
 											const colors = chartData.map(d => {
 												if (d.Status === 'complete') {
 													return 'rgba(34, 197, 94, 0.8)'; // Green for success
+												} else if (!isFailure(d)) {
+													return 'rgba(148, 163, 184, 0.8)'; // Grey for runs without an outcome
 												} else {
 													return 'rgba(239, 68, 68, 0.8)'; // Red for failure
 												}
@@ -82,6 +96,8 @@
 											const pointColors = chartData.map(d => {
 												if (d.Status === 'complete') {
 													return 'rgb(34, 197, 94)'; // Green
+												} else if (!isFailure(d)) {
+													return 'rgb(148, 163, 184)'; // Grey
 												} else {
 													return 'rgb(239, 68, 68)'; // Red
 												}
@@ -95,7 +111,7 @@
 
 											// Determine overall color based on recent failures
 											const recentRuns = chartData.slice(-5); // Last 5 runs
-											const recentFailures = recentRuns.filter(d => d.Status !== 'complete').length;
+											const recentFailures = recentRuns.filter(isFailure).length;
 
 											if (recentFailures > 2) {
 												// Mostly failures - red gradient
@@ -146,7 +162,17 @@
 																return 'rgba(34, 197, 94, 0.8)'; // Green for success segments
 															}
 														}
-													}]
+													}].concat(outputKey ? [{
+														data: outputValues,
+														yAxisID: 'y1',
+														borderColor: 'rgba(99, 102, 241, 0.8)',
+														borderDash: [4, 3],
+														borderWidth: 1.5,
+														pointRadius: 2,
+														tension: 0.3,
+														fill: false,
+														spanGaps: true
+													}] : [])
 												},
 												options: {
 													responsive: true,
@@ -167,6 +193,9 @@
 															borderWidth: 1,
 															padding: 8,
 															displayColors: false,
+															filter: function(item) {
+																return item.datasetIndex === 0; // output is listed in the duration label
+															},
 															callbacks: {
 																title: function(context) {
 																	const index = context[0].dataIndex;
@@ -176,10 +205,14 @@
 																label: function(context) {
 																	const index = context.dataIndex;
 																	const run = chartData[index];
-																	return [
+																	const lines = [
 																		'Duration: ' + context.parsed.y.toFixed(1) + ' ms',
 																		'Status: ' + run.Status + (run.Status !== 'complete' ? ' ❌' : ' ✅')
 																	];
+																	if (outputKey && outputValues[index] !== null) {
+																		lines.push(outputKey + ': ' + outputValues[index].toLocaleString());
+																	}
+																	return lines;
 																},
 																labelTextColor: function(context) {
 																	const index = context.dataIndex;
@@ -196,6 +229,11 @@
 														y: {
 															display: false,
 															beginAtZero: true
+														},
+														y1: {
+															display: false,
+															beginAtZero: true,
+															position: 'right'
 														}
 													}
 												}
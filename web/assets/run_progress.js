@@ -0,0 +1,44 @@
+// Progress of runs arrives on the job update SSE channel, prefixed with "progress ".
+// It updates the progress bars in place, and is kept from the table body, which would fetch its rows again.
+(function() {
+    'use strict';
+
+    const prefix = 'progress ';
+
+    // The label of a progress: step, message and whether the run looks stuck
+    function progressLabel(p) {
+        const parts = [];
+        if (p.Percent >= 0) parts.push(Math.round(p.Percent) + '%');
+        if (p.Step) parts.push(p.Step);
+        if (p.Message) parts.push(p.Message);
+        if (p.Stuck) parts.push('stuck: no heartbeat since ' +
+            (p.LastHeartbeat && !p.LastHeartbeat.startsWith('0001') ? new Date(p.LastHeartbeat).toLocaleTimeString() : 'it started'));
+        return parts.join(' · ');
+    }
+
+    function updateProgress(el, p) {
+        if (p.Done) {
+            el.style.display = 'none';
+            return;
+        }
+        el.style.display = '';
+        el.classList.toggle('run-progress-unknown', p.Percent < 0);
+        el.classList.toggle('run-progress-stuck', p.Stuck);
+        el.querySelector('.run-progress-fill').style.width = Math.max(p.Percent, 0) + '%';
+        const label = el.querySelector('.run-progress-label');
+        label.textContent = progressLabel(p);
+        label.title = label.textContent;
+    }
+
+    document.addEventListener('sse:job-update', function(evt) {
+        const data = evt.detail && evt.detail.data;
+        if (typeof data !== 'string' || !data.startsWith(prefix)) {
+            return;
+        }
+        evt.stopPropagation();
+
+        const p = JSON.parse(data.slice(prefix.length));
+        const el = document.querySelector('.run-progress[data-job-id="' + CSS.escape(p.JobID) + '"]');
+        if (el) updateProgress(el, p);
+    }, true); // capturing, to get the event before the table body
+})();
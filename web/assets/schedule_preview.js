@@ -0,0 +1,61 @@
+	// Fetch and show the next runs of the schedule in the preview form
+	function previewSchedule(form) {
+		const result = form.parentElement.querySelector('.schedule-preview-result');
+		const params = new URLSearchParams(new FormData(form));
+
+		fetch('/api/v1/schedules/preview?' + params.toString())
+			.then(response => response.json().then(data => ({ ok: response.ok, data })))
+			.then(({ ok, data }) => {
+				result.replaceChildren();
+				if (!ok) {
+					result.appendChild(previewLine('preview-error', data.error || 'Invalid schedule'));
+					return;
+				}
+
+				result.appendChild(previewLine('preview-description', data.Description));
+				(data.Warnings || []).forEach(warning => {
+					result.appendChild(previewLine('preview-warning', '⚠ ' + warning));
+				});
+
+				const list = document.createElement('ol');
+				list.className = 'preview-runs';
+				(data.Runs || []).forEach(run => {
+					let text = formatPreviewTime(run.Time, data.Timezone);
+					if (run.Blackout) {
+						text += ' - excluded by calendar ' + run.Blackout.Calendar +
+							(run.Blackout.Reason ? ' (' + run.Blackout.Reason + ')' : '');
+						if (run.DeferredTo) {
+							text += ', deferred to ' + formatPreviewTime(run.DeferredTo, data.Timezone);
+						}
+					}
+					const item = document.createElement('li');
+					item.textContent = text;
+					if (run.Blackout) {
+						item.className = 'preview-excluded';
+					}
+					list.appendChild(item);
+				});
+				result.appendChild(list);
+			})
+			.catch(error => {
+				result.replaceChildren(previewLine('preview-error', 'Preview failed: ' + error.message));
+			});
+	}
+
+	function previewLine(className, text) {
+		const div = document.createElement('div');
+		div.className = className;
+		div.textContent = text;
+		return div;
+	}
+
+	// Show a run in the schedule's timezone (or the browser's) and in UTC
+	function formatPreviewTime(iso, timezone) {
+		const t = new Date(iso);
+		const opts = { weekday: 'short', year: 'numeric', month: 'short', day: 'numeric',
+			hour: '2-digit', minute: '2-digit', second: '2-digit', timeZoneName: 'short' };
+		if (timezone) {
+			opts.timeZone = timezone;
+		}
+		return t.toLocaleString(undefined, opts) + '  (' + t.toISOString().replace('.000Z', 'Z') + ')';
+	}
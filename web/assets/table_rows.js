@@ -72,3 +72,76 @@
 				button.disabled = true;
 			});
 	}
+
+	// Apply a bulk action (pause, resume, stop, run-now) to all jobs matching the active tag filter
+	function bulkAction(button) {
+		const action = button.getAttribute('data-action');
+		const selector = button.getAttribute('data-selector');
+		if (!confirm('Apply "' + action + '" to all jobs matching ' + selector + '?')) {
+			return;
+		}
+
+		fetch('/jobs/bulk/' + action + '?selector=' + encodeURIComponent(selector), {method: 'POST'})
+			.then(response => response.json())
+			.then(data => {
+				if (data.error) {
+					throw new Error(data.error);
+				}
+				const failed = data.results.filter(r => r.Error);
+				console.log('Bulk ' + action + ':', data);
+				if (failed.length > 0) {
+					alert(action + ' failed for ' + failed.length + ' of ' + data.results.length + ' jobs:\n' +
+						failed.map(r => r.JobID + ': ' + r.Error).join('\n'));
+				}
+			})
+			.catch(error => {
+				console.error('Error applying bulk action:', error);
+				alert('Bulk ' + action + ' failed: ' + error.message);
+			});
+	}
+
+	// Cancel one run in progress - the job stays scheduled, the table refreshes once the run is recorded
+	function cancelRun(button) {
+		const runId = button.getAttribute('data-run-id');
+		if (!confirm('Cancel this run?')) {
+			return;
+		}
+
+		button.disabled = true;
+		fetch('/api/v1/runs/' + encodeURIComponent(runId) + '/cancel', {method: 'POST'})
+			.then(response => response.json())
+			.then(data => {
+				if (data.error) {
+					throw new Error(data.error);
+				}
+				console.log('Run cancelled:', data);
+			})
+			.catch(error => {
+				console.error('Error cancelling run:', error);
+				alert('Cancel failed: ' + error.message);
+				button.disabled = false;
+			});
+	}
+
+	// Hide or show the runs with a status - the hidden statuses are kept as classes of the body
+	function toggleStatusFilter(chip) {
+		const status = chip.getAttribute('data-status');
+		const hidden = JSON.parse(localStorage.getItem('hiddenStatuses') || '[]').filter(s => s !== status);
+		if (!document.body.classList.contains('hide-run-' + status)) {
+			hidden.push(status);
+		}
+		localStorage.setItem('hiddenStatuses', JSON.stringify(hidden));
+		applyStatusFilter();
+	}
+
+	function applyStatusFilter() {
+		const hidden = JSON.parse(localStorage.getItem('hiddenStatuses') || '[]');
+		document.querySelectorAll('.status-filter-chip').forEach(chip => {
+			const status = chip.getAttribute('data-status');
+			const isHidden = hidden.includes(status);
+			document.body.classList.toggle('hide-run-' + status, isHidden);
+			chip.classList.toggle('status-filter-off', isHidden);
+		});
+	}
+
+	applyStatusFilter();
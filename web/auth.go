@@ -0,0 +1,115 @@
+package web
+
+import (
+	"crypto/subtle"
+	"job_processor/jobpro"
+	"net/http"
+	"strings"
+
+	"github.com/rohanthewiz/rweb"
+)
+
+const (
+	tokenCookie  = "jobpro_token"
+	principalKey = "principal"
+)
+
+// Principal is an API token holder
+// Non admin principals only see and operate on the jobs of their namespace.
+type Principal struct {
+	Name      string
+	Token     string
+	Namespace string
+	Admin     bool
+}
+
+// useAuth requires a principal's token on every request except the root health check
+// and webhook deliveries, which are signed instead.
+// The token is taken from an "Authorization: Bearer" header, a "token" query param
+// (which is then kept in a cookie so the UI keeps working), or that cookie.
+// Authentication is disabled when there are no principals.
+func useAuth(s *rweb.Server, principals []Principal) {
+	if len(principals) == 0 {
+		return
+	}
+
+	s.Use(func(ctx rweb.Context) error {
+		req := ctx.Request()
+		if req.Path() == "/" || strings.HasPrefix(req.Path(), "/hooks/") {
+			return ctx.Next()
+		}
+
+		token, fromQuery := requestToken(req)
+		p, ok := findPrincipal(principals, token)
+		if !ok {
+			ctx.Status(401)
+			return ctx.WriteJSON(map[string]string{
+				"error": "unauthorized",
+			})
+		}
+
+		if fromQuery {
+			cookie := http.Cookie{Name: tokenCookie, Value: token, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode}
+			ctx.Response().SetHeader("Set-Cookie", cookie.String())
+		}
+
+		ctx.Set(principalKey, p)
+		return ctx.Next()
+	})
+}
+
+// requestToken returns the token presented by the request and whether it came from the query string
+func requestToken(req rweb.ItfRequest) (token string, fromQuery bool) {
+	if auth := req.Header("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token), false
+		}
+	}
+	if token = req.QueryParam("token"); token != "" {
+		return token, true
+	}
+
+	header := http.Header{"Cookie": {req.Header("Cookie")}}
+	if cookie, err := (&http.Request{Header: header}).Cookie(tokenCookie); err == nil {
+		return cookie.Value, false
+	}
+	return "", false
+}
+
+// findPrincipal returns the principal holding the token
+func findPrincipal(principals []Principal, token string) (Principal, bool) {
+	if token == "" {
+		return Principal{}, false
+	}
+	for _, p := range principals {
+		if subtle.ConstantTimeCompare([]byte(p.Token), []byte(token)) == 1 {
+			return p, true
+		}
+	}
+	return Principal{}, false
+}
+
+// managerFor returns the job manager the request may use:
+// the whole manager for admins (or when authentication is disabled),
+// otherwise a view restricted to the principal's namespace
+func managerFor(ctx rweb.Context, jobMgr *jobpro.DefaultJobManager) *jobpro.DefaultJobManager {
+	p, ok := ctx.Get(principalKey).(Principal)
+	if !ok || p.Admin {
+		return jobMgr
+	}
+	return jobMgr.ForNamespace(p.Namespace)
+}
+
+// isAdmin reports whether the request may change state shared by all namespaces
+func isAdmin(ctx rweb.Context) bool {
+	p, ok := ctx.Get(principalKey).(Principal)
+	return !ok || p.Admin
+}
+
+// forbidden writes a 403 JSON error
+func forbidden(ctx rweb.Context) error {
+	ctx.Status(403)
+	return ctx.WriteJSON(map[string]string{
+		"error": "admin access required",
+	})
+}
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/rweb"
+)
+
+// registerCalendarRoutes adds the blackout calendar endpoints
+// Calendars are shared by all namespaces, so only admins can change them
+func registerCalendarRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	s.Get("/api/v1/calendars", func(ctx rweb.Context) error {
+		cals, err := jobMgr.ListCalendars()
+		if err != nil {
+			return serverError(ctx, err, "Failed to list calendars")
+		}
+		return ctx.WriteJSON(cals)
+	})
+
+	// Create or replace a calendar (or a JSON array of calendars)
+	s.Post("/api/v1/calendars", func(ctx rweb.Context) error {
+		if !isAdmin(ctx) {
+			return forbidden(ctx)
+		}
+
+		body := ctx.Request().Body()
+		var cals []jobpro.Calendar
+		if err := json.Unmarshal(body, &cals); err != nil {
+			var cal jobpro.Calendar
+			if err := json.Unmarshal(body, &cal); err != nil {
+				return badRequest(ctx, errors.New("invalid calendar: "+err.Error()))
+			}
+			cals = []jobpro.Calendar{cal}
+		}
+
+		if err := jobMgr.SaveCalendars(cals...); err != nil {
+			return badRequest(ctx, err)
+		}
+		return ctx.WriteJSON(cals)
+	})
+
+	s.Delete("/api/v1/calendars/:name", func(ctx rweb.Context) error {
+		if !isAdmin(ctx) {
+			return forbidden(ctx)
+		}
+
+		name := ctx.Request().Param("name")
+		if err := jobMgr.DeleteCalendar(name); err != nil {
+			return serverError(ctx, err, "Failed to delete calendar", "name", name)
+		}
+		return ctx.WriteJSON(map[string]string{
+			"name":   name,
+			"status": "deleted",
+		})
+	})
+}
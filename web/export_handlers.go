@@ -0,0 +1,79 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"job_processor/jobpro"
+	"job_processor/util"
+	"time"
+
+	"github.com/rohanthewiz/rweb"
+)
+
+// registerExportRoutes adds the export and import endpoints
+func registerExportRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	// Export job definitions and results
+	// Query params: format (json|csv|parquet), table (jobs|results - csv and parquet only),
+	// from and to (date, timestamp or relative time like "-24h")
+	s.Get("/api/v1/export", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		opts := jobpro.ExportOptions{
+			Format: jobpro.ExportFormat(req.QueryParam("format")),
+			Table:  jobpro.ExportTable(req.QueryParam("table")),
+		}
+		if opts.Format == "" {
+			opts.Format = jobpro.ExportJSON
+		}
+
+		var err error
+		if opts.From, err = util.ParseDateOrTime(req.QueryParam("from")); err != nil {
+			return badRequest(ctx, err)
+		}
+		if opts.To, err = util.ParseDateOrTime(req.QueryParam("to")); err != nil {
+			return badRequest(ctx, err)
+		}
+
+		if err = opts.Validate(); err != nil {
+			return badRequest(ctx, err)
+		}
+
+		data, err := managerFor(ctx, jobMgr).Export(opts)
+		if err != nil {
+			return serverError(ctx, err, "Failed to export jobs")
+		}
+
+		contentType := map[jobpro.ExportFormat]string{
+			jobpro.ExportJSON:    "application/json",
+			jobpro.ExportCSV:     "text/csv",
+			jobpro.ExportParquet: "application/vnd.apache.parquet",
+		}[opts.Format]
+		fileName := fmt.Sprintf("jobs-export-%s.%s", time.Now().UTC().Format("20060102-150405"), opts.Format)
+
+		ctx.Response().SetHeader("Content-Type", contentType)
+		ctx.Response().SetHeader("Content-Disposition", `attachment; filename="`+fileName+`"`)
+		return ctx.Bytes(data)
+	})
+
+	// Import a JSON export bundle
+	// Query params: overwrite=true to overwrite conflicting definitions, replay=true to replay results
+	s.Post("/api/v1/import", func(ctx rweb.Context) error {
+		bundle, err := jobpro.ReadExportBundle(bytes.NewReader(ctx.Request().Body()))
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+
+		report, err := managerFor(ctx, jobMgr).Import(bundle, jobpro.ImportOptions{
+			Overwrite:     ctx.Request().QueryParam("overwrite") == "true",
+			ReplayResults: ctx.Request().QueryParam("replay") == "true",
+		})
+		if errors.Is(err, jobpro.ErrCommandJobsNotAllowed) {
+			return forbidden(ctx)
+		}
+		if err != nil {
+			return serverError(ctx, err, "Failed to import jobs")
+		}
+
+		return ctx.WriteJSON(report)
+	})
+}
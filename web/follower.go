@@ -0,0 +1,40 @@
+package web
+
+import (
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/element"
+	"github.com/rohanthewiz/rweb"
+)
+
+// useReadOnlyFollower refuses every request but reads while the instance follows the leader,
+// as only the leader schedules and runs jobs
+func useReadOnlyFollower(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	s.Use(func(ctx rweb.Context) error {
+		if method := ctx.Request().Method(); method == "GET" || method == "HEAD" || jobMgr.IsLeader() {
+			return ctx.Next()
+		}
+
+		l := jobMgr.Leadership()
+		ctx.Status(503)
+		return ctx.WriteJSON(map[string]string{
+			"error":  "this instance is a read-only follower, send changes to the leader",
+			"leader": l.Leader,
+		})
+	})
+}
+
+// renderFollowerBanner tells that the page is read-only while the instance follows the leader
+func renderFollowerBanner(b *element.Builder, l jobpro.Leadership) (x any) {
+	if !l.Elected || l.IsLeader {
+		return
+	}
+
+	leader := l.Leader
+	if leader == "" {
+		leader = "not elected yet"
+	}
+	b.DivClass("follower-banner", "title", "Only the leader schedules and runs jobs").T(
+		"Read-only follower " + l.Instance + " - the leader is " + leader)
+	return
+}
@@ -1,14 +1,28 @@
 package web
 
 import (
+	"job_processor/jobpro"
+	"job_processor/util"
 	"os"
 
 	"github.com/rohanthewiz/rweb"
 )
 
-func rootHandler(ctx rweb.Context) error {
-	return ctx.WriteJSON(map[string]interface{}{
+func rootHandler(ctx rweb.Context, jobMgr *jobpro.DefaultJobManager) error {
+	var leaked int
+	for _, n := range jobpro.LeakedWorkers() {
+		leaked += n
+	}
+
+	health := map[string]interface{}{
 		"response": "OK",
 		"ENV":      os.Getenv("ENV"),
-	})
+		// Workers that ignored being cancelled and are still running
+		"leakedWorkers": leaked,
+	}
+	if l := jobMgr.Leadership(); l.Elected {
+		health["instance"], health["leader"] = l.Instance, l.Leader
+		health["role"] = util.If(l.IsLeader, "leader", "follower")
+	}
+	return ctx.WriteJSON(health)
 }
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"errors"
+	"job_processor/jobpro"
+	"job_processor/util"
+
+	"github.com/rohanthewiz/rweb"
+)
+
+// signatureHeader carries the HMAC-SHA256 signature of a webhook delivery, as sent by GitHub
+const signatureHeader = "X-Signature-256"
+
+// registerHookRoutes adds the endpoint inbound webhooks are delivered to.
+// Deliveries are authenticated by their signature rather than a token.
+func registerHookRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	s.Post("/hooks/:name", func(ctx rweb.Context) error {
+		req := ctx.Request()
+		delivery, err := jobMgr.DeliverHook(req.Param("name"), req.Body(), req.Header(signatureHeader))
+		if err != nil {
+			return hookError(ctx, err)
+		}
+
+		ctx.Status(util.If(delivery.Status == "pending", 202, 200))
+		return ctx.WriteJSON(delivery)
+	})
+}
+
+// hookError writes the error of a webhook delivery
+func hookError(ctx rweb.Context, err error) error {
+	switch {
+	case errors.Is(err, jobpro.ErrHookNotFound):
+		ctx.Status(404)
+	case errors.Is(err, jobpro.ErrBadSignature):
+		ctx.Status(401)
+	case errors.Is(err, jobpro.ErrBadPayload):
+		ctx.Status(400)
+	case errors.Is(err, jobpro.ErrHookJobInactive):
+		ctx.Status(409)
+	case errors.Is(err, jobpro.ErrNotLeader):
+		ctx.Status(503)
+	default:
+		return serverError(ctx, err, "Failed to deliver webhook")
+	}
+	return ctx.WriteJSON(map[string]string{
+		"error": err.Error(),
+	})
+}
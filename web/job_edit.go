@@ -0,0 +1,97 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/element"
+	"github.com/rohanthewiz/rweb"
+)
+
+//go:embed assets/job_edit.js
+var jobEditJS string
+
+// registerJobEditRoutes adds the endpoints reading and changing a job's settings
+//
+//	PUT /api/v1/jobs/:job-id/settings {"Schedule": "0 */10 * * * *", "MaxRunTime": 120}
+//
+// Settings left out of the body are unchanged.
+func registerJobEditRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	s.Get("/api/v1/jobs/:job-id/settings", func(ctx rweb.Context) error {
+		settings, err := managerFor(ctx, jobMgr).GetJobSettings(ctx.Request().Param("job-id"))
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		return ctx.WriteJSON(settings)
+	})
+
+	s.Put("/api/v1/jobs/:job-id/settings", func(ctx rweb.Context) error {
+		jobID := ctx.Request().Param("job-id")
+		mgr := managerFor(ctx, jobMgr)
+
+		var upd jobpro.JobSettings
+		if err := json.Unmarshal(ctx.Request().Body(), &upd); err != nil {
+			return badRequest(ctx, errors.New("invalid settings: "+err.Error()))
+		}
+
+		if err := mgr.UpdateJob(jobID, upd); err != nil {
+			return badRequest(ctx, err)
+		}
+
+		settings, err := mgr.GetJobSettings(jobID)
+		if err != nil {
+			return serverError(ctx, err, "Failed to get job settings", "jobID", jobID)
+		}
+		return ctx.WriteJSON(settings)
+	})
+}
+
+// renderJobEditDialog renders the dialog the edit button of each job opens, filled in with the job's settings
+func renderJobEditDialog(b *element.Builder) (x any) {
+	b.DialogClass("job-edit-dialog", "id", "job-edit-dialog").R(
+		b.FormClass("job-edit-form", "onsubmit", "saveJobEdit(this); return false;").R(
+			b.H3().T("Edit job"),
+			b.Input("type", "hidden", "name", "jobID"),
+			editField(b, "Name", "Name", "text", ""),
+			editField(b, "Schedule", "Schedule", "text", "0 30 9 * * 1-5, every weekday at 09:30, in 2h"),
+			editField(b, "Timezone", "Timezone", "text", "e.g. Europe/Paris"),
+			editField(b, "MaxRunTime", "Max run time (s)", "number", "0 for no limit"),
+			editField(b, "TriggerEndpoint", "Trigger endpoint", "text", "/api/jobs/sync"),
+			editField(b, "Tags", "Tags", "text", "team=etl,env=prod"),
+			editField(b, "Calendars", "Calendars", "text", "e.g. ops,holidays"),
+			b.Label().R(
+				b.Span().T("Calendar policy"),
+				b.Select("name", "CalendarPolicy").R(
+					b.Option("value", "").T(""),
+					b.Option("value", "skip").T("skip"),
+					b.Option("value", "defer").T("defer"),
+				),
+			),
+			editField(b, "Jitter", "Jitter (s)", "number", "0"),
+			editField(b, "Spread", "Spread (s)", "number", "0"),
+			b.DivClass("job-edit-error").R(),
+			b.DivClass("btn-group").R(
+				b.ButtonClass("btn btn-primary", "type", "submit").T("Save"),
+				b.ButtonClass("btn btn-secondary", "type", "button",
+					"onclick", "this.closest('dialog').close()").T("Cancel"),
+			),
+		),
+		b.Script().T(jobEditJS),
+	)
+	return
+}
+
+// editField renders a labelled input of the job edit form
+func editField(b *element.Builder, name, label, inputType, placeholder string) (x any) {
+	attrs := []string{"type", inputType, "name", name, "placeholder", placeholder}
+	if inputType == "number" {
+		attrs = append(attrs, "min", "0")
+	}
+	b.Label().R(
+		b.Span().T(label),
+		b.Input(attrs...),
+	)
+	return
+}
@@ -3,9 +3,14 @@ package web
 import (
 	_ "embed"
 	"fmt"
+	"html"
 	"job_processor/jobpro"
 	"job_processor/util"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rohanthewiz/element"
 )
@@ -22,14 +27,18 @@ var periodicJobRow string
 //go:embed assets/time_tooltip.js
 var timeTooltip string
 
+//go:embed assets/run_progress.js
+var runProgress string
+
 const jobEvent = "job-update"
 
 const barChartEmoji = `<svg width="16" height="16" viewBox="0 0 16 16" fill="none" xmlns="http://www.w3.org/2000/svg" style="vertical-align: middle;"><rect x="1" y="8" width="2" height="6" fill="currentColor"/><rect x="4" y="4" width="2" height="10" fill="currentColor"/><rect x="7" y="6" width="2" height="8" fill="currentColor"/><rect x="10" y="2" width="2" height="12" fill="currentColor"/><rect x="13" y="10" width="2" height="4" fill="currentColor"/></svg>`
 
 const stopWatchEmoji = `<svg width="16" height="16" viewBox="0 0 16 16" fill="none" xmlns="http://www.w3.org/2000/svg" style="vertical-align: middle;"><circle cx="8" cy="9" r="6" stroke="currentColor" stroke-width="1.5" fill="none"/><path d="M8 6v3l2 2" stroke="currentColor" stroke-width="1.5" stroke-linecap="round"/><rect x="6" y="1" width="4" height="2" rx="1" fill="currentColor"/><circle cx="8" cy="9" r="1" fill="currentColor"/></svg>`
 
-// renderJobsTable renders the full jobs table page
-func renderJobsTable(jobs []jobpro.JobRun, resultCounts map[string]int) string {
+// renderJobsTable renders the full jobs table page, filtered by the selector
+func renderJobsTable(jobs []jobpro.JobRun, resultCounts map[string]int, sel jobpro.Selector, leadership jobpro.Leadership,
+	pools []jobpro.PoolStats, commands bool) string {
 	b := element.NewBuilder()
 	cols := []string{"Job", "Id", "Freq", "Status", "Created", "Updated",
 		"Run&nbsp;Id", "Run Start", "Duration", "Status", "Error", "Controls"}
@@ -48,11 +57,20 @@ func renderJobsTable(jobs []jobpro.JobRun, resultCounts map[string]int) string {
 			b.T(`<script src="https://cdn.jsdelivr.net/npm/chart.js@4.4.1/dist/chart.umd.min.js"></script>`),
 			// Add time tooltip functionality
 			b.Script().T(timeTooltip),
+			// Update the progress bars of running jobs as their progress is pushed
+			b.Script().T(runProgress),
 		),
 		b.Body().R(
 			// Add SSE source connection to the body
 			b.DivClass("container").R(
 				b.H1Class("table-title").T("JOBS"),
+				renderFollowerBanner(b, leadership),
+				renderLeakedWorkers(b, jobs),
+				renderFilterBar(b, sel),
+				renderStatusFilter(b),
+				renderNewJobForm(b, commands),
+				renderSchedulePreview(b),
+				renderJobEditDialog(b),
 				b.DivClass("table-responsive").R(
 					b.Table().R(
 						b.THead().R(
@@ -65,10 +83,10 @@ func renderJobsTable(jobs []jobpro.JobRun, resultCounts map[string]int) string {
 						b.TBody("id", "jobs-table-body",
 							"hx-ext", "sse", "sse-connect", "/jobs/update-notify",
 							"hx-trigger", "sse:"+jobEvent,
-							"hx-get", "/jobs/get-table-rows",
+							"hx-get", "/jobs/get-table-rows"+strings.TrimPrefix(jobsURL(sel), "/jobs"),
 							"hx-swap", "innerHTML").R( // It seems best to do the SSE Swap on the immediate children
 
-							renderJobsTableRows(b, jobs, resultCounts),
+							renderJobsTableRows(b, jobs, resultCounts, sel, pools),
 						),
 					),
 				),
@@ -80,10 +98,15 @@ func renderJobsTable(jobs []jobpro.JobRun, resultCounts map[string]int) string {
 }
 
 // renderJobsTableRows renders just the table rows - for HTMX updates
-func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts map[string]int) (x any) {
+// sel is the active filter, extended by the tag chips of each job
+func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts map[string]int, sel jobpro.Selector,
+	pools []jobpro.PoolStats) (x any) {
 	// Add JavaScript for expand/collapse functionality and load more
 	b.Script().T(tableRows)
 
+	// The utilization of the resource pools is refreshed with the rows, as runs take and give back slots
+	renderPoolUsage(b, pools)
+
 	// Track results per job
 	currentJobID := ""
 	resultCount := 0
@@ -134,7 +157,8 @@ func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts
 		if isMainRow {
 			rowClass = "job-main-row"
 		} else {
-			rowClass = "job-result-row"
+			// The run status class lets the status filter hide the row
+			rowClass = "job-result-row run-status-" + job.ResultStatus
 		}
 
 		b.Tr("class", rowClass, "data-job-id", job.JobID, "style", func() string {
@@ -152,7 +176,16 @@ func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts
 							b.Span("class", "toggle-btn", "data-job-id", job.JobID,
 								"onclick", "toggleJobResults('"+job.JobID+"')",
 								"style", "cursor: pointer; font-size: 0.8rem; user-select: none; flex-shrink: 0;").T("&#9658;"),
-							b.Span("style", "flex-grow: 1;").T(job.JobName),
+							b.Span("style", "flex-grow: 1;").R(
+								b.T(html.EscapeString(job.JobName)),
+								b.Wrap(func() {
+									// Jobs outside the default namespace are only listed together with others for admins
+									if job.Namespace != "" && job.Namespace != jobpro.DefaultNamespace {
+										b.SpanClass("tag-chip namespace-chip", "title", "Namespace").T(html.EscapeString(job.Namespace))
+									}
+								}),
+								renderTagChips(b, job.Tags, sel),
+							),
 							// Add result count indicator for periodic jobs
 							b.Wrap(func() {
 								if strings.ToLower(job.ScheduleType) == "periodic" {
@@ -173,7 +206,7 @@ func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts
 						)
 					} else {
 						// For result rows, just show the name without toggle
-						b.T(job.JobName)
+						b.T(html.EscapeString(job.JobName))
 					}
 				}),
 			),
@@ -189,38 +222,23 @@ func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts
 							tooltip = util.FormatDurationUntil(job.NextRunTime)
 						}
 					} else if strings.ToLower(job.ScheduleType) == "periodic" && job.FreqType != "" {
-						// It's a periodic job with a cron expression
-						tooltip = util.ParseCronToEnglish(job.FreqType)
-					}
-
-					if tooltip != "" {
-						b.TdClass("cron tooltip", "title", tooltip).T(job.FreqType)
-					} else {
-						b.TdClass("cron").T(job.FreqType)
+						// It's a periodic job with a cron, natural language or RRULE schedule
+						tooltip = jobpro.DescribeSchedule(job.FreqType, job.Timezone)
 					}
 
-					statusClass := "badge badge-inactive"
-					switch strings.ToLower(job.JobStatus) {
-					case "running":
-						statusClass = "badge badge-active"
-					case "scheduled":
-						statusClass = "badge badge-scheduled"
-					case "paused":
-						statusClass = "badge badge-pending"
-					case "pending":
-						statusClass = "badge badge-pending"
-					case "complete":
-						statusClass = "badge badge-complete"
-					case "cancelled":
-						statusClass = "badge badge-cancelled"
-					case "stopped":
-						statusClass = "badge badge-stopped"
-					case "failed", "error":
-						statusClass = "badge badge-error"
-					}
+					b.TdClass(util.If(tooltip != "", "cron tooltip", "cron"), "title", html.EscapeString(tooltip)).R(
+						b.T(job.FreqType),
+						b.Wrap(func() {
+							// Upcoming runs are shown in the job's timezone and in UTC
+							if job.NextRunTime.After(time.Now()) {
+								b.DivClass("next-run").T("Next: " + formatNextRun(job.NextRunTime, job.Timezone))
+							}
+						}),
+					)
 
 					b.Td().R(
-						b.SpanClass(statusClass).T(job.JobStatus),
+						b.SpanClass(statusBadgeClass(job.JobStatus)).T(job.JobStatus),
+						renderRunProgress(b, job.JobID, job.Progress),
 					)
 					b.TdClass("timestamp").T(job.CreatedAt.UTC().Format("2006-01-02 15:04 MST"))
 					b.TdClass("timestamp").T(job.UpdatedAt.UTC().Format("2006-01-02 15:04 MST"))
@@ -259,28 +277,29 @@ func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts
 									const container = document.getElementById(summaryId);
 									if (!container) return;
 									
-									// Fetch job history
-									fetch('/jobs/history/` + job.JobID + `')
+									// Fetch job summary (computed server side over the analytics window)
+									fetch('/api/v1/analytics/jobs/` + job.JobID + `/summary?recent=1')
 										.then(response => response.json())
-										.then(data => {
+										.then(summary => {
+											const data = summary ? summary.RecentRuns : null;
 											if (!data || data.length === 0) {
 												container.innerHTML = '<div class="summary-empty">No runs yet</div>';
 												return;
 											}
 											
-											// Calculate statistics
-											const totalRuns = data.length;
-											const successfulRuns = data.filter(d => d.Status === 'complete').length;
-											const successRate = Math.round((successfulRuns / totalRuns) * 100);
-											const durations = data.map(d => d.Duration / 1000000); // Convert to ms
-											const avgDuration = durations.reduce((a, b) => a + b, 0) / durations.length;
+											// Statistics
+											const totalRuns = summary.Runs;
+											const successRate = Math.round(summary.SuccessRate);
+											const avgDuration = summary.Durations.AvgMs;
 											
 											// Get last run info
 											const lastRun = data[0]; // Most recent
 											const lastRunTime = new Date(lastRun.StartTime);
 											const timeSince = getTimeSince(lastRunTime);
-											const lastRunStatus = lastRun.Status === 'complete' ? '&#10003;' : '&#10007;';
-											const lastRunClass = lastRun.Status === 'complete' ? 'success' : 'error';
+											// Skipped, cancelled and interrupted runs are neither successes nor failures
+											const lastRunFailed = lastRun.Status === 'failed' || lastRun.Status === 'timed_out';
+											const lastRunStatus = lastRun.Status === 'complete' ? '&#10003;' : lastRunFailed ? '&#10007;' : '&#8211;';
+											const lastRunClass = lastRun.Status === 'complete' ? 'success' : lastRunFailed ? 'error' : 'warning';
 											
 											// Create summary HTML
 											const summaryHTML = 
@@ -310,7 +329,7 @@ func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts
 											container.innerHTML = summaryHTML;
 										})
 										.catch(error => {
-											console.error('Error fetching job history:', error);
+											console.error('Error fetching job summary:', error);
 											container.innerHTML = '<div class="summary-error">Failed to load stats</div>';
 										});
 									
@@ -342,20 +361,26 @@ func renderJobsTableRows(b *element.Builder, jobs []jobpro.JobRun, resultCounts
 									// One-time job controls based on status
 									renderOneTimeJobControls(b, job.JobID, strings.ToLower(job.JobStatus))
 								}
+								renderEditJobButton(b, job.JobID)
 							}),
 						),
 					)
 
 				} else { // run level things
-					b.Td().F("#%d", job.RunNumber)
-					b.TdClass("timestamp").T(job.StartTime.UTC().Format("2006-01-02 15:04 MST"))
-					b.Td().F("%0.1f ms", float64(job.Duration.Microseconds())/1000)
-					b.Td().T(job.ResultStatus)
-					b.Td().T(job.ErrorMsg)
+					b.Td().R(b.F("#%d", job.RunNumber), renderTrigger(b, job.Trigger))
+					b.TdClass("timestamp", "title", runStartTitle(job.StartTime, job.ScheduledTime)).T(
+						job.StartTime.UTC().Format("2006-01-02 15:04 MST"))
+					b.Td().R(b.F("%0.1f ms", float64(job.Duration.Microseconds())/1000), renderPoolWait(b, job.PoolWait))
+					b.Td().R(b.SpanClass(statusBadgeClass(job.ResultStatus)).T(job.ResultStatus), renderResumedMarker(b, job.Resumed))
+					b.Td().T(html.EscapeString(job.ErrorMsg))
 					b.Td().T("")
 				}
 			}),
 		)
+
+		if isMainRow && job.RunID != "" {
+			renderRunningRow(b, job)
+		}
 	}
 
 	// Add load more button for the last job if needed
@@ -427,6 +452,18 @@ func renderPeriodicJobControls(b *element.Builder, jobID string, status string)
 	)
 }
 
+// renderEditJobButton renders the button opening the job edit dialog
+func renderEditJobButton(b *element.Builder, jobID string) {
+	b.AClass("btn btn-secondary", "data-job-id", jobID, "title", "Edit Job", "onClick",
+		`openJobEdit(this.getAttribute('data-job-id'))`).R(
+		b.T(`<svg width="20" height="20" viewBox="0 0 20 20" fill="none"
+		xmlns="http://www.w3.org/2000/svg"
+		style="vertical-align: middle;">
+		<path d="M13.5 3.5L16.5 6.5L7 16H4V13L13.5 3.5Z" stroke="currentColor" stroke-width="2" stroke-linejoin="round"/>
+		</svg>`),
+	)
+}
+
 // renderOneTimeJobControls renders control buttons for one-time jobs based on their status
 func renderOneTimeJobControls(b *element.Builder, jobID string, status string) {
 	switch status {
@@ -485,7 +522,7 @@ func renderOneTimeJobControls(b *element.Builder, jobID string, status string) {
 			</svg>`),
 		)
 
-	case "complete", "failed", "stopped":
+	case "complete", "failed", "stopped", "timed_out":
 		// Retry button
 		b.AClass("btn btn-primary", "data-job-id", jobID, "title", "Retry Job", "onClick",
 			`fetch('/jobs/run-now/' + this.getAttribute('data-job-id'), {method: 'POST'}).then(response => { if (response.ok) return response.json(); throw new Error('Network response was not ok'); }).then(data => console.log('Job retried:', data)).catch(error => console.error('Error retrying job:', error))`).R(
@@ -500,3 +537,220 @@ func renderOneTimeJobControls(b *element.Builder, jobID string, status string) {
 		)
 	}
 }
+
+// renderLeakedWorkers warns of the listed jobs' workers still running after timing out or being stopped
+func renderLeakedWorkers(b *element.Builder, jobs []jobpro.JobRun) (x any) {
+	leaked := jobpro.LeakedWorkers()
+	var terms []string
+	for _, job := range jobs {
+		if n := leaked[job.JobID]; n > 0 && job.ResultId == 0 {
+			terms = append(terms, fmt.Sprintf("%s (%d)", html.EscapeString(job.JobName), n))
+		}
+	}
+	if len(terms) == 0 {
+		return
+	}
+
+	b.DivClass("leak-warning", "title", "These workers ignored the cancellation of their context").T(
+		"⚠ Workers still running after timing out or being stopped: " + strings.Join(terms, ", "))
+	return
+}
+
+// renderRunProgress renders the progress bar of a job's run in progress, hidden while the job is not running.
+// The bar is updated in place as progress is pushed on the SSE channel.
+func renderRunProgress(b *element.Builder, jobID string, progress *jobpro.Progress) (x any) {
+	p := jobpro.Progress{Percent: -1, Done: true}
+	if progress != nil {
+		p = *progress
+	}
+
+	var label []string
+	if p.Percent >= 0 {
+		label = append(label, fmt.Sprintf("%.0f%%", p.Percent))
+	}
+	label = append(label, p.Step, p.Message)
+	if p.Stuck {
+		label = append(label, "stuck: no heartbeat since "+util.If(p.LastHeartbeat.IsZero(), "it started",
+			p.LastHeartbeat.Local().Format("15:04:05")))
+	}
+	text := html.EscapeString(strings.Join(slices.DeleteFunc(label, func(s string) bool { return s == "" }), " · "))
+
+	class := "run-progress"
+	if p.Percent < 0 {
+		class += " run-progress-unknown"
+	}
+	if p.Stuck {
+		class += " run-progress-stuck"
+	}
+	b.Div("class", class, "data-job-id", jobID, "style", util.If(p.Done, "display: none;", "")).R(
+		b.DivClass("run-progress-bar").R(
+			b.DivClass("run-progress-fill", "style", fmt.Sprintf("width: %.0f%%;", max(p.Percent, 0))).T(""),
+		),
+		b.DivClass("run-progress-label", "title", text).T(text),
+	)
+	return
+}
+
+// renderRunningRow renders the run in progress of a job as the first of its result rows,
+// with a button cancelling that run only
+func renderRunningRow(b *element.Builder, job jobpro.JobRun) {
+	b.Tr("class", "job-result-row run-status-running", "data-job-id", job.JobID, "style", "display: none;").R(
+		b.Td().T(html.EscapeString(job.JobName)),
+		b.Td().T(job.JobID),
+		b.Td().T(""),
+		b.Td().T(""),
+		b.Td().T(""),
+		b.Td().T(""),
+		b.Td("title", "Run "+job.RunID).T("current"),
+		b.TdClass("timestamp").T(job.RunStart.UTC().Format("2006-01-02 15:04 MST")),
+		b.Td().F("%s so far", time.Since(job.RunStart).Round(time.Second)),
+		b.Td().R(b.SpanClass(statusBadgeClass("running")).T("running")),
+		b.Td().T(""),
+		b.Td().R(
+			b.ButtonClass("btn btn-danger", "data-run-id", job.RunID, "onclick", "cancelRun(this)",
+				"title", "Cancel this run, the job stays scheduled").T("Cancel"),
+		),
+	)
+}
+
+// renderResumedMarker marks a run that resumed one interrupted by a shutdown or crash
+func renderResumedMarker(b *element.Builder, resumed bool) (x any) {
+	if resumed {
+		b.SpanClass("badge badge-resumed", "title", "Resumed an interrupted run, or carried on from its checkpoint").T("resumed")
+	}
+	return
+}
+
+// renderTrigger tells what started a run
+func renderTrigger(b *element.Builder, trigger jobpro.TriggerSource) (x any) {
+	if trigger != "" {
+		b.SpanClass("run-trigger trigger-"+string(trigger), "title", "Started by "+string(trigger)).T(string(trigger))
+	}
+	return
+}
+
+// renderPoolWait tells how long a run waited for the resource pools of its job
+func renderPoolWait(b *element.Builder, wait time.Duration) (x any) {
+	if wait > 0 {
+		b.SpanClass("pool-wait", "title", "Waited for the job's resource pools before starting").T(
+			"+" + wait.Round(time.Millisecond).String() + " wait")
+	}
+	return
+}
+
+// renderPoolUsage renders a row with the utilization of each resource pool, if any are configured
+func renderPoolUsage(b *element.Builder, pools []jobpro.PoolStats) {
+	if len(pools) == 0 {
+		return
+	}
+
+	b.TrClass("pool-usage-row").R(
+		b.Td("colspan", "12").R(
+			b.DivClass("pool-usage").R(
+				b.SpanClass("pool-usage-label").T("Pools:"),
+				element.ForEach(pools, func(p jobpro.PoolStats) {
+					usage := []string{fmt.Sprintf("%d running", p.InUse)}
+					if p.MaxConcurrent > 0 {
+						usage[0] = fmt.Sprintf("%d/%d running", p.InUse, p.MaxConcurrent)
+					}
+					if p.Waiting > 0 {
+						usage = append(usage, fmt.Sprintf("%d waiting", p.Waiting))
+					}
+					if p.RateLimit > 0 {
+						usage = append(usage, fmt.Sprintf("%d starts left (%d per %ds)", int(p.Tokens), p.RateLimit,
+							util.If(p.RatePeriod > 0, p.RatePeriod, 60)))
+					}
+					full := p.Waiting > 0 || (p.MaxConcurrent > 0 && p.InUse >= p.MaxConcurrent)
+					b.SpanClass(util.If(full, "pool-chip pool-full", "pool-chip"), "title", "Resource pool "+html.EscapeString(p.Name)).R(
+						b.SpanClass("pool-name").T(html.EscapeString(p.Name)),
+						b.T(" "+strings.Join(usage, " · ")),
+					)
+				}),
+			),
+		),
+	)
+}
+
+// statusBadgeClass returns the badge classes of a job or run status
+func statusBadgeClass(status string) string {
+	switch strings.ToLower(status) {
+	case "running":
+		return "badge badge-active"
+	case "scheduled":
+		return "badge badge-scheduled"
+	case "paused", "pending":
+		return "badge badge-pending"
+	case "complete":
+		return "badge badge-complete"
+	case "cancelled":
+		return "badge badge-cancelled"
+	case "stopped":
+		return "badge badge-stopped"
+	case string(jobpro.StatusTimedOut):
+		return "badge badge-timed-out"
+	case string(jobpro.StatusInterruptedByShutdown):
+		return "badge badge-interrupted"
+	case string(jobpro.StatusSkippedCalendar), string(jobpro.StatusSkippedOverlap), string(jobpro.StatusSkippedPool):
+		return "badge badge-skipped"
+	case "failed", "error":
+		return "badge badge-error"
+	}
+	return "badge badge-inactive"
+}
+
+// renderStatusFilter renders a chip per run status, clicking one hides or shows the runs with that status
+func renderStatusFilter(b *element.Builder) (x any) {
+	b.DivClass("status-filter", "id", "status-filter").R(
+		b.SpanClass("status-filter-label").T("Runs:"),
+		element.ForEach(jobpro.ResultStatuses, func(status jobpro.JobStatus) {
+			b.SpanClass(statusBadgeClass(string(status))+" status-filter-chip",
+				"data-status", string(status), "title", "Hide or show runs with this status",
+				"onclick", "toggleStatusFilter(this)").T(string(status))
+		}),
+	)
+	return
+}
+
+// runStartTitle describes when a scheduled run was due and how late it started, for the Run Start tooltip
+func runStartTitle(start, scheduled time.Time) string {
+	if scheduled.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("Scheduled for %s, started %s late",
+		scheduled.UTC().Format("2006-01-02 15:04:05 MST"), start.Sub(scheduled).Round(time.Millisecond))
+}
+
+// formatNextRun formats a run time in the job's timezone (server local time if none) and in UTC
+func formatNextRun(t time.Time, timezone string) string {
+	loc := time.Local
+	if timezone != "" {
+		if l, err := time.LoadLocation(timezone); err == nil {
+			loc = l
+		}
+	}
+
+	local := t.In(loc)
+	if _, offset := local.Zone(); offset == 0 {
+		return local.Format("2006-01-02 15:04 MST")
+	}
+	return local.Format("2006-01-02 15:04 MST") + " / " + t.UTC().Format("15:04 MST")
+}
+
+// formatOutput renders a run's structured output compactly as "key=value" pairs sorted by key
+func formatOutput(output map[string]any) string {
+	keys := make([]string, 0, len(output))
+	for k := range output {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		val := output[k]
+		if f, ok := val.(float64); ok { // JSON numbers decode as float64 - avoid exponent notation
+			val = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", k, val))
+	}
+	return strings.Join(parts, " ")
+}
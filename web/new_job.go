@@ -0,0 +1,93 @@
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/element"
+	"github.com/rohanthewiz/rweb"
+)
+
+//go:embed assets/new_job.js
+var newJobJS string
+
+// registerNewJobRoutes adds the endpoint creating jobs at runtime
+//
+//	POST /api/v1/jobs {"Name": "Sync", "IsPeriodic": true, "Schedule": "0 */5 * * * *", "TriggerEndpoint": "/sync", "AutoStart": true}
+func registerNewJobRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	s.Post("/api/v1/jobs", func(ctx rweb.Context) error {
+		var jc jobpro.JobConfig
+		if err := json.Unmarshal(ctx.Request().Body(), &jc); err != nil {
+			return badRequest(ctx, errors.New("invalid job: "+err.Error()))
+		}
+
+		jobID, err := managerFor(ctx, jobMgr).CreateJob(jc)
+		if errors.Is(err, jobpro.ErrCommandJobsNotAllowed) {
+			return forbidden(ctx)
+		}
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+
+		return ctx.WriteJSON(map[string]string{
+			"jobID":  jobID,
+			"status": "created",
+		})
+	})
+}
+
+// renderNewJobForm renders the form creating a job, with a live preview of its schedule.
+// The command type is offered if commands is set.
+func renderNewJobForm(b *element.Builder, commands bool) (x any) {
+	b.Details("class", "new-job").R(
+		b.Summary().T("New job"),
+		b.FormClass("new-job-form", "onsubmit", "createJob(this); return false;").R(
+			b.DivClass("new-job-row").R(
+				b.Input("type", "text", "name", "Name", "class", "selector-input short-input",
+					"placeholder", "Name", "required", "required"),
+				b.Input("type", "text", "name", "Id", "class", "selector-input short-input",
+					"placeholder", "Id (optional)"),
+				b.Select("name", "IsPeriodic", "onchange", "newJobScheduleChanged(this.form)").R(
+					b.Option("value", "true").T("Periodic"),
+					b.Option("value", "false").T("One-time"),
+				),
+				b.Select("name", "Type", "onchange", "newJobTypeChanged(this.form)").R(
+					b.Option("value", "http").T("HTTP trigger"),
+					b.Wrap(func() {
+						if commands {
+							b.Option("value", "command").T("Command")
+						}
+					}),
+				),
+				b.Input("type", "text", "name", "TriggerEndpoint", "class", "selector-input short-input",
+					"placeholder", "Endpoint path, e.g. /jobs/sync"),
+				b.Input("type", "text", "name", "Command", "class", "selector-input short-input",
+					"placeholder", "Shell command", "hidden", "hidden"),
+			),
+			b.DivClass("new-job-row").R(
+				b.Input("type", "text", "name", "Schedule", "class", "selector-input",
+					"placeholder", "0 */5 * * * *, every weekday at 09:30, in 2h (empty for manual start)",
+					"oninput", "newJobScheduleChanged(this.form)"),
+				b.Input("type", "text", "name", "Timezone", "class", "selector-input short-input",
+					"placeholder", "Timezone, e.g. Europe/Paris", "oninput", "newJobScheduleChanged(this.form)"),
+			),
+			b.DivClass("new-job-preview").R(),
+			b.DivClass("new-job-row").R(
+				b.Label().R(b.Span().T("Timeout (s)"),
+					b.Input("type", "number", "name", "MaxRunTime", "min", "0", "value", "0")),
+				b.Label().R(b.Span().T("Retries"),
+					b.Input("type", "number", "name", "RetryCount", "min", "0", "value", "0")),
+				b.Input("type", "text", "name", "Tags", "class", "selector-input short-input",
+					"placeholder", "Tags, e.g. team=etl,env=prod"),
+				b.Label().R(b.Input("type", "checkbox", "name", "AutoStart", "checked", "checked"),
+					b.Span().T("Start now")),
+				b.ButtonClass("btn btn-primary", "type", "submit").T("Create"),
+			),
+			b.DivClass("new-job-status").R(),
+		),
+		b.Script().T(newJobJS),
+	)
+	return
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	_ "embed"
+	"job_processor/jobpro"
+	"strings"
+
+	"github.com/rohanthewiz/element"
+	"github.com/rohanthewiz/rweb"
+)
+
+//go:embed assets/schedule_preview.js
+var schedulePreviewJS string
+
+// registerScheduleRoutes adds the schedule preview endpoint
+//
+//	GET /api/v1/schedules/preview?schedule=every+weekday+at+09:30&timezone=Europe/Paris&count=10&calendars=ops,holidays&policy=defer
+func registerScheduleRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	s.Get("/api/v1/schedules/preview", func(ctx rweb.Context) error {
+		req := ctx.Request()
+
+		var cals []string
+		if c := strings.TrimSpace(req.QueryParam("calendars")); c != "" {
+			for _, name := range strings.Split(c, ",") {
+				cals = append(cals, strings.TrimSpace(name))
+			}
+		}
+
+		preview, err := jobMgr.PreviewSchedule(req.QueryParam("schedule"), jobpro.PreviewOptions{
+			Timezone:       req.QueryParam("timezone"),
+			Calendars:      cals,
+			CalendarPolicy: jobpro.CalendarPolicy(req.QueryParam("policy")),
+			Count:          intQueryParam(ctx, "count", 0),
+		})
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		return ctx.WriteJSON(preview)
+	})
+}
+
+// renderSchedulePreview renders the schedule preview widget: a schedule, timezone and calendars
+// in, the description, warnings and next runs out
+func renderSchedulePreview(b *element.Builder) (x any) {
+	b.Details("class", "schedule-preview").R(
+		b.Summary().T("Schedule preview"),
+		b.FormClass("schedule-preview-form", "onsubmit", "previewSchedule(this); return false;").R(
+			b.Input("type", "text", "name", "schedule", "class", "selector-input",
+				"placeholder", "0 30 9 * * 1-5, every weekday at 09:30, FREQ=MONTHLY;BYDAY=-1FR, in 2h"),
+			b.Input("type", "text", "name", "timezone", "class", "selector-input short-input",
+				"placeholder", "Timezone, e.g. Europe/Paris"),
+			b.Input("type", "text", "name", "calendars", "class", "selector-input short-input",
+				"placeholder", "Calendars, e.g. ops,holidays"),
+			b.Select("name", "policy").R(
+				b.Option("value", "skip").T("skip"),
+				b.Option("value", "defer").T("defer"),
+			),
+			b.ButtonClass("btn btn-secondary", "type", "submit").T("Preview"),
+		),
+		b.DivClass("schedule-preview-result").R(),
+		b.Script().T(schedulePreviewJS),
+	)
+	return
+}
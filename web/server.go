@@ -2,9 +2,12 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"job_processor/jobpro"
 	"job_processor/pubsub"
+	"job_processor/util"
 	"strconv"
 
 	"github.com/rohanthewiz/element"
@@ -13,40 +16,56 @@ import (
 	"github.com/rohanthewiz/serr"
 )
 
-func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
+// StartWebServer serves the UI and API for the job manager
+// Principals, if any, are required to present a token; see useAuth
+func StartWebServer(jobMgr *jobpro.DefaultJobManager, principals []Principal) {
 	s := rweb.NewServer(rweb.ServerOptions{
 		Address: fmt.Sprintf(":%s", "8000"),
 		Verbose: true,
 	})
 
 	s.Use(rweb.RequestInfo)
+	useAuth(s, principals)
+	useReadOnlyFollower(s, jobMgr)
 	s.ElementDebugRoutes()
 
 	// Serve static files from the artifacts directory
 	s.StaticFiles("/job/config/", "artifacts/config", 2)
 
-	s.Get("/", rootHandler)
+	s.Get("/", func(ctx rweb.Context) error {
+		return rootHandler(ctx, jobMgr)
+	})
 
+	// The optional "selector" query param filters jobs by tags, e.g. ?selector=team=etl,env!=dev
 	s.Get("/jobs", func(ctx rweb.Context) error {
-		jobs, resultCounts, err := jobMgr.ListJobsWithPagination(10)
+		sel, err := selectorParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		jobs, resultCounts, err := managerFor(ctx, jobMgr).ListJobsWithPagination(10, sel)
 		if err != nil {
 			logger.LogErr(err, "Failed to list jobs")
 			return serr.Wrap(err)
 		}
-		return ctx.WriteHTML(renderJobsTable(jobs, resultCounts))
+		return ctx.WriteHTML(renderJobsTable(jobs, resultCounts, sel, jobMgr.Leadership(), jobMgr.Pools(),
+			managerFor(ctx, jobMgr).CommandJobsAllowed()))
 	})
 
 	// Endpoint to get the jobs table rows
 	// Typically this is called after an SSE event is received on job update
 	s.Get("/jobs/get-table-rows", func(ctx rweb.Context) error {
-		jobs, resultCounts, err := jobMgr.ListJobsWithPagination(10)
+		sel, err := selectorParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		jobs, resultCounts, err := managerFor(ctx, jobMgr).ListJobsWithPagination(10, sel)
 		if err != nil {
 			logger.LogErr(err, "Failed to list jobs")
 			return serr.Wrap(err) // guaranteed
 		}
 
 		b := element.NewBuilder()
-		renderJobsTableRows(b, jobs, resultCounts)
+		renderJobsTableRows(b, jobs, resultCounts, sel, jobMgr.Pools())
 
 		return ctx.WriteHTML(b.String())
 	})
@@ -64,7 +83,7 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 			}
 		}
 
-		results, totalCount, err := jobMgr.GetJobResultsPaginated(jobID, offset, 10)
+		results, totalCount, err := managerFor(ctx, jobMgr).GetJobResultsPaginated(jobID, offset, 10)
 		if err != nil {
 			logger.LogErr(err, "Failed to get job results", "jobID", jobID)
 			ctx.Status(500)
@@ -78,18 +97,21 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 		for i, result := range results {
 			// Calculate actual run number: most recent run has highest number
 			runNumber := totalCount - offset - i
-			b.Tr("class", fmt.Sprintf("job-result-row job-%s", jobID), "data-job-id", jobID, "style", "display: none;").R(
+			b.Tr("class", fmt.Sprintf("job-result-row job-%s run-status-%s", jobID, result.Status),
+				"data-job-id", jobID, "style", "display: none;").R(
 				b.Td().T(""),    // Empty for job name
 				b.Td().T(jobID), // Job Id
 				b.Td().T(""),    // Empty for frequency
 				b.Td().T(""),    // Empty for status
 				b.Td().T(""),    // Empty for created
 				b.Td().T(""),    // Empty for updated
-				b.Td().F("#%d", runNumber),
-				b.TdClass("timestamp").T(result.StartTime.Format("2006-01-02 15:04 MST")),
-				b.Td().F("%0.1f ms", float64(result.Duration.Microseconds())/1000),
-				b.Td().T(string(result.Status)),
-				b.Td().T(result.ErrorMsg),
+				b.Td().R(b.F("#%d", runNumber), renderTrigger(b, result.Trigger)),
+				b.TdClass("timestamp", "title", runStartTitle(result.StartTime, result.ScheduledTime)).T(
+					result.StartTime.Format("2006-01-02 15:04 MST")),
+				b.Td().R(b.F("%0.1f ms", float64(result.Duration.Microseconds())/1000), renderPoolWait(b, result.PoolWait)),
+				b.Td().R(b.SpanClass(statusBadgeClass(string(result.Status))).T(string(result.Status)),
+					renderResumedMarker(b, result.Resumed)),
+				b.Td().T(html.EscapeString(util.If(result.ErrorMsg != "", result.ErrorMsg, formatOutput(result.Output)))),
 				b.Td().T(""), // Empty controls column for result rows
 			)
 		}
@@ -119,10 +141,19 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 	s.Get("/jobs/update-notify", func(ctx rweb.Context) error {
 		fmt.Println("Handling SSE request")
 		out := make(chan any, 1)
-		_, err := pubsub.SubscribeToUpdates(out)
+
+		// Namespace views get the updates through a filter, so they don't see the progress of other namespaces
+		mgr, in := managerFor(ctx, jobMgr), out
+		if mgr.Namespace() != "" {
+			in = make(chan any, 1)
+		}
+		sub, err := pubsub.SubscribeToUpdates(in)
 		if err != nil {
 			return serr.Wrap(err)
 		}
+		if mgr.Namespace() != "" {
+			go filterUpdates(mgr, sub, in, out)
+		}
 
 		// Remember that this is just the setup of the SSE connection headers etc.
 		// Data will flow *after* this function exits
@@ -137,7 +168,7 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 		jobID := ctx.Request().Param("job-id")
 
 		// Assume your jobpro.Manager has a PauseJob method
-		if err := jobMgr.PauseJob(jobID); err != nil {
+		if err := managerFor(ctx, jobMgr).PauseJob(jobID); err != nil {
 			logger.LogErr(err, "Failed to pause job", "jobID", jobID)
 			ctx.Status(500)
 			return ctx.WriteJSON(map[string]string{
@@ -154,7 +185,7 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 	s.Post("/jobs/resume/:job-id", func(ctx rweb.Context) error {
 		jobID := ctx.Request().Param("job-id")
 
-		if err := jobMgr.ResumeJob(jobID); err != nil {
+		if err := managerFor(ctx, jobMgr).ResumeJob(jobID); err != nil {
 			logger.LogErr(err, "Failed to resume job", "jobID", jobID)
 			ctx.Status(500)
 			return ctx.WriteJSON(map[string]string{
@@ -171,7 +202,7 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 	s.Post("/jobs/run-now/:job-id", func(ctx rweb.Context) error {
 		jobID := ctx.Request().Param("job-id")
 
-		if err := jobMgr.TriggerJobNow(jobID); err != nil {
+		if err := managerFor(ctx, jobMgr).TriggerJobNow(jobID); err != nil {
 			logger.LogErr(err, "Failed to trigger job", "jobID", jobID)
 			ctx.Status(500)
 			return ctx.WriteJSON(map[string]string{
@@ -188,7 +219,7 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 	s.Post("/jobs/start/:job-id", func(ctx rweb.Context) error {
 		jobID := ctx.Request().Param("job-id")
 
-		if err := jobMgr.StartJob(jobID); err != nil {
+		if err := managerFor(ctx, jobMgr).StartJob(jobID); err != nil {
 			logger.LogErr(err, "Failed to start job", "jobID", jobID)
 			ctx.Status(500)
 			return ctx.WriteJSON(map[string]string{
@@ -205,7 +236,7 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 	s.Post("/jobs/stop/:job-id", func(ctx rweb.Context) error {
 		jobID := ctx.Request().Param("job-id")
 
-		if err := jobMgr.StopJob(jobID); err != nil {
+		if err := managerFor(ctx, jobMgr).StopJob(jobID); err != nil {
 			logger.LogErr(err, "Failed to stop job", "jobID", jobID)
 			ctx.Status(500)
 			return ctx.WriteJSON(map[string]string{
@@ -244,7 +275,7 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 			})
 		}
 
-		if err := jobMgr.RescheduleJob(jobID, req.Schedule); err != nil {
+		if err := managerFor(ctx, jobMgr).RescheduleJob(jobID, req.Schedule); err != nil {
 			logger.LogErr(err, "Failed to reschedule job", "jobID", jobID)
 			ctx.Status(500)
 			return ctx.WriteJSON(map[string]string{
@@ -259,11 +290,11 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 		})
 	})
 
-	// Get job history for charts
+	// Get raw job history - the jobs table charts use the analytics summary instead
 	s.Get("/jobs/history/:job-id", func(ctx rweb.Context) error {
 		jobID := ctx.Request().Param("job-id")
 
-		results, err := jobMgr.GetJobHistory(jobID, 10) // Get last 10 runs
+		results, err := managerFor(ctx, jobMgr).GetJobHistory(jobID, intQueryParam(ctx, "limit", 10))
 		if err != nil {
 			logger.LogErr(err, "Failed to get job history", "jobID", jobID)
 			ctx.Status(500)
@@ -275,9 +306,87 @@ func StartWebServer(jobMgr *jobpro.DefaultJobManager) {
 		return ctx.WriteJSON(results)
 	})
 
+	// Progress of the job's run in progress, as reported by the job
+	s.Get("/api/v1/jobs/:job-id/progress", func(ctx rweb.Context) error {
+		jobID := ctx.Request().Param("job-id")
+
+		progress, running := managerFor(ctx, jobMgr).RunProgress(jobID)
+		if !running {
+			ctx.Status(404)
+			return ctx.WriteJSON(map[string]string{
+				"error": fmt.Sprintf("job %s is not running", jobID),
+			})
+		}
+		return ctx.WriteJSON(progress)
+	})
+
+	// The utilization of the resource pools, shared by all namespaces
+	s.Get("/api/v1/pools", func(ctx rweb.Context) error {
+		return ctx.WriteJSON(jobMgr.Pools())
+	})
+
+	// The runs in progress, with the run Ids to cancel them by
+	s.Get("/api/v1/runs", func(ctx rweb.Context) error {
+		return ctx.WriteJSON(managerFor(ctx, jobMgr).RunningRuns())
+	})
+
+	// Cancel one run in progress - the job stays scheduled
+	s.Post("/api/v1/runs/:run-id/cancel", func(ctx rweb.Context) error {
+		runID := ctx.Request().Param("run-id")
+
+		if err := managerFor(ctx, jobMgr).CancelRun(runID); err != nil {
+			if !errors.Is(err, jobpro.ErrRunNotFound) {
+				return serverError(ctx, err, "Failed to cancel run")
+			}
+			ctx.Status(404)
+			return ctx.WriteJSON(map[string]string{
+				"error": err.Error(),
+			})
+		}
+
+		return ctx.WriteJSON(map[string]string{
+			"runID":  runID,
+			"status": "cancelling",
+		})
+	})
+
+	registerAnalyticsRoutes(s, jobMgr)
+	registerExportRoutes(s, jobMgr)
+	registerTagRoutes(s, jobMgr)
+	registerCalendarRoutes(s, jobMgr)
+	registerScheduleRoutes(s, jobMgr)
+	registerJobEditRoutes(s, jobMgr)
+	registerNewJobRoutes(s, jobMgr)
+	registerWorkerRoutes(s, jobMgr)
+	registerHookRoutes(s, jobMgr)
+	registerWatchRoutes(s, jobMgr)
+
 	// Run the server
 	err := s.Run()
 	if err != nil {
 		logger.LogErr(err, "where", "at server exit")
 	}
 }
+
+// filterUpdates passes on to out the updates of in the namespace view may see. Like the broker, it drops
+// the subscription once out has missed several updates in a row, as when the client went away.
+func filterUpdates(mgr *jobpro.DefaultJobManager, sub *pubsub.Subscription, in <-chan any, out chan<- any) {
+	fails := 0
+	for msg := range in {
+		if !mgr.UpdateVisible(msg) {
+			continue
+		}
+		select {
+		case out <- msg:
+			fails = 0
+		default:
+			if fails++; fails > 3 {
+				sub.Unsubscribe()
+				return
+			}
+		}
+		if msg == pubsub.CloseSignal {
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"html"
+	"job_processor/jobpro"
+	"net/url"
+	"slices"
+
+	"github.com/rohanthewiz/element"
+	"github.com/rohanthewiz/rweb"
+)
+
+// registerTagRoutes adds the selector based job listing and bulk action endpoints
+// The "selector" query param is a label selector such as "team=etl,env!=dev"
+func registerTagRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	// Job definitions matching the selector
+	s.Get("/api/v1/jobs", func(ctx rweb.Context) error {
+		sel, err := selectorParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		jobs, err := managerFor(ctx, jobMgr).SelectJobs(sel)
+		if err != nil {
+			return serverError(ctx, err, "Failed to select jobs", "selector", sel.String())
+		}
+		return ctx.WriteJSON(jobs)
+	})
+
+	// Bulk pause, resume, stop or run-now of the jobs matching the selector
+	s.Post("/jobs/bulk/:action", func(ctx rweb.Context) error {
+		action := jobpro.BulkAction(ctx.Request().Param("action"))
+		sel, err := selectorParam(ctx)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+
+		results, err := managerFor(ctx, jobMgr).Bulk(action, sel)
+		if err != nil {
+			return badRequest(ctx, err)
+		}
+		return ctx.WriteJSON(map[string]any{
+			"action":   action,
+			"selector": sel.String(),
+			"results":  results,
+		})
+	})
+}
+
+// selectorParam reads the "selector" query param
+func selectorParam(ctx rweb.Context) (jobpro.Selector, error) {
+	return jobpro.ParseSelector(ctx.Request().QueryParam("selector"))
+}
+
+// jobsURL returns the jobs page URL filtered by the selector
+func jobsURL(sel jobpro.Selector) string {
+	if sel.Empty() {
+		return "/jobs"
+	}
+	return "/jobs?selector=" + url.QueryEscape(sel.String())
+}
+
+// renderFilterBar renders the selector input, the active filter chips and the bulk action buttons
+func renderFilterBar(b *element.Builder, sel jobpro.Selector) (x any) {
+	b.FormClass("filter-bar", "method", "get", "action", "/jobs").R(
+		b.Input("type", "text", "name", "selector", "class", "selector-input",
+			"placeholder", "Filter by tags, e.g. team=etl,env!=dev",
+			"value", html.EscapeString(sel.String())),
+		b.ButtonClass("btn btn-secondary", "type", "submit").T("Filter"),
+		b.Wrap(func() {
+			if sel.Empty() {
+				return
+			}
+
+			// Active filters - clicking one removes it
+			for i, req := range sel {
+				rest := slices.Delete(slices.Clone(sel), i, i+1)
+				b.AClass("tag-chip tag-chip-active", "href", jobsURL(rest), "title", "Remove filter").R(
+					b.T(html.EscapeString(jobpro.Selector{req}.String()) + " &times;"),
+				)
+			}
+			b.AClass("btn btn-secondary", "href", "/jobs").T("Clear")
+
+			// Bulk actions apply to every job matching the selector
+			b.SpanClass("bulk-actions").R(
+				element.ForEach([]jobpro.BulkAction{jobpro.BulkPause, jobpro.BulkResume,
+					jobpro.BulkStop, jobpro.BulkRunNow}, func(action jobpro.BulkAction) {
+					b.ButtonClass("btn btn-primary", "type", "button",
+						"data-action", string(action),
+						"data-selector", html.EscapeString(sel.String()),
+						"onclick", "bulkAction(this)").T(string(action))
+				}),
+			)
+		}),
+	)
+	return
+}
+
+// renderTagChips renders a job's tags as chips, each adding its tag to the current filter
+func renderTagChips(b *element.Builder, tags map[string]string, sel jobpro.Selector) (x any) {
+	if len(tags) == 0 {
+		return
+	}
+
+	b.DivClass("tag-chips").R(
+		b.Wrap(func() {
+			for _, kv := range jobpro.SortedTags(tags) {
+				req := jobpro.Requirement{Key: kv[0], Op: jobpro.OpEquals, Value: kv[1]}
+				next := sel
+				if !slices.Contains(sel, req) {
+					next = append(slices.Clone(sel), req)
+				}
+				b.AClass("tag-chip", "href", jobsURL(next), "title", "Filter by this tag").
+					T(html.EscapeString(kv[0] + "=" + kv[1]))
+			}
+		}),
+	)
+	return
+}
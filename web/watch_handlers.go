@@ -0,0 +1,32 @@
+package web
+
+import (
+	"job_processor/jobpro"
+
+	"github.com/rohanthewiz/rweb"
+)
+
+// registerWatchRoutes adds the endpoint listing the directory watches.
+// Watches are on the server's file system, so they are for admins only.
+func registerWatchRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	// The watches, with the latest files each picked up
+	s.Get("/api/v1/watches", func(ctx rweb.Context) error {
+		if !isAdmin(ctx) {
+			return forbidden(ctx)
+		}
+
+		type watchStatus struct {
+			jobpro.WatchConfig
+			Files []jobpro.WatchedFile
+		}
+		watches := []watchStatus{}
+		for _, cfg := range jobMgr.Watches() {
+			files, err := jobMgr.WatchedFiles(cfg.Name, 20)
+			if err != nil {
+				return serverError(ctx, err, "Failed to list watched files", "watch", cfg.Name)
+			}
+			watches = append(watches, watchStatus{WatchConfig: cfg, Files: files})
+		}
+		return ctx.WriteJSON(watches)
+	})
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"job_processor/jobpro"
+	"time"
+
+	"github.com/rohanthewiz/rweb"
+)
+
+// maxClaimWait caps how long a worker's claim is held open waiting for a run
+const maxClaimWait = time.Minute
+
+// registerWorkerRoutes adds the endpoints remote workers claim runs from and report them to.
+// Workers serve every namespace, so they need an admin token.
+func registerWorkerRoutes(s *rweb.Server, jobMgr *jobpro.DefaultJobManager) {
+	// Long poll: responds with a run as soon as one the worker can run is queued, or without one after the wait
+	s.Post("/api/v1/workers/claim", func(ctx rweb.Context) error {
+		if !isAdmin(ctx) {
+			return forbidden(ctx)
+		}
+
+		var worker jobpro.WorkerInfo
+		if err := json.Unmarshal(ctx.Request().Body(), &worker); err != nil {
+			return badRequest(ctx, errors.New("invalid worker: "+err.Error()))
+		}
+		wait := 30 * time.Second
+		if val := ctx.Request().QueryParam("wait"); val != "" {
+			var err error
+			if wait, err = time.ParseDuration(val); err != nil || wait < 0 {
+				return badRequest(ctx, errInvalidParam("wait", val))
+			}
+		}
+
+		// rweb gives handlers no request context, so a claim left by a worker that went away ends
+		// when the worker claims again or the manager shuts down, rather than when the request is dropped
+		claim, err := jobMgr.ClaimRun(context.Background(), worker, min(wait, maxClaimWait))
+		if err != nil {
+			return workerError(ctx, err)
+		}
+		return ctx.WriteJSON(claim)
+	})
+
+	s.Post("/api/v1/workers/runs/:run-id/heartbeat", func(ctx rweb.Context) error {
+		if !isAdmin(ctx) {
+			return forbidden(ctx)
+		}
+
+		// The worker forwards the progress reported by the job, if any
+		var req struct {
+			Worker   string
+			Progress *jobpro.Progress
+		}
+		if err := json.Unmarshal(ctx.Request().Body(), &req); err != nil {
+			return badRequest(ctx, errors.New("invalid heartbeat: "+err.Error()))
+		}
+		stop, err := jobMgr.HeartbeatRun(ctx.Request().Param("run-id"), req.Worker, req.Progress)
+		if err != nil {
+			return workerError(ctx, err)
+		}
+		return ctx.WriteJSON(map[string]bool{"Stop": stop})
+	})
+
+	s.Post("/api/v1/workers/runs/:run-id/complete", func(ctx rweb.Context) error {
+		if !isAdmin(ctx) {
+			return forbidden(ctx)
+		}
+
+		var report jobpro.RunReport
+		if err := json.Unmarshal(ctx.Request().Body(), &report); err != nil {
+			return badRequest(ctx, errors.New("invalid run report: "+err.Error()))
+		}
+		runID := ctx.Request().Param("run-id")
+		if err := jobMgr.CompleteRun(runID, report); err != nil {
+			return workerError(ctx, err)
+		}
+		return ctx.WriteJSON(map[string]string{
+			"runID":  runID,
+			"status": "recorded",
+		})
+	})
+
+	// The workers and the runs queued for them or claimed by them
+	s.Get("/api/v1/workers", func(ctx rweb.Context) error {
+		return ctx.WriteJSON(map[string]any{
+			"workers": jobMgr.Workers(),
+			"queue":   managerFor(ctx, jobMgr).QueuedRuns(),
+		})
+	})
+}
+
+// workerError writes the error of a worker request: 410 for a run the worker lost, 503 on followers
+func workerError(ctx rweb.Context, err error) error {
+	switch {
+	case errors.Is(err, jobpro.ErrRunLost):
+		ctx.Status(410)
+	case errors.Is(err, jobpro.ErrNotLeader):
+		ctx.Status(503)
+	default:
+		return serverError(ctx, err, "Failed to handle worker request")
+	}
+	return ctx.WriteJSON(map[string]string{
+		"error": err.Error(),
+	})
+}